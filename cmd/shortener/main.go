@@ -16,12 +16,13 @@ import (
 	"github.com/rawen554/shortener/internal/app"
 	"github.com/rawen554/shortener/internal/config"
 	"github.com/rawen554/shortener/internal/handlers"
-	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/linkcheck"
 	"github.com/rawen554/shortener/internal/logger"
 	"github.com/rawen554/shortener/internal/logic"
 	"github.com/rawen554/shortener/internal/store"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var (
@@ -64,57 +65,113 @@ func main() {
 		wg.Wait()
 	}()
 
-	wg.Add(1)
-	go func() {
-		defer logger.Info("closed DB")
-		defer wg.Done()
-		<-ctx.Done()
+	coreLogic := logic.NewCoreLogic(config, storage, logger.Named("logic"))
+	// background фоновые задачи, которые обращаются к хранилищу помимо серверов
+	background := &sync.WaitGroup{}
 
-		storage.Close()
-	}()
+	a := app.NewApp(config, coreLogic, logger.Named("app"))
 
-	componentsErrs := make(chan error, 1)
+	if config.CheckInterval > 0 {
+		checker := linkcheck.NewChecker(linkcheck.Config{
+			Interval:      config.CheckInterval,
+			HostDelay:     config.CheckHostDelay,
+			Workers:       config.CheckWorkers,
+			AllowLoopback: config.AllowLoopback,
+		}, storage, logger.Named("linkcheck"), nil)
+		component := a.Health().Component("linkcheck")
 
-	coreLogic := logic.NewCoreLogic(config, storage, logger.Named("logic"))
-	a := app.NewApp(config, coreLogic, logger.Named("app"))
+		background.Add(1)
+		go func() {
+			defer logger.Info("link checker has been stopped")
+			defer background.Done()
+
+			component.SetReady()
+			checker.Run(ctx)
+			component.SetDown(nil)
+		}()
+	}
+
+	componentsErrs := make(chan error, 1)
 
 	r, err := a.SetupRouter()
 	if err != nil {
 		logger.Fatal(err)
 	}
+
+	reloadKeys := make(chan os.Signal, 1)
+	signal.Notify(reloadKeys, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(reloadKeys)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reloadKeys:
+				if err := a.ReloadKeys(); err != nil {
+					logger.Error(err)
+					continue
+				}
+				logger.Info("signing keys have been reloaded")
+			}
+		}
+	}()
+
+	if config.EnableHTTPS {
+		if err := ensureCertificates(config.TLSCertPath, config.TLSKeyPath, logger); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	var grpcServer *grpc.Server
+	if config.GRPCPort != "" || config.SinglePort {
+		var opts []grpc.ServerOption
+		// на общем порту TLS завершает http.Server
+		if config.EnableHTTPS && !config.SinglePort {
+			creds, err := handlers.NewServerCredentials(config.TLSCertPath, config.TLSKeyPath, config.GRPCClientCA)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		grpcServer = handlers.NewServer(config.TrustedSubnet, a.Keys(), coreLogic, logger, opts...)
+		healthpb.RegisterHealthServer(grpcServer, handlers.NewHealthService(a.Health()))
+	}
+
 	srv := http.Server{
 		Addr:    config.RunAddr,
 		Handler: r,
 	}
 
-	go func(errs chan<- error) {
-		if config.EnableHTTPS {
-			_, errCert := os.ReadFile(config.TLSCertPath)
-			_, errKey := os.ReadFile(config.TLSKeyPath)
-
-			if errors.Is(errCert, os.ErrNotExist) || errors.Is(errKey, os.ErrNotExist) {
-				privateKey, certBytes, err := app.CreateCertificates(logger.Named("certs-builder"))
-				if err != nil {
-					errs <- fmt.Errorf("error creating tls certs: %w", err)
-					return
-				}
+	if config.SinglePort {
+		srv.Handler = handlers.NewMixedHandler(grpcServer, r)
+	}
 
-				if err := app.WriteCertificates(certBytes, config.TLSCertPath, privateKey, config.TLSKeyPath, logger); err != nil {
-					errs <- fmt.Errorf("error writing tls certs: %w", err)
-					return
-				}
+	go func(errs chan<- error) {
+		// на общем порту gRPC готов, только когда порт уже открыт
+		lis, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			if config.SinglePort {
+				a.Health().Component("grpc").SetDown(err)
 			}
+			errs <- fmt.Errorf("failed to listen: %w", err)
+			return
+		}
+		if config.SinglePort {
+			a.Health().Component("grpc").SetReady()
+			logger.Infof("running gRPC service on %s", config.RunAddr)
+		}
 
-			if err := srv.ListenAndServeTLS(config.TLSCertPath, config.TLSKeyPath); err != nil {
+		if config.EnableHTTPS {
+			if err := srv.ServeTLS(lis, config.TLSCertPath, config.TLSKeyPath); err != nil {
 				if errors.Is(err, http.ErrServerClosed) {
 					return
 				}
 				errs <- fmt.Errorf("run tls server has failed: %w", err)
-				return
 			}
+			return
 		}
 
-		if err := srv.ListenAndServe(); err != nil {
+		if err := srv.Serve(lis); err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
 				return
 			}
@@ -123,23 +180,24 @@ func main() {
 	}(componentsErrs)
 
 	if config.GRPCPort != "" {
+		component := a.Health().Component("grpc")
+
 		wg.Add(1)
 		go func(errs chan<- error) {
 			defer wg.Done()
 			lis, err := net.Listen("tcp", fmt.Sprintf(":%s", config.GRPCPort))
 			if err != nil {
-				logger.Errorf("failed to listen: %w", err)
+				logger.Errorf("failed to listen: %v", err)
+				component.SetDown(err)
 				errs <- err
 				return
 			}
-			grpcServer := grpc.NewServer()
-			reflection.Register(grpcServer)
-
-			pb.RegisterShortenerServer(grpcServer, handlers.NewService(logger, coreLogic))
 
 			logger.Infof("running gRPC service on %s", config.GRPCPort)
+			component.SetReady()
 
 			if err = grpcServer.Serve(lis); err != nil {
+				component.SetDown(err)
 				if errors.Is(err, grpc.ErrServerStopped) {
 					return
 				}
@@ -154,11 +212,41 @@ func main() {
 		defer wg.Done()
 		<-ctx.Done()
 
+		// до остановки серверов сервис перестает отчитываться о готовности и продолжает
+		// обслуживать запросы, пока балансировщик не уберет его по результатам проб
+		a.Health().Shutdown()
+		if config.ShutdownDrain > 0 {
+			logger.Infof("draining for %v before shutdown", config.ShutdownDrain)
+			time.Sleep(config.ShutdownDrain)
+		}
+
 		shutdownTimeoutCtx, cancelShutdownTimeoutCtx := context.WithTimeout(context.Background(), timeoutServerShutdown)
 		defer cancelShutdownTimeoutCtx()
 		if err := srv.Shutdown(shutdownTimeoutCtx); err != nil {
 			logger.Errorf("an error occurred during server shutdown: %v", err)
 		}
+		if grpcServer != nil {
+			// GracefulStop ждет завершения всех вызовов, в том числе потоковых,
+			// поэтому по истечении того же срока соединения закрываются принудительно
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-shutdownTimeoutCtx.Done():
+				logger.Errorf("gRPC server graceful stop timed out: %v", shutdownTimeoutCtx.Err())
+				grpcServer.Stop()
+				<-stopped
+			}
+		}
+
+		// хранилище закрывается, только когда серверы и фоновые задачи завершены
+		background.Wait()
+		coreLogic.Close()
+		storage.Close()
+		logger.Info("closed DB")
 	}()
 
 	select {
@@ -169,10 +257,30 @@ func main() {
 	}
 
 	go func() {
-		ctx, cancelCtx := context.WithTimeout(context.Background(), timeoutShutdown)
+		ctx, cancelCtx := context.WithTimeout(context.Background(), config.ShutdownDrain+timeoutShutdown)
 		defer cancelCtx()
 
 		<-ctx.Done()
 		logger.Fatal("failed to gracefully shutdown the service")
 	}()
 }
+
+// ensureCertificates создает самоподписанный сертификат, если файлы сертификата или ключа отсутствуют.
+func ensureCertificates(certPath string, keyPath string, logger *zap.SugaredLogger) error {
+	_, errCert := os.Stat(certPath)
+	_, errKey := os.Stat(keyPath)
+	if !errors.Is(errCert, os.ErrNotExist) && !errors.Is(errKey, os.ErrNotExist) {
+		return nil
+	}
+
+	privateKey, certBytes, err := app.CreateCertificates(logger.Named("certs-builder"))
+	if err != nil {
+		return fmt.Errorf("error creating tls certs: %w", err)
+	}
+
+	if err := app.WriteCertificates(certBytes, certPath, privateKey, keyPath, logger); err != nil {
+		return fmt.Errorf("error writing tls certs: %w", err)
+	}
+
+	return nil
+}
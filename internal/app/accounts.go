@@ -0,0 +1,64 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+func (a *App) Register(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	var creds models.Credentials
+	if err := json.NewDecoder(c.Request.Body).Decode(&creds); err != nil {
+		a.logger.Errorf(ErrorDecodeBody, err)
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	account, err := a.coreLogic.Register(c, userID, creds)
+	if err != nil {
+		a.writeError(c, "Error registering user", err)
+		return
+	}
+
+	a.writeAccount(c, http.StatusCreated, account)
+}
+
+func (a *App) Login(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	var creds models.Credentials
+	if err := json.NewDecoder(c.Request.Body).Decode(&creds); err != nil {
+		a.logger.Errorf(ErrorDecodeBody, err)
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	account, err := a.coreLogic.Login(c, userID, creds)
+	if err != nil {
+		a.writeError(c, "Error logging in", err)
+		return
+	}
+
+	a.writeAccount(c, http.StatusOK, account)
+}
+
+// writeAccount выдает cookie с токеном аккаунта и отвечает данными аккаунта.
+func (a *App) writeAccount(c *gin.Context, code int, account *models.Account) {
+	if err := auth.SetTokenCookie(c, a.keys, a.tokenTTL(), account.UserID); err != nil {
+		a.logger.Errorf("Error building token: %v", err)
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(code, account)
+}
+
+// tokenTTL возвращает время жизни токенов из конфигурации.
+func (a *App) tokenTTL() auth.TokenTTL {
+	return auth.TokenTTL{Access: a.config.AccessTokenTTL, Refresh: a.config.RefreshTokenTTL}
+}
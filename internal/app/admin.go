@@ -0,0 +1,84 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+func (a *App) SearchLinks(c *gin.Context) {
+	filter := models.LinkFilter{
+		Slug:   c.Query("slug"),
+		URL:    c.Query("url"),
+		UserID: c.Query("user_id"),
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	links, err := a.coreLogic.SearchLinks(c, filter)
+	if err != nil {
+		a.writeError(c, "Error searching links", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+func (a *App) DisableLink(c *gin.Context) {
+	a.setLinkDisabled(c, true)
+}
+
+func (a *App) EnableLink(c *gin.Context) {
+	a.setLinkDisabled(c, false)
+}
+
+func (a *App) setLinkDisabled(c *gin.Context, disabled bool) {
+	if err := a.coreLogic.SetLinkDisabled(c, c.Param("id"), disabled); err != nil {
+		a.writeError(c, "Error updating link", err)
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) TransferLinks(c *gin.Context) {
+	var req models.TransferReq
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		a.logger.Errorf(ErrorDecodeBody, err)
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	count, err := a.coreLogic.TransferLinks(c, c.Param("id"), req.UserID)
+	if err != nil {
+		a.writeError(c, "Error transferring links", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TransferRes{Transferred: count})
+}
+
+func (a *App) PurgeUser(c *gin.Context) {
+	count, err := a.coreLogic.PurgeUser(c, c.Param("id"))
+	if err != nil {
+		a.writeError(c, "Error purging user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PurgeRes{Deleted: count})
+}
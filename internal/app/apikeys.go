@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+func (a *App) CreateAPIKey(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	var req models.APIKeyReq
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		a.logger.Errorf(ErrorDecodeBody, err)
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	key, err := a.coreLogic.CreateAPIKey(c, userID, req)
+	if err != nil {
+		a.writeError(c, "Error creating api key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+func (a *App) GetAPIKeys(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	keys, err := a.coreLogic.GetAPIKeys(c, userID)
+	if err != nil {
+		a.logger.Errorf("Error getting api keys: %v", err)
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (a *App) RevokeAPIKey(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	if err := a.coreLogic.RevokeAPIKey(c, userID, c.Param("id")); err != nil {
+		a.writeError(c, "Error revoking api key", err)
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
@@ -1,14 +1,15 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/rawen554/shortener/internal/config"
+	"github.com/rawen554/shortener/internal/health"
 	"github.com/rawen554/shortener/internal/logic"
 	"github.com/rawen554/shortener/internal/middleware/auth"
 	"github.com/rawen554/shortener/internal/models"
@@ -21,10 +22,22 @@ const (
 	textPlain       = "text/plain"
 	contentType     = "Content-Type"
 	location        = "Location"
+	cacheControl    = "Cache-Control"
+	noCache         = "private, no-cache"
+
+	variantCookiePrefix = "ab_"
+	variantCookieMaxAge = 3600 * 24 * 30
 
 	rootPath       = "/"
 	pingPath       = "/ping"
+	jwksPath       = "/.well-known/jwks.json"
+	healthzPath    = "/healthz"
+	readyzPath     = "/readyz"
+	openAPIPath    = "/api/openapi.json"
+	docsPath       = "/api/docs"
+	docsAssetPath  = docsPath + "/:asset"
 	apiShortenPath = "/api/shorten"
+	apiV2Path      = "/api/v2"
 
 	ErrorJoinURL     = "URL cannot be joined: %v"
 	ErrorDecodeBody  = "Body cannot be decoded: %v"
@@ -33,27 +46,56 @@ const (
 )
 
 type Store interface {
-	Get(id string) (string, error)
+	Get(id string) (*models.Link, error)
 	GetStats() (*models.Stats, error)
 	GetAllByUserID(userID string) ([]models.URLRecord, error)
-	DeleteMany(ids models.DeleteUserURLsReq, userID string) error
-	Put(id string, shortURL string, userID string) (string, error)
+	DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error)
+	Put(id string, shortURL string, userID string, opts models.LinkOptions) (string, error)
 	PutBatch(data []models.URLBatchReq, userID string) ([]models.URLBatchRes, error)
-	Ping() error
+	SetRules(id string, rules []models.RedirectRule) error
+	RecordClick(id string, variant string) error
+	GetClicks(id string) (*models.ClickStats, error)
+	PutWebhook(hook models.Webhook) error
+	GetWebhooks(userID string) ([]models.Webhook, error)
+	UpdateWebhook(hook models.Webhook) error
+	DeleteWebhook(id string) error
+	PutWebhookDelivery(delivery models.WebhookDelivery) error
+	GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error)
+	SearchLinks(filter models.LinkFilter) ([]models.Link, error)
+	SetDisabled(id string, disabled bool) error
+	TransferLinks(fromUserID string, toUserID string) (int, error)
+	PurgeUser(userID string) (int, error)
+	PutAPIKey(key models.APIKey) error
+	GetAPIKeys(userID string) ([]models.APIKey, error)
+	GetAPIKeyByHash(hash string) (*models.APIKey, error)
+	DeleteAPIKey(id string) error
+	CreateUser(user models.User) error
+	GetUserByLogin(login string) (*models.User, error)
+	GetUserByID(id string) (*models.User, error)
+	Ping(ctx context.Context) error
 }
 
 type App struct {
 	config    *config.ServerConfig
 	logger    *zap.SugaredLogger
 	coreLogic *logic.CoreLogic
+	keys      *auth.Keyring
+	health    *health.Checker
+	openAPI   *openAPIDocument
 }
 
 func NewApp(config *config.ServerConfig, coreLogic *logic.CoreLogic, logger *zap.SugaredLogger) *App {
-	return &App{
+	a := &App{
 		config:    config,
 		coreLogic: coreLogic,
 		logger:    logger,
+		health:    health.NewChecker(),
 	}
+	a.health.AddCheck("store", func(ctx context.Context) error {
+		return a.coreLogic.Ping(ctx)
+	})
+
+	return a
 }
 
 func (a *App) DeleteUserRecords(c *gin.Context) {
@@ -77,47 +119,111 @@ func (a *App) DeleteUserRecords(c *gin.Context) {
 	res.WriteHeader(http.StatusAccepted)
 }
 
+// GetUserRecords отвечает списком ссылок пользователя. Без параметра limit
+// возвращаются все ссылки, курсор следующей страницы передается в заголовках.
 func (a *App) GetUserRecords(c *gin.Context) {
-	res := c.Writer
+	query, cursor, err := parseListQuery(c, 0)
+	if err != nil {
+		a.writeError(c, "Error parsing list query", err)
+		return
+	}
+
+	links, next, err := a.coreLogic.ListUserLinks(c, c.GetString(auth.UserIDKey), query, cursor)
+	if err != nil {
+		a.writeError(c, "Error getting all user urls", err)
+		return
+	}
+
+	setNextPage(c, next)
+	if len(links) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+func (a *App) GetLinkRules(c *gin.Context) {
 	userID := c.GetString(auth.UserIDKey)
 
-	records, err := a.coreLogic.GetUserRecords(c, userID)
+	rules, err := a.coreLogic.GetRules(c, userID, c.Param("id"))
 	if err != nil {
-		if errors.Is(err, logic.ErrNoContent) {
-			res.WriteHeader(http.StatusNoContent)
-			return
-		}
+		a.writeError(c, "Error getting link rules", err)
+		return
+	}
 
-		a.logger.Errorf("Error getting all user urls: %v", err)
-		res.WriteHeader(http.StatusInternalServerError)
+	c.JSON(http.StatusOK, rules)
+}
+
+func (a *App) SetLinkRules(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	rules := make([]models.RedirectRule, 0)
+	if err := json.NewDecoder(c.Request.Body).Decode(&rules); err != nil {
+		a.logger.Errorf(ErrorDecodeBody, err)
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := a.coreLogic.SetRules(c, userID, c.Param("id"), rules); err != nil {
+		a.writeError(c, "Error saving link rules", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, records)
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) GetLinkStats(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	stats, err := a.coreLogic.GetLinkStats(c, userID, c.Param("id"))
+	if err != nil {
+		a.writeError(c, "Error getting link stats", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
 }
 
 func (a *App) RedirectToOriginal(c *gin.Context) {
-	res := c.Writer
-	id := c.Param("id")
+	id, preview := isPreviewRequest(c)
+	if preview {
+		a.PreviewLink(c, id)
+		return
+	}
 
-	originalURL, err := a.coreLogic.GetOriginalURL(c, id)
+	// ошибка означает отсутствие cookie, тогда вариант будет выбран заново
+	variant, _ := c.Cookie(variantCookiePrefix + id)
+
+	redirect, err := a.coreLogic.GetOriginalURL(c, &models.RedirectReq{
+		ShortURL:  id,
+		Query:     c.Request.URL.Query(),
+		UserAgent: c.Request.UserAgent(),
+		Variant:   variant,
+	})
 	if err != nil {
-		if errors.Is(err, logic.ErrIsDeleted) {
-			res.WriteHeader(http.StatusGone)
-			return
-		}
+		a.writeError(c, "Error getting original URL", err)
+		return
+	}
 
-		if errors.Is(err, logic.ErrNotFound) {
-			res.WriteHeader(http.StatusNotFound)
-			return
-		}
+	if redirect.Sticky {
+		c.SetCookie(variantCookiePrefix+id, redirect.Variant, variantCookieMaxAge, rootPath+id, "", false, true)
+	}
 
-		a.logger.Errorf("Error getting original URL: %v", err)
-		res.WriteHeader(http.StatusInternalServerError)
+	if redirect.Preview != nil {
+		a.renderPreview(c, redirect.Preview, true)
 		return
 	}
 
-	c.Redirect(http.StatusTemporaryRedirect, originalURL)
+	if models.IsPermanentRedirect(redirect.RedirectType) {
+		if a.config.CacheControl != "" {
+			c.Header(cacheControl, a.config.CacheControl)
+		}
+	} else {
+		c.Header(cacheControl, noCache)
+	}
+
+	c.Redirect(redirect.RedirectType, redirect.URL)
 }
 
 func (a *App) ShortenBatch(c *gin.Context) {
@@ -134,8 +240,7 @@ func (a *App) ShortenBatch(c *gin.Context) {
 
 	result, err := a.coreLogic.ShortenBatch(c, userID, batch)
 	if err != nil {
-		a.logger.Errorf("Cant put batch: %v", err)
-		res.WriteHeader(http.StatusInternalServerError)
+		a.writeError(c, "Cant put batch", err)
 		return
 	}
 
@@ -148,6 +253,7 @@ func (a *App) ShortenURL(c *gin.Context) {
 	userID := c.GetString(auth.UserIDKey)
 
 	var originalURL string
+	var opts models.LinkOptions
 
 	switch req.RequestURI {
 	case apiShortenPath:
@@ -158,6 +264,7 @@ func (a *App) ShortenURL(c *gin.Context) {
 			return
 		}
 		originalURL = shorten.URL
+		opts = shorten.LinkOptions
 	case rootPath:
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
@@ -168,15 +275,9 @@ func (a *App) ShortenURL(c *gin.Context) {
 		originalURL = string(body)
 	}
 
-	resultURL, err := a.coreLogic.ShortenURL(c, userID, originalURL)
+	resultURL, err := a.coreLogic.ShortenURL(c, userID, originalURL, opts)
 	if err != nil {
-		if errors.Is(err, logic.ErrConflict) {
-			res.WriteHeader(http.StatusConflict)
-			return
-		}
-
-		a.logger.Errorf("Error saving data: %v", err)
-		res.WriteHeader(http.StatusInternalServerError)
+		a.writeError(c, "Error saving data", err)
 		return
 	}
 
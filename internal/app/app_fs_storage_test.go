@@ -6,12 +6,17 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/rawen554/shortener/internal/config"
 	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
 	"github.com/rawen554/shortener/internal/models"
 	"github.com/rawen554/shortener/internal/store/fs"
 	"github.com/stretchr/testify/assert"
@@ -24,6 +29,16 @@ var testConfig = &config.ServerConfig{
 	Secret:  "b4952c3809196592c026529df00774e46bfb5be0",
 }
 
+// testKeyring возвращает ключи, которыми подписывает токены приложение с testConfig.
+func testKeyring(t *testing.T) *auth.Keyring {
+	t.Helper()
+	keys, err := auth.NewSecretKeyring(testConfig.Secret)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	return keys
+}
+
 const (
 	ErrorDeletingTestFile = "error deleting test file: %v"
 	ErrorStoringRecord    = "error storing value: %v"
@@ -86,7 +101,7 @@ func Test_redirectToOriginal(t *testing.T) {
 			}()
 
 			for url := range tt.args.urls {
-				if _, err := storage.Put(url, tt.args.urls[url], ""); err != nil {
+				if _, err := storage.Put(url, tt.args.urls[url], "", models.LinkOptions{}); err != nil {
 					t.Errorf(ErrorStoringRecord, err)
 				}
 			}
@@ -160,7 +175,7 @@ func Test_shortURL_V1(t *testing.T) {
 			}()
 
 			for url := range tt.args.urls {
-				if _, err := storage.Put(url, tt.args.urls[url], ""); err != nil {
+				if _, err := storage.Put(url, tt.args.urls[url], "", models.LinkOptions{}); err != nil {
 					t.Errorf(ErrorStoringRecord, err)
 				}
 			}
@@ -232,7 +247,7 @@ func Test_shortURL_V2(t *testing.T) {
 			}()
 
 			for url := range tt.args.urls {
-				if _, err := storage.Put(url, tt.args.urls[url], ""); err != nil {
+				if _, err := storage.Put(url, tt.args.urls[url], "", models.LinkOptions{}); err != nil {
 					t.Errorf(ErrorStoringRecord, err)
 				}
 			}
@@ -264,3 +279,190 @@ func Test_shortURL_V2(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_RestartFS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var (
+		storage   *fs.FSStorage
+		coreLogic *logic.CoreLogic
+		r         *gin.Engine
+	)
+	start := func() {
+		var err error
+		storage, err = fs.NewFileStorage(TestStoragePath)
+		require.NoError(t, err)
+		coreLogic = logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+		r, err = NewApp(testConfig, coreLogic, zap.L().Sugar()).SetupRouter()
+		require.NoError(t, err)
+	}
+	// restart останавливает приложение и запускает его заново с хранилищем из того же файла
+	restart := func() {
+		coreLogic.Close()
+		storage.Close()
+		start()
+	}
+
+	start()
+	defer func() {
+		coreLogic.Close()
+		storage.Close()
+		if err := storage.DeleteStorageFile(); err != nil {
+			t.Errorf(ErrorDeletingTestFile, err)
+		}
+	}()
+
+	// do выполняет запрос к приложению; credential — cookie с токеном
+	// или ключ API, если начинается с "Bearer "
+	do := func(credential string, method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(contentType, applicationJSON)
+		if strings.HasPrefix(credential, "Bearer ") {
+			req.Header.Set("Authorization", credential)
+		} else if credential != "" {
+			req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: credential})
+		}
+		r.ServeHTTP(w, req)
+		require.NoError(t, w.Result().Body.Close())
+		return w
+	}
+
+	w := do("", http.MethodPost, "/api/user/register", `{"login": "alice", "password": "correct horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var account models.Account
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+	token, err := auth.BuildJWTString(testKeyring(t), account.UserID, auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	t.Run("users", func(t *testing.T) {
+		restart()
+
+		w := do("", http.MethodPost, "/api/user/login", `{"login": "alice", "password": "correct horse"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var restored models.Account
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &restored))
+		assert.Equal(t, account.UserID, restored.UserID)
+
+		w = do("", http.MethodPost, "/api/user/register", `{"login": "alice", "password": "another one"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("api keys", func(t *testing.T) {
+		createKey := func() models.APIKeyRes {
+			w := do(token, http.MethodPost, "/api/user/keys", `{"name": "ci"}`)
+			require.Equal(t, http.StatusCreated, w.Code)
+			var key models.APIKeyRes
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &key))
+			return key
+		}
+		kept, revoked := createKey(), createKey()
+		w := do(token, http.MethodDelete, "/api/user/keys/"+revoked.ID, "")
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		restart()
+
+		shorten := func(key string) int {
+			return do("Bearer "+key, http.MethodPost, "/api/shorten", `{"url": "https://ya.ru"}`).Code
+		}
+		assert.Equal(t, http.StatusCreated, shorten(kept.Key))
+		assert.Equal(t, http.StatusUnauthorized, shorten(revoked.Key))
+
+		w = do(token, http.MethodGet, "/api/user/keys", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var keys []models.APIKey
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+		require.Len(t, keys, 1)
+		assert.Equal(t, kept.ID, keys[0].ID)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		create := func() models.Webhook {
+			w := do(token, http.MethodPost, "/api/user/webhooks", `{"url": "https://93.184.216.34/hook", "events": ["link.deleted"]}`)
+			require.Equal(t, http.StatusCreated, w.Code)
+			var hook models.Webhook
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hook))
+			return hook
+		}
+		kept, deleted := create(), create()
+		require.NoError(t, storage.PutWebhookDelivery(models.WebhookDelivery{
+			ID: "1", WebhookID: kept.ID, Event: models.EventLinkDeleted, Status: http.StatusBadGateway, Attempts: 3,
+		}))
+		require.NoError(t, storage.UpdateWebhook(models.Webhook{
+			ID: kept.ID, Failures: 3, LastError: "status 502", Disabled: true,
+		}))
+		w := do(token, http.MethodDelete, "/api/user/webhooks/"+deleted.ID, "")
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		restart()
+
+		w = do(token, http.MethodGet, "/api/user/webhooks", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var hooks []models.Webhook
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hooks))
+		require.Len(t, hooks, 1)
+		assert.Equal(t, kept.ID, hooks[0].ID)
+		assert.Equal(t, kept.URL, hooks[0].URL)
+		assert.Equal(t, []string{models.EventLinkDeleted}, hooks[0].Events)
+		assert.Equal(t, 3, hooks[0].Failures)
+		assert.True(t, hooks[0].Disabled)
+
+		w = do(token, http.MethodGet, "/api/user/webhooks/"+kept.ID+"/deliveries", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var deliveries []models.WebhookDelivery
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, http.StatusBadGateway, deliveries[0].Status)
+	})
+
+	t.Run("deleted links", func(t *testing.T) {
+		w := do(token, http.MethodPost, "/api/shorten", `{"url": "https://go.dev/deleted"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var res models.ShortenRes
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		id := path.Base(res.Result)
+
+		require.Equal(t, http.StatusAccepted, do(token, http.MethodDelete, "/api/user/urls", `["`+id+`"]`).Code)
+		require.Eventually(t, func() bool {
+			return do("", http.MethodGet, "/"+id, "").Code == http.StatusGone
+		}, time.Second, 10*time.Millisecond)
+
+		restart()
+
+		assert.Equal(t, http.StatusGone, do("", http.MethodGet, "/"+id, "").Code)
+		w = do(token, http.MethodGet, "/api/user/urls?deleted=true", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var links []models.UserLink
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &links))
+		require.Len(t, links, 1)
+		assert.Equal(t, id, links[0].ShortURL)
+		assert.True(t, links[0].Deleted)
+	})
+
+	t.Run("clicks", func(t *testing.T) {
+		w := do(token, http.MethodPost, "/api/shorten",
+			`{"url": "https://ya.ru", "variants": [{"id": "a", "url": "https://ya.ru/a", "weight": 1}]}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var res models.ShortenRes
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		id := path.Base(res.Result)
+		for i := 0; i < 2; i++ {
+			require.Equal(t, http.StatusTemporaryRedirect, do("", http.MethodGet, "/"+id, "").Code)
+		}
+
+		restart()
+
+		// переходы сохраняются одной строкой со счетчиками при закрытии хранилища
+		data, err := os.ReadFile(TestStoragePath)
+		require.NoError(t, err)
+		assert.Equal(t, 1, bytes.Count(data, []byte(`"kind":"click","short_url":"`+id+`"`)))
+
+		w = do(token, http.MethodGet, "/api/user/urls/"+id+"/stats", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var stats models.LinkStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		assert.Equal(t, int64(2), stats.Clicks)
+		require.Len(t, stats.Variants, 1)
+		assert.Equal(t, int64(2), stats.Variants[0].Clicks)
+	})
+}
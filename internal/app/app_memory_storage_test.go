@@ -1,19 +1,28 @@
 package app
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/rawen554/shortener/internal/config"
 	"github.com/rawen554/shortener/internal/logic"
 	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/middleware/requestid"
 	"github.com/rawen554/shortener/internal/models"
 	"github.com/rawen554/shortener/internal/store/memory"
 	"github.com/stretchr/testify/assert"
@@ -82,7 +91,7 @@ func Test_DeleteManyInMemory(t *testing.T) {
 			req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", bytes.NewBuffer(obj))
 			req.Header.Add(contentType, applicationJSON)
 
-			token, err := auth.BuildJWTString(testConfig.Secret, tt.userID)
+			token, err := auth.BuildJWTString(testKeyring(t), tt.userID, auth.DefaultTokenTTL.Access)
 			if err != nil {
 				t.Error(err)
 			}
@@ -174,7 +183,7 @@ func TestApp_GetUserRecordsInMemory(t *testing.T) {
 				t.Error(err)
 			}
 
-			token, err := auth.BuildJWTString(testConfig.Secret, tt.args.userID)
+			token, err := auth.BuildJWTString(testKeyring(t), tt.args.userID, auth.DefaultTokenTTL.Access)
 			if err != nil {
 				t.Error(err)
 			}
@@ -347,3 +356,1564 @@ func TestApp_ShortenBatchInMemory(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_RedirectTypeInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		record           models.URLRecordMemory
+		wantCode         int
+		wantCacheControl string
+	}{
+		{
+			name:             "service default",
+			record:           models.URLRecordMemory{OriginalURL: "https://ya.ru", UserID: "1"},
+			wantCode:         http.StatusTemporaryRedirect,
+			wantCacheControl: noCache,
+		},
+		{
+			name: "permanent link",
+			record: models.URLRecordMemory{
+				OriginalURL: "https://ya.ru",
+				UserID:      "1",
+				LinkOptions: models.LinkOptions{RedirectType: http.StatusPermanentRedirect},
+			},
+			wantCode:         http.StatusPermanentRedirect,
+			wantCacheControl: "public, max-age=600",
+		},
+		{
+			name: "temporary link",
+			record: models.URLRecordMemory{
+				OriginalURL: "https://ya.ru",
+				UserID:      "1",
+				LinkOptions: models.LinkOptions{RedirectType: http.StatusFound},
+			},
+			wantCode:         http.StatusFound,
+			wantCacheControl: noCache,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{"1": tt.record})
+			if err != nil {
+				t.Errorf(ErrorSetupStorage, err)
+				return
+			}
+
+			conf := *testConfig
+			conf.CacheControl = "public, max-age=600"
+			coreLogic := logic.NewCoreLogic(&conf, storage, zap.L().Sugar())
+			testApp := NewApp(&conf, coreLogic, zap.L().Sugar())
+			r, err := testApp.SetupRouter()
+			if err != nil {
+				t.Errorf(ErrorSetupRouter, err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/1", nil)
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			if err := res.Body.Close(); err != nil {
+				t.Errorf(ErrorClosingBody, err)
+			}
+
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+			assert.Equal(t, tt.record.OriginalURL, res.Header.Get(location))
+			assert.Equal(t, tt.wantCacheControl, res.Header.Get(cacheControl))
+		})
+	}
+}
+
+func TestApp_ShortenWithRedirectTypeInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		req      models.ShortenReq
+		wantCode int
+	}{
+		{
+			name: "permanent",
+			req: models.ShortenReq{
+				URL:         "https://ya.ru",
+				LinkOptions: models.LinkOptions{RedirectType: http.StatusMovedPermanently},
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "unsupported redirect type",
+			req: models.ShortenReq{
+				URL:         "https://ya.ru",
+				LinkOptions: models.LinkOptions{RedirectType: http.StatusOK},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+			if err != nil {
+				t.Errorf(ErrorSetupStorage, err)
+				return
+			}
+
+			coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+			testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+			r, err := testApp.SetupRouter()
+			if err != nil {
+				t.Errorf(ErrorSetupRouter, err)
+			}
+
+			obj, err := json.Marshal(tt.req)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, apiShortenPath, bytes.NewBuffer(obj))
+			req.Header.Add(contentType, applicationJSON)
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			if err := res.Body.Close(); err != nil {
+				t.Errorf(ErrorClosingBody, err)
+			}
+
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+		})
+	}
+}
+
+func TestApp_LinkRulesInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const (
+		userID  = "1"
+		iosURL  = "https://apps.apple.com/app/id1"
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X)"
+		desktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
+	)
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"1": {OriginalURL: "https://ya.ru", UserID: userID},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	token, err := auth.BuildJWTString(testKeyring(t), userID, auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	rules := []models.RedirectRule{{Platform: models.PlatformIOS, URL: iosURL}}
+	obj, err := json.Marshal(rules)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/user/urls/1/rules", bytes.NewBuffer(obj))
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/api/user/urls/2/rules", bytes.NewBuffer(obj))
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{name: "matched rule", userAgent: iPhone, want: iosURL},
+		{name: "fallback to original", userAgent: desktop, want: "https://ya.ru"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/1", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get(location))
+		})
+	}
+}
+
+func TestApp_StickyVariantInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const userID = "1"
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"1": {
+			OriginalURL: "https://ya.ru",
+			UserID:      userID,
+			LinkOptions: models.LinkOptions{
+				Sticky: true,
+				Variants: []models.Variant{
+					{ID: "a", URL: "https://a.ya.ru", Weight: 1},
+					{ID: "b", URL: "https://b.ya.ru", Weight: 1},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/1", nil))
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+	var variantCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == variantCookiePrefix+"1" {
+			variantCookie = cookie
+		}
+	}
+	require.NotNil(t, variantCookie)
+	first := w.Header().Get(location)
+
+	for i := 0; i < 5; i++ {
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/1", nil)
+		req.AddCookie(variantCookie)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, first, w.Header().Get(location))
+	}
+
+	token, err := auth.BuildJWTString(testKeyring(t), userID, auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls/1/stats", nil)
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats models.LinkStats
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	assert.Equal(t, int64(6), stats.Clicks)
+	require.Len(t, stats.Variants, 2)
+	for _, v := range stats.Variants {
+		if v.ID == variantCookie.Value {
+			assert.Equal(t, int64(6), v.Clicks)
+		} else {
+			assert.Equal(t, int64(0), v.Clicks)
+		}
+	}
+}
+
+func TestApp_PreviewInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"1": {
+			OriginalURL: "https://ya.ru/?q=<b>",
+			UserID:      "1",
+			LinkOptions: models.LinkOptions{Title: "Search"},
+		},
+		"2": {
+			OriginalURL: "https://ya.ru",
+			UserID:      "1",
+			LinkOptions: models.LinkOptions{Interstitial: true},
+		},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	tests := []struct {
+		name          string
+		url           string
+		wantCode      int
+		wantContains  []string
+		wantCountdown bool
+	}{
+		{
+			name:         "preview suffix",
+			url:          "/1+",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"Search", "https://ya.ru/?q=&lt;b&gt;"},
+		},
+		{
+			name:         "preview param",
+			url:          "/1?preview=1",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"Search"},
+		},
+		{
+			name:          "interstitial",
+			url:           "/2",
+			wantCode:      http.StatusOK,
+			wantContains:  []string{"https://ya.ru"},
+			wantCountdown: true,
+		},
+		{
+			name:     "preview of unknown link",
+			url:      "/3+",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Empty(t, w.Header().Get(location))
+			body := w.Body.String()
+			for _, s := range tt.wantContains {
+				assert.Contains(t, body, s)
+			}
+			assert.Equal(t, tt.wantCountdown, strings.Contains(body, `id="countdown"`))
+		})
+	}
+}
+
+func TestApp_QRCodeInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"1": {OriginalURL: "https://ya.ru", UserID: "1"},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	tests := []struct {
+		name            string
+		url             string
+		wantCode        int
+		wantContentType string
+	}{
+		{name: "png", url: "/1/qr", wantCode: http.StatusOK, wantContentType: "image/png"},
+		{name: "svg", url: "/1/qr?format=svg&size=512&level=h&fg=%23112233", wantCode: http.StatusOK, wantContentType: "image/svg+xml"},
+		{name: "bad size", url: "/1/qr?size=abc", wantCode: http.StatusBadRequest},
+		{name: "unknown link", url: "/2/qr", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantContentType, w.Header().Get(contentType))
+			assert.NotEmpty(t, w.Header().Get(etag))
+
+			w2 := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set(ifNoneMatch, w.Header().Get(etag))
+			r.ServeHTTP(w2, req)
+			assert.Equal(t, http.StatusNotModified, w2.Code)
+		})
+	}
+}
+
+func TestApp_WebhooksInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const userID = "1"
+
+	events := make(chan models.WebhookEvent, 10)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event models.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			events <- event
+		}
+	}))
+	defer hookSrv.Close()
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	// получатель событий слушает loopback
+	config := *testConfig
+	config.AllowLoopback = true
+	coreLogic := logic.NewCoreLogic(&config, storage, zap.L().Sugar())
+	testApp := NewApp(&config, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	token, err := auth.BuildJWTString(testKeyring(t), userID, auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	do := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/user/webhooks", `{"url": "ftp://example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(http.MethodPost, "/api/user/webhooks", `{"url": "https://example.com", "events": ["link.updated"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	for _, target := range []string{"http://169.254.169.254/latest", "http://10.0.0.1/", "http://[fd00::1]/"} {
+		w = do(http.MethodPost, "/api/user/webhooks", `{"url": "`+target+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	w = do(http.MethodPost, "/api/user/webhooks", `{"url": "`+hookSrv.URL+`", "events": ["link.created"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var hook models.Webhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hook))
+	assert.NotEmpty(t, hook.ID)
+	assert.NotEmpty(t, hook.Secret)
+
+	w = do(http.MethodGet, "/api/user/webhooks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var hooks []models.Webhook
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hooks))
+	require.Len(t, hooks, 1)
+	assert.Empty(t, hooks[0].Secret)
+
+	w = do(http.MethodPost, "/api/shorten", `{"url": "https://ya.ru"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, models.EventLinkCreated, event.Type)
+		assert.Equal(t, "https://ya.ru", event.Data.OriginalURL)
+	case <-time.After(time.Second * 5):
+		t.Fatal("webhook event was not delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		w := do(http.MethodGet, "/api/user/webhooks/"+hook.ID+"/deliveries", "")
+		var deliveries []models.WebhookDelivery
+		return w.Code == http.StatusOK &&
+			json.Unmarshal(w.Body.Bytes(), &deliveries) == nil &&
+			len(deliveries) == 1 && deliveries[0].Success
+	}, time.Second*5, time.Millisecond*10)
+
+	w = do(http.MethodGet, "/api/user/webhooks/unknown/deliveries", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	t.Run("deleted event only for deleted links", func(t *testing.T) {
+		w := do(http.MethodPost, "/api/user/webhooks", `{"url": "`+hookSrv.URL+`", "events": ["link.deleted"]}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		_, err := storage.Put("foreign", "https://go.dev/", "2", models.LinkOptions{})
+		require.NoError(t, err)
+		_, err = storage.Put("own", "https://go.dev/doc", userID, models.LinkOptions{})
+		require.NoError(t, err)
+
+		w = do(http.MethodDelete, "/api/user/urls", `["foreign", "missing", "own"]`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		select {
+		case event := <-events:
+			assert.Equal(t, models.EventLinkDeleted, event.Type)
+			ownURL, err := url.JoinPath(testConfig.RedirectBaseURL, "own")
+			require.NoError(t, err)
+			assert.Equal(t, ownURL, event.Data.ShortURL)
+		case <-time.After(time.Second * 5):
+			t.Fatal("webhook event was not delivered")
+		}
+		select {
+		case event := <-events:
+			t.Fatalf("unexpected event for %s", event.Data.ShortURL)
+		case <-time.After(time.Millisecond * 100):
+		}
+
+		link, err := storage.Get("foreign")
+		require.NoError(t, err)
+		assert.NotNil(t, link)
+	})
+
+	w = do(http.MethodDelete, "/api/user/webhooks/"+hook.ID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodDelete, "/api/user/webhooks/"+hook.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	coreLogic.Close()
+}
+
+func TestApp_AdminInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"aaaa": {OriginalURL: "https://ya.ru/news", UserID: "1"},
+		"bbbb": {OriginalURL: "https://go.dev", UserID: "1"},
+		"cccc": {OriginalURL: "https://ya.ru/maps", UserID: "2"},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	conf := *testConfig
+	conf.TrustedSubnet = "192.168.0.0/24"
+	coreLogic := logic.NewCoreLogic(&conf, storage, zap.L().Sugar())
+	testApp := NewApp(&conf, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	do := func(method string, path string, body string, realIP string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Real-IP", realIP)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	const trusted = "192.168.0.1"
+
+	w := do(http.MethodGet, "/api/internal/links", "", "10.0.0.1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	search := func(query string) []models.Link {
+		w := do(http.MethodGet, "/api/internal/links?"+query, "", trusted)
+		require.Equal(t, http.StatusOK, w.Code)
+		var links []models.Link
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &links))
+		return links
+	}
+	assert.Len(t, search(""), 3)
+	assert.Len(t, search("url=YA.RU"), 2)
+	assert.Len(t, search("user_id=1"), 2)
+	assert.Len(t, search("slug=bb"), 1)
+	assert.Len(t, search("limit=1"), 1)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/internal/links?limit=x", "", trusted).Code)
+
+	w = do(http.MethodPost, "/api/internal/links/aaaa/disable", "", trusted)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodGet, "/aaaa", "", "")
+	assert.Equal(t, http.StatusGone, w.Code)
+	w = do(http.MethodPost, "/api/internal/links/aaaa/enable", "", trusted)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodGet, "/aaaa", "", "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	w = do(http.MethodPost, "/api/internal/links/zzzz/disable", "", trusted)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodPost, "/api/internal/users/1/transfer", `{"user_id": "1"}`, trusted)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(http.MethodPost, "/api/internal/users/1/transfer", `{"user_id": "2"}`, trusted)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"transferred": 2}`, w.Body.String())
+	assert.Len(t, search("user_id=2"), 3)
+
+	w = do(http.MethodDelete, "/api/internal/users/2", "", trusted)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deleted": 3}`, w.Body.String())
+	assert.Empty(t, search(""))
+}
+
+func TestApp_APIKeysInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const userID = "1"
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"aaaa": {OriginalURL: "https://ya.ru", UserID: userID},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	token, err := auth.BuildJWTString(testKeyring(t), userID, auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	withCookie := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+		r.ServeHTTP(w, req)
+		return w
+	}
+	withKey := func(method string, path string, body string, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	createKey := func(body string) models.APIKeyRes {
+		w := withCookie(http.MethodPost, "/api/user/keys", body)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var key models.APIKeyRes
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &key))
+		return key
+	}
+
+	assert.Equal(t, http.StatusBadRequest, withCookie(http.MethodPost, "/api/user/keys", `{"scope": "admin"}`).Code)
+
+	writeKey := createKey(`{"name": "ci"}`)
+	assert.Equal(t, models.ScopeWrite, writeKey.Scope)
+	assert.True(t, strings.HasPrefix(writeKey.Key, writeKey.Prefix))
+	readKey := createKey(`{"name": "reports", "scope": "read"}`)
+
+	w := withCookie(http.MethodGet, "/api/user/keys", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), writeKey.Key)
+	assert.NotContains(t, w.Body.String(), readKey.Key)
+
+	for _, key := range []string{writeKey.Key, readKey.Key} {
+		w = withKey(http.MethodGet, "/api/user/urls", "", key)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "https://ya.ru")
+		assert.Empty(t, w.Result().Cookies())
+		require.NoError(t, w.Result().Body.Close())
+	}
+
+	w = withKey(http.MethodPost, "/api/shorten", `{"url": "https://go.dev"}`, readKey.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = withKey(http.MethodPost, "/api/shorten", `{"url": "https://go.dev"}`, writeKey.Key)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = withKey(http.MethodGet, "/api/user/urls", "", "shk_unknown")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = withCookie(http.MethodDelete, "/api/user/keys/"+writeKey.ID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = withKey(http.MethodGet, "/api/user/urls", "", writeKey.Key)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = withCookie(http.MethodDelete, "/api/user/keys/"+writeKey.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestApp_AccountsInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	// do выполняет запрос с cookie клиента и сохраняет выданную сервером cookie
+	do := func(cookie *string, method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if *cookie != "" {
+			req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: *cookie})
+		}
+		r.ServeHTTP(w, req)
+
+		res := w.Result()
+		for _, c := range res.Cookies() {
+			if c.Name == auth.CookieName {
+				*cookie = c.Value
+			}
+		}
+		require.NoError(t, res.Body.Close())
+		return w
+	}
+	userOf := func(cookie string) string {
+		userID, err := auth.GetUserID(cookie, testKeyring(t))
+		require.NoError(t, err)
+		return userID
+	}
+
+	var laptop, phone string
+	w := do(&laptop, http.MethodPost, "/api/shorten", `{"url": "https://ya.ru"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	anonymousID := userOf(laptop)
+
+	w = do(&laptop, http.MethodPost, "/api/user/register", `{"login": "alice", "password": "short"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(&laptop, http.MethodPost, "/api/user/register", `{"login": "alice", "password": "correct horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var account models.Account
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+	assert.Equal(t, anonymousID, account.UserID)
+	assert.Equal(t, anonymousID, userOf(laptop))
+
+	w = do(&phone, http.MethodPost, "/api/user/register", `{"login": "alice", "password": "another one"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = do(&phone, http.MethodPost, "/api/shorten", `{"url": "https://go.dev"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = do(&phone, http.MethodPost, "/api/user/login", `{"login": "alice", "password": "wrong password"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(&phone, http.MethodPost, "/api/user/login", `{"login": "alice", "password": "correct horse"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+	assert.Equal(t, 1, account.Merged)
+	assert.Equal(t, anonymousID, userOf(phone))
+
+	w = do(&phone, http.MethodGet, "/api/user/urls", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var records []models.URLRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+	assert.Len(t, records, 2)
+
+	t.Run("concurrent register", func(t *testing.T) {
+		const clients = 5
+		codes := make(chan int, clients)
+		var wg sync.WaitGroup
+		for i := 0; i < clients; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var cookie string
+				codes <- do(&cookie, http.MethodPost, "/api/user/register", `{"login": "bob", "password": "correct horse"}`).Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		created := 0
+		for code := range codes {
+			if code == http.StatusCreated {
+				created++
+				continue
+			}
+			assert.Equal(t, http.StatusConflict, code)
+		}
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("store conflicts", func(t *testing.T) {
+		err := storage.CreateUser(models.User{ID: uuid.New().String(), Login: "alice"})
+		assert.ErrorIs(t, err, models.ErrUserConflict)
+
+		err = storage.CreateUser(models.User{ID: anonymousID, Login: "carol"})
+		assert.ErrorIs(t, err, models.ErrUserConflict)
+	})
+}
+
+func TestApp_JWKSInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, jwksPath, http.NoBody)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// ключ HS256 из секрета не публикуется
+	assert.JSONEq(t, `{"keys": []}`, w.Body.String())
+	// ключи запрашивают проверяющие сервисы, им не нужна сессия пользователя
+	assert.Empty(t, w.Header().Values("Set-Cookie"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, jwksPath, http.NoBody)
+	req.Header.Set("Authorization", "Bearer not-an-api-key")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Values("Set-Cookie"))
+}
+
+func TestApp_HealthInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		// служебные маршруты не выдают cookie пользователя
+		assert.Empty(t, w.Result().Cookies())
+		return w
+	}
+
+	w := get(readyzPath)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok", "components": {"store": {"status": "ok"}}}`, w.Body.String())
+
+	grpc := testApp.Health().Component("grpc")
+	w = get(readyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status": "down", "components": {"store": {"status": "ok"}, "grpc": {"status": "starting"}}}`,
+		w.Body.String())
+
+	grpc.SetReady()
+	testApp.Health().Shutdown()
+	w = get(readyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"shutting_down"`)
+
+	w = get(healthzPath)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get(pingPath)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestApp_OpenAPIInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, openAPIPath, http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+		OpenAPI string `json:"openapi"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+
+	t.Run("every route is described", func(t *testing.T) {
+		for _, route := range r.Routes() {
+			path, _ := openAPIPathTemplate(route.Path)
+			_, ok := doc.Paths[path][strings.ToLower(route.Method)]
+			assert.True(t, ok, "route %s %s is missing from OpenAPI document", route.Method, route.Path)
+		}
+	})
+
+	t.Run("every reference resolves", func(t *testing.T) {
+		refs := regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(w.Body.String(), -1)
+		require.NotEmpty(t, refs)
+		for _, ref := range refs {
+			assert.Contains(t, doc.Components.Schemas, ref[1])
+		}
+	})
+
+	t.Run("docs page", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, docsPath, http.NoBody))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, textHTML, w.Header().Get(contentType))
+		assert.Contains(t, w.Body.String(), openAPIPath)
+		assert.NotContains(t, w.Body.String(), "://", "docs page must not load external resources")
+	})
+
+	t.Run("docs assets", func(t *testing.T) {
+		for name, assetType := range docsAssets {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, docsPath+"/"+name, http.NoBody))
+			assert.Equal(t, http.StatusOK, w.Code, name)
+			assert.Equal(t, assetType, w.Header().Get(contentType), name)
+			assert.Empty(t, w.Header().Get("Set-Cookie"), name)
+			assert.NotZero(t, w.Body.Len(), name)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, docsPath+"/LICENSE", http.NoBody))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestApp_V2InMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	tests := []struct {
+		headers     map[string]string
+		name        string
+		method      string
+		path        string
+		body        string
+		wantCode    string
+		wantField   string
+		wantStatus  int
+		wantDetails bool
+	}{
+		{
+			name: "shorten", method: http.MethodPost, path: "/api/v2/shorten",
+			body: `{"url": "https://practicum.yandex.ru/"}`, wantStatus: http.StatusCreated,
+		},
+		{
+			name: "shorten with query string", method: http.MethodPost, path: "/api/v2/shorten?x=1",
+			body: `{"url": "https://practicum.yandex.ru/"}`, wantStatus: http.StatusCreated,
+		},
+		{
+			name: "malformed json", method: http.MethodPost, path: "/api/v2/shorten",
+			body: `{"url":`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantDetails: true,
+		},
+		{
+			name: "empty body", method: http.MethodPost, path: "/api/v2/shorten",
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantDetails: true,
+		},
+		{
+			name: "trailing data", method: http.MethodPost, path: "/api/v2/shorten",
+			body:       `{"url": "https://practicum.yandex.ru/"} {}`,
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_JSON", wantDetails: true,
+		},
+		{
+			name: "unknown field", method: http.MethodPost, path: "/api/v2/shorten",
+			body: `{"uri": "https://practicum.yandex.ru/"}`, wantStatus: http.StatusBadRequest,
+			wantCode: "INVALID_JSON", wantField: "uri", wantDetails: true,
+		},
+		{
+			name: "wrong type", method: http.MethodPost, path: "/api/v2/shorten",
+			body: `{"url": 1}`, wantStatus: http.StatusBadRequest,
+			wantCode: "INVALID_JSON", wantField: "url", wantDetails: true,
+		},
+		{
+			name: "invalid url", method: http.MethodPost, path: "/api/v2/shorten",
+			body: `{"url": "practicum"}`, wantStatus: http.StatusBadRequest,
+			wantCode: "INVALID_REQUEST", wantField: "url", wantDetails: true,
+		},
+		{
+			name: "invalid link options", method: http.MethodPost, path: "/api/v2/shorten",
+			body:       `{"url": "https://practicum.yandex.ru/", "redirect_type": 200}`,
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_LINK_OPTIONS",
+		},
+		{
+			name: "batch item without correlation id", method: http.MethodPost, path: "/api/v2/shorten/batch",
+			body:       `[{"original_url": "https://practicum.yandex.ru/"}]`,
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_REQUEST", wantField: "[0].correlation_id",
+			wantDetails: true,
+		},
+		{
+			name: "unsupported media type", method: http.MethodPost, path: "/api/v2/shorten",
+			headers: map[string]string{contentType: textPlain}, body: "https://practicum.yandex.ru/",
+			wantStatus: http.StatusUnsupportedMediaType, wantCode: "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			name: "not acceptable", method: http.MethodGet, path: "/api/v2/user/urls",
+			headers:    map[string]string{accept: "text/html"},
+			wantStatus: http.StatusNotAcceptable, wantCode: "NOT_ACCEPTABLE",
+		},
+		{
+			name: "empty list", method: http.MethodGet, path: "/api/v2/user/urls",
+			headers: map[string]string{accept: "application/json, text/plain;q=0.5"}, wantStatus: http.StatusOK,
+		},
+		{
+			name: "rules of unknown link", method: http.MethodGet, path: "/api/v2/user/urls/missing/rules",
+			wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND",
+		},
+		{
+			name: "unknown route", method: http.MethodGet, path: "/api/v2/unknown",
+			wantStatus: http.StatusNotFound, wantCode: "ROUTE_NOT_FOUND",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(contentType, applicationJSON)
+			}
+			req.Header.Set(requestid.Header, "req-"+strings.ReplaceAll(tt.name, " ", "-"))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantCode == "" {
+				if w.Code == http.StatusOK {
+					assert.JSONEq(t, `[]`, w.Body.String())
+				}
+				return
+			}
+
+			var res models.ErrorRes
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tt.wantCode, res.Error.Code)
+			assert.NotEmpty(t, res.Error.Message)
+			assert.Equal(t, req.Header.Get(requestid.Header), res.Error.RequestID)
+			assert.Equal(t, tt.wantDetails, len(res.Error.Details) > 0)
+			if tt.wantField != "" {
+				assert.Equal(t, tt.wantField, res.Error.Details[0].Field)
+			}
+		})
+	}
+
+	t.Run("v1 keeps bare errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, apiShortenPath, strings.NewReader(`{"url":`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+}
+
+func TestApp_ListUserLinksInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return created.AddDate(0, 0, n) }
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"a": {OriginalURL: "https://practicum.yandex.ru/learn", UserID: "1", CreatedAt: day(0)},
+		"b": {OriginalURL: "https://yandex.ru/search", UserID: "1", CreatedAt: day(1)},
+		"c": {OriginalURL: "https://example.com/yandex.ru", UserID: "1", CreatedAt: day(2)},
+		"d": {OriginalURL: "https://go.dev/doc", UserID: "1", CreatedAt: day(3)},
+		"e": {OriginalURL: "https://go.dev/", UserID: "2", CreatedAt: day(4)},
+		"g": {OriginalURL: "https://go.dev/play", UserID: "1", CreatedAt: day(5), Deleted: true},
+	})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+	for slug, clicks := range map[string]int{"a": 1, "b": 3, "c": 2} {
+		for i := 0; i < clicks; i++ {
+			require.NoError(t, storage.RecordClick(slug, ""))
+		}
+	}
+
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	testApp := NewApp(testConfig, coreLogic, zap.L().Sugar())
+	r, err := testApp.SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+
+	token, err := auth.BuildJWTString(testKeyring(t), "1", auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+	get := func(t *testing.T, path string) ([]string, *httptest.ResponseRecorder) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		slugs := make([]string, 0)
+		if w.Code == http.StatusOK {
+			var links []models.UserLink
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &links))
+			for _, link := range links {
+				slugs = append(slugs, link.ShortURL)
+			}
+		}
+		return slugs, w
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		want       []string
+		wantStatus int
+	}{
+		{name: "newest first by default", query: "", want: []string{"d", "c", "b", "a"}, wantStatus: http.StatusOK},
+		{name: "oldest first", query: "?order=asc", want: []string{"a", "b", "c", "d"}, wantStatus: http.StatusOK},
+		{name: "most clicked", query: "?sort=clicks", want: []string{"b", "c", "a", "d"}, wantStatus: http.StatusOK},
+		{name: "least clicked", query: "?sort=clicks&order=asc", want: []string{"d", "a", "c", "b"}, wantStatus: http.StatusOK},
+		{name: "url substring", query: "?url=YANDEX", want: []string{"c", "b", "a"}, wantStatus: http.StatusOK},
+		{name: "domain with subdomains", query: "?domain=yandex.ru", want: []string{"b", "a"}, wantStatus: http.StatusOK},
+		{
+			name: "creation range", query: "?created_from=2024-01-02T00:00:00Z&created_to=2024-01-04T00:00:00Z",
+			want: []string{"c", "b"}, wantStatus: http.StatusOK,
+		},
+		{name: "only deleted", query: "?deleted=true", want: []string{"g"}, wantStatus: http.StatusOK},
+		{name: "with deleted", query: "?deleted=all", want: []string{"g", "d", "c", "b", "a"}, wantStatus: http.StatusOK},
+		{name: "unknown sort", query: "?sort=title", wantStatus: http.StatusBadRequest},
+		{name: "bad limit", query: "?limit=-1", wantStatus: http.StatusBadRequest},
+		{name: "bad cursor", query: "?cursor=bm90LWEtY3Vyc29y", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slugs, w := get(t, "/api/user/urls"+tt.query)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.want != nil {
+				assert.Equal(t, tt.want, slugs)
+			}
+		})
+	}
+
+	for _, sortBy := range []string{models.SortByCreatedAt, models.SortByClicks} {
+		t.Run("pages by "+sortBy, func(t *testing.T) {
+			all, _ := get(t, "/api/user/urls?sort="+sortBy)
+
+			pages := make([]string, 0)
+			path := "/api/user/urls?limit=3&sort=" + sortBy
+			for i := 0; path != ""; i++ {
+				require.Less(t, i, 3)
+				slugs, w := get(t, path)
+				require.Equal(t, http.StatusOK, w.Code)
+				pages = append(pages, slugs...)
+
+				path = ""
+				if cursor := w.Header().Get(nextCursorHeader); cursor != "" {
+					assert.Contains(t, w.Header().Get(linkHeader), url.QueryEscape(cursor))
+					path = "/api/user/urls?limit=3&sort=" + sortBy + "&cursor=" + url.QueryEscape(cursor)
+				}
+			}
+			assert.Equal(t, all, pages)
+		})
+	}
+
+	t.Run("cursor of another order", func(t *testing.T) {
+		_, w := get(t, "/api/user/urls?limit=1")
+		cursor := w.Header().Get(nextCursorHeader)
+		require.NotEmpty(t, cursor)
+
+		_, w = get(t, "/api/user/urls?limit=1&order=asc&cursor="+cursor)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("v2 pages by default", func(t *testing.T) {
+		_, w := get(t, "/api/v2/user/urls?sort=views")
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var res models.ErrorRes
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "INVALID_LIST_QUERY", res.Error.Code)
+
+		// limit=0 в v1 означает все ссылки, в v2 страница всегда ограничена
+		_, w = get(t, "/api/v2/user/urls?limit=0")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		slugs, w := get(t, "/api/v2/user/urls?deleted=all&order=asc")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"a", "b", "c", "d", "g"}, slugs)
+		assert.Empty(t, w.Header().Get(nextCursorHeader))
+	})
+
+	t.Run("index follows deletes", func(t *testing.T) {
+		_, err := storage.DeleteMany(models.DeleteUserURLsReq{"b"}, "1")
+		require.NoError(t, err)
+		_, err = storage.Put("f", "https://go.dev/blog", "1", models.LinkOptions{})
+		require.NoError(t, err)
+
+		slugs, _ := get(t, "/api/user/urls?order=asc")
+		assert.Equal(t, []string{"a", "c", "d", "f"}, slugs)
+
+		slugs, _ = get(t, "/api/user/urls?deleted=true&order=asc")
+		assert.Equal(t, []string{"b", "g"}, slugs)
+	})
+}
+
+func TestApp_ExportImportInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// хранилище в памяти изменяет переданную карту, поэтому она создается заново
+	links := func() map[string]models.URLRecordMemory {
+		return map[string]models.URLRecordMemory{
+			"a": {OriginalURL: "https://practicum.yandex.ru/", UserID: "1", CreatedAt: created},
+			"b": {
+				OriginalURL: "https://go.dev/doc", UserID: "1", CreatedAt: created.Add(time.Hour),
+				LinkOptions: models.LinkOptions{Title: "Go, docs", UTM: map[string]string{"source": "export"}},
+			},
+			"c": {OriginalURL: "https://example.com/", UserID: "2", CreatedAt: created},
+		}
+	}
+
+	token, err := auth.BuildJWTString(testKeyring(t), "1", auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+	setup := func(t *testing.T, storage *memory.MemoryStorage) *gin.Engine {
+		t.Helper()
+		coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+		r, err := NewApp(testConfig, coreLogic, zap.L().Sugar()).SetupRouter()
+		require.NoError(t, err)
+		return r
+	}
+	serve := func(r *gin.Engine, method string, path string, body string, mediaType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+		if mediaType != "" {
+			req.Header.Set(contentType, mediaType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	importLinks := func(t *testing.T, r *gin.Engine, body string, mediaType string) models.ImportRes {
+		t.Helper()
+		w := serve(r, http.MethodPost, "/api/user/urls/import", body, mediaType)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res models.ImportRes
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+	tests := []struct {
+		format      string
+		contentType string
+	}{
+		{format: models.FormatJSON, contentType: applicationJSON},
+		{format: models.FormatNDJSON, contentType: applicationNDJSON},
+		{format: models.FormatCSV, contentType: textCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			source, err := memory.NewMemoryStorage(links())
+			if err != nil {
+				t.Errorf(ErrorSetupStorage, err)
+				return
+			}
+			r := setup(t, source)
+
+			w := serve(r, http.MethodGet, "/api/user/urls/export?format="+tt.format, "", "")
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, strings.HasPrefix(w.Header().Get(contentType), tt.contentType))
+			export := w.Body.String()
+			assert.NotContains(t, export, "example.com")
+
+			target, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{})
+			require.NoError(t, err)
+			res := importLinks(t, setup(t, target), export, tt.contentType)
+			assert.Equal(t, 2, res.Created)
+			assert.Equal(t, 0, res.Failed)
+			for _, row := range res.Rows {
+				assert.True(t, row.SlugPreserved)
+			}
+			link, err := target.Get("b")
+			require.NoError(t, err)
+			assert.Equal(t, "Go, docs", link.Title)
+			assert.Equal(t, map[string]string{"source": "export"}, link.UTM)
+
+			// в исходном хранилище идентификаторы заняты, ссылки получают новые
+			res = importLinks(t, r, export, tt.contentType)
+			assert.Equal(t, 2, res.Created)
+			for _, row := range res.Rows {
+				assert.False(t, row.SlugPreserved)
+				assert.NotEqual(t, row.Slug, strings.TrimPrefix(row.ShortURL, testConfig.RedirectBaseURL+"/"))
+			}
+		})
+	}
+
+	source, err := memory.NewMemoryStorage(links())
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+	r := setup(t, source)
+
+	t.Run("report of invalid rows", func(t *testing.T) {
+		body := "slug,original_url,redirect_type\n" +
+			"ok,https://go.dev/blog,\n" +
+			"bad/slug,https://go.dev/,\n" +
+			",ftp://go.dev/,\n" +
+			"code,https://go.dev/,303\n" +
+			"short,row\n"
+		// без format и Content-Type тело разбирается как JSON
+		assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/api/user/urls/import", body, "").Code)
+
+		res := importLinks(t, r, body, textCSV+"; charset=utf-8")
+		assert.Equal(t, 1, res.Created)
+		assert.Equal(t, 4, res.Failed)
+		require.Len(t, res.Rows, 5)
+		for i, row := range res.Rows {
+			assert.Equal(t, i+1, row.Row)
+		}
+		assert.Equal(t, models.ImportCreated, res.Rows[0].Status)
+		assert.True(t, res.Rows[0].SlugPreserved)
+		for _, row := range res.Rows[1:3] {
+			assert.Equal(t, "INVALID_IMPORT_RECORD", row.Reason)
+		}
+		assert.Equal(t, models.ImportFailed, res.Rows[3].Status)
+		assert.Equal(t, "INVALID_IMPORT_RECORD", res.Rows[4].Reason)
+	})
+
+	t.Run("slugs of service routes", func(t *testing.T) {
+		for _, route := range r.Routes() {
+			segment := strings.SplitN(strings.TrimPrefix(route.Path, "/"), "/", 2)[0]
+			if segment == "" || strings.HasPrefix(segment, ":") || strings.Contains(segment, ".") {
+				continue
+			}
+			assert.True(t, logic.IsReservedSlug(segment), route.Path)
+		}
+
+		res := importLinks(t, r, "slug,original_url\nping,https://go.dev/\nreadyz,https://go.dev/\n", textCSV)
+		assert.Equal(t, 2, res.Created)
+		for _, row := range res.Rows {
+			assert.False(t, row.SlugPreserved)
+		}
+		assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/ping", "", "").Code)
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodGet, "/api/user/urls/export?format=xml", "", "").Code)
+		assert.Equal(t, http.StatusUnsupportedMediaType,
+			serve(r, http.MethodPost, "/api/user/urls/import", "<links/>", "application/xml").Code)
+		assert.Equal(t, http.StatusBadRequest,
+			serve(r, http.MethodPost, "/api/user/urls/import", `{"original_url":"https://go.dev/"}`, applicationJSON).Code)
+	})
+}
+
+func TestApp_ShortenBatchStreamInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{})
+	if err != nil {
+		t.Errorf(ErrorSetupStorage, err)
+		return
+	}
+	coreLogic := logic.NewCoreLogic(testConfig, storage, zap.L().Sugar())
+	r, err := NewApp(testConfig, coreLogic, zap.L().Sugar()).SetupRouter()
+	if err != nil {
+		t.Errorf(ErrorSetupRouter, err)
+	}
+	// ответ должен приходить, пока тело запроса еще отправляется, поэтому нужен настоящий сервер
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	token, err := auth.BuildJWTString(testKeyring(t), "1", auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	body, upload := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/shorten/batch/stream", body)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+	req.Header.Set(contentType, applicationNDJSON)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, applicationNDJSON, res.Header.Get(contentType))
+
+	shortURL := func(slug string) string {
+		result, err := url.JoinPath(testConfig.RedirectBaseURL, slug)
+		require.NoError(t, err)
+		return result
+	}
+	results := bufio.NewScanner(res.Body)
+	next := func(t *testing.T) models.URLBatchStreamRes {
+		t.Helper()
+		require.True(t, results.Scan(), results.Err())
+		var result models.URLBatchStreamRes
+		require.NoError(t, json.Unmarshal(results.Bytes(), &result))
+		return result
+	}
+
+	_, err = io.WriteString(upload, `{"correlation_id":"first","original_url":"https://go.dev/"}`+"\n")
+	require.NoError(t, err)
+	first := next(t)
+	assert.Equal(t, models.URLBatchStreamRes{
+		CorrelationID: "first", ShortURL: shortURL("first"), Line: 1,
+	}, first)
+
+	_, err = io.WriteString(upload, "\n"+
+		`{"correlation_id":"broken",`+"\n"+
+		`{"original_url":"https://go.dev/doc"}`+"\n"+
+		`{"correlation_id":"bad-type","original_url":"https://go.dev/","redirect_type":303}`+"\n"+
+		`{"correlation_id":"last","original_url":"https://go.dev/blog","title":"Go"}`+"\n")
+	require.NoError(t, err)
+	require.NoError(t, upload.Close())
+
+	tests := []struct {
+		name     string
+		line     int
+		code     string
+		shortURL string
+	}{
+		{name: "malformed json", line: 3, code: "INVALID_JSON"},
+		{name: "missing correlation id", line: 4, code: "INVALID_REQUEST"},
+		{name: "bad link options", line: 5, code: logic.DescribeError(logic.ErrBadRedirectType).Reason},
+		{name: "saved after errors", line: 6, shortURL: shortURL("last")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := next(t)
+			assert.Equal(t, tt.line, result.Line)
+			assert.Equal(t, tt.shortURL, result.ShortURL)
+			if tt.code == "" {
+				assert.Nil(t, result.Error)
+				return
+			}
+			require.NotNil(t, result.Error)
+			assert.Equal(t, tt.code, result.Error.Code)
+			assert.NotEmpty(t, result.Error.RequestID)
+		})
+	}
+	assert.False(t, results.Scan())
+
+	link, err := storage.Get("last")
+	require.NoError(t, err)
+	assert.Equal(t, "Go", link.Title)
+
+	t.Run("requires ndjson", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch/stream", strings.NewReader("[]"))
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+		req.Header.Set(contentType, applicationJSON)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+}
+
+// failingBatchStore хранилище в памяти, в котором вызов PutBatch с номером failOn завершается ошибкой.
+type failingBatchStore struct {
+	*memory.MemoryStorage
+	calls  int32
+	failOn int32
+}
+
+func (s *failingBatchStore) PutBatch(urls []models.URLBatchReq, userID string) ([]models.URLBatchRes, error) {
+	if atomic.AddInt32(&s.calls, 1) == s.failOn {
+		return nil, errors.New("storage is down")
+	}
+	return s.MemoryStorage.PutBatch(urls, userID)
+}
+
+func TestApp_ShortenBatchStreamStopsInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{})
+	require.NoError(t, err)
+	coreLogic := logic.NewCoreLogic(testConfig, &failingBatchStore{MemoryStorage: storage, failOn: 2}, zap.L().Sugar())
+	r, err := NewApp(testConfig, coreLogic, zap.L().Sugar()).SetupRouter()
+	require.NoError(t, err)
+	// сервер отмечает возврат из обработчика, а тело запроса — чтение, завершившееся после него
+	var returned, readAfterReturn atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Body = &trackingBody{ReadCloser: req.Body, returned: &returned, readAfterReturn: &readAfterReturn}
+		r.ServeHTTP(w, req)
+		returned.Store(true)
+	}))
+	defer srv.Close()
+
+	token, err := auth.BuildJWTString(testKeyring(t), "1", auth.DefaultTokenTTL.Access)
+	require.NoError(t, err)
+
+	body, upload := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/shorten/batch/stream", body)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token})
+	req.Header.Set(contentType, applicationNDJSON)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	results := bufio.NewScanner(res.Body)
+	next := func(t *testing.T) models.URLBatchStreamRes {
+		t.Helper()
+		require.True(t, results.Scan(), results.Err())
+		var result models.URLBatchStreamRes
+		require.NoError(t, json.Unmarshal(results.Bytes(), &result))
+		return result
+	}
+
+	_, err = io.WriteString(upload, `{"correlation_id":"first","original_url":"https://go.dev/"}`+"\n")
+	require.NoError(t, err)
+	assert.Nil(t, next(t).Error)
+
+	_, err = io.WriteString(upload, `{"correlation_id":"second","original_url":"https://go.dev/doc"}`+"\n")
+	require.NoError(t, err)
+	second := next(t)
+	require.NotNil(t, second.Error)
+	assert.Equal(t, 2, second.Line)
+
+	// тело запроса еще не закрыто, но обработчик прекратил чтение и завершил ответ
+	assert.False(t, results.Scan())
+	require.Eventually(t, returned.Load, time.Second, 10*time.Millisecond)
+	require.NoError(t, upload.Close())
+	assert.Never(t, readAfterReturn.Load, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+// trackingBody тело запроса, отмечающее чтение, которое завершилось после возврата из обработчика.
+type trackingBody struct {
+	io.ReadCloser
+	returned        *atomic.Bool
+	readAfterReturn *atomic.Bool
+}
+
+func (b *trackingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.returned.Load() {
+		b.readAfterReturn.Store(true)
+	}
+	return n, err
+}
@@ -30,9 +30,11 @@ func Test_GetRecord(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	gomock.InOrder(
-		store.EXPECT().Get("any").Return(link, nil),
+		store.EXPECT().Get("any").Return(&models.Link{ShortURL: "any", OriginalURL: link}, nil),
+		store.EXPECT().RecordClick("any", "").Return(nil),
 	)
 
 	coreLogic := logic.NewCoreLogic(testConfig, store, zap.L().Sugar())
@@ -103,9 +105,10 @@ func Test_PutRecord(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	gomock.InOrder(
-		store.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any()).Return("link", nil),
+		store.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("link", nil),
 	)
 
 	coreLogic := logic.NewCoreLogic(testConfig, store, zap.L().Sugar())
@@ -177,11 +180,13 @@ func TestApp_GetUserRecords(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	gomock.InOrder(
-		store.EXPECT().GetAllByUserID(gomock.Any()).Return([]models.URLRecord{}, nil),
-		store.EXPECT().GetAllByUserID(gomock.Any()).Return([]models.URLRecord{{ShortURL: "test", OriginalURL: "test"}}, nil),
-		store.EXPECT().GetAllByUserID(gomock.Any()).Return([]models.URLRecord{}, fmt.Errorf("test error")),
+		store.EXPECT().ListByUserID(gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.UserLink{}, nil),
+		store.EXPECT().ListByUserID(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			[]models.UserLink{{URLRecord: models.URLRecord{ShortURL: "test", OriginalURL: "test"}, Clicks: 2}}, nil),
+		store.EXPECT().ListByUserID(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test error")),
 	)
 
 	coreLogic := logic.NewCoreLogic(testConfig, store, zap.L().Sugar())
@@ -215,7 +220,7 @@ func TestApp_GetUserRecords(t *testing.T) {
 			name: "successfull get",
 			args: args{
 				url:      "/api/user/urls",
-				response: "[{\"short_url\":\"test\",\"original_url\":\"test\"}]",
+				response: `[{"created_at":"0001-01-01T00:00:00Z","short_url":"test","original_url":"test","clicks":2}]`,
 				wantCode: http.StatusOK,
 			},
 		},
@@ -267,9 +272,10 @@ func TestApp_DeleteUserRecords(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	gomock.InOrder(
-		store.EXPECT().DeleteMany(models.DeleteUserURLsReq{"1", "2"}, gomock.Any()).Return(nil),
+		store.EXPECT().DeleteMany(models.DeleteUserURLsReq{"1", "2"}, gomock.Any()).Return(models.DeleteUserURLsReq{"1", "2"}, nil),
 	)
 
 	coreLogic := logic.NewCoreLogic(testConfig, store, zap.L().Sugar())
@@ -352,10 +358,11 @@ func TestApp_Ping(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	gomock.InOrder(
-		store.EXPECT().Ping().Return(nil),
-		store.EXPECT().Ping().Return(fmt.Errorf("lost connection to db")),
+		store.EXPECT().Ping(gomock.Any()).Return(nil),
+		store.EXPECT().Ping(gomock.Any()).Return(fmt.Errorf("lost connection to db")),
 	)
 
 	coreLogic := logic.NewCoreLogic(testConfig, store, zap.L().Sugar())
@@ -420,6 +427,7 @@ func TestApp_ShortenBatch(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetWebhooks(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	gomock.InOrder(
 		store.EXPECT().PutBatch(
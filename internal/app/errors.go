@@ -0,0 +1,35 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/logic"
+)
+
+// httpStatuses сопоставляет виды ошибок логики кодам HTTP.
+var httpStatuses = map[logic.ErrorCode]int{
+	logic.CodeInvalidArgument: http.StatusBadRequest,
+	logic.CodeNotFound:        http.StatusNotFound,
+	logic.CodeGone:            http.StatusGone,
+	logic.CodeConflict:        http.StatusConflict,
+	logic.CodeUnauthenticated: http.StatusUnauthorized,
+}
+
+// httpStatus возвращает код HTTP для ошибки логики.
+func httpStatus(err error) int {
+	if code, ok := httpStatuses[logic.DescribeError(err).Code]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError отвечает кодом HTTP, соответствующим ошибке логики.
+// Внутренние ошибки журналируются с сообщением message.
+func (a *App) writeError(c *gin.Context, message string, err error) {
+	code := httpStatus(err)
+	if code == http.StatusInternalServerError {
+		a.logger.Errorf("%s: %v", message, err)
+	}
+	c.Writer.WriteHeader(code)
+}
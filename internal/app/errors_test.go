@@ -0,0 +1,36 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/qr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		name string
+		want int
+	}{
+		{name: "not found", err: fmt.Errorf("error getting link: %w", logic.ErrNotFound), want: http.StatusNotFound},
+		{name: "deleted", err: logic.ErrIsDeleted, want: http.StatusGone},
+		{name: "disabled", err: logic.ErrIsDisabled, want: http.StatusGone},
+		{name: "conflict", err: logic.ErrConflict, want: http.StatusConflict},
+		{name: "bad link options", err: logic.ErrBadVariants, want: http.StatusBadRequest},
+		{name: "bad qr options", err: qr.ErrBadOptions, want: http.StatusBadRequest},
+		{name: "bad credentials", err: logic.ErrBadCredentials, want: http.StatusBadRequest},
+		{name: "wrong credentials", err: logic.ErrInvalidCredentials, want: http.StatusUnauthorized},
+		{name: "internal", err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, httpStatus(tt.err))
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package app
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	textCSV            = "text/csv"
+	applicationNDJSON  = "application/x-ndjson"
+	contentDisposition = "Content-Disposition"
+
+	// maxImportRows наибольшее число записей в одном импорте, остальные записи не обрабатываются.
+	maxImportRows = 10000
+	// maxImportLine наибольшая длина строки NDJSON при импорте.
+	maxImportLine = 1 << 20
+	// exportFlushEvery через сколько записей экспорт отправляется клиенту.
+	exportFlushEvery = 100
+
+	reasonTooManyRows       = "TOO_MANY_ROWS"
+	reasonInvalidImportFile = "INVALID_IMPORT_FILE"
+)
+
+// csvColumns колонки CSV экспорта. Составные параметры ссылки (utm, rules, variants)
+// записываются в ячейки в виде JSON.
+var csvColumns = []string{
+	"slug", "short_url", "original_url", "created_at", "clicks", "title", "redirect_type",
+	"query_mode", "sticky", "interstitial", "utm", "rules", "variants",
+}
+
+var exportContentTypes = map[string]string{
+	models.FormatCSV:    textCSV + "; charset=utf-8",
+	models.FormatJSON:   applicationJSON,
+	models.FormatNDJSON: applicationNDJSON,
+}
+
+var importFormats = map[string]string{
+	textCSV:           models.FormatCSV,
+	applicationJSON:   models.FormatJSON,
+	applicationNDJSON: models.FormatNDJSON,
+}
+
+// ExportLinks отдает ссылки пользователя в формате csv, json или ndjson (параметр format,
+// по умолчанию json). Ссылки пишутся в ответ по мере чтения из хранилища.
+func (a *App) ExportLinks(c *gin.Context) {
+	format := c.DefaultQuery("format", models.FormatJSON)
+	enc := newExportEncoder(format, c.Writer)
+	if enc == nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.Header(contentType, exportContentTypes[format])
+	c.Header(contentDisposition, fmt.Sprintf(`attachment; filename="links.%s"`, format))
+	c.Status(http.StatusOK)
+
+	count := 0
+	err := enc.begin()
+	if err == nil {
+		err = a.coreLogic.ExportUserLinks(c, c.GetString(auth.UserIDKey), func(link models.LinkExport) error {
+			if err := enc.encode(link); err != nil {
+				return err
+			}
+			if count++; count%exportFlushEvery == 0 {
+				return enc.flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = enc.end()
+	}
+	if err != nil {
+		// ответ уже начат, клиент увидит оборванный файл
+		a.logger.Errorf("Error exporting links: %v", err)
+		c.Abort()
+	}
+}
+
+// ImportLinks создает ссылки пользователя из файла экспорта и отвечает отчетом по каждой записи.
+// Формат задается параметром format или заголовком Content-Type.
+func (a *App) ImportLinks(c *gin.Context) {
+	format, status := importFormat(c)
+	if status != http.StatusOK {
+		c.Writer.WriteHeader(status)
+		return
+	}
+
+	userID := c.GetString(auth.UserIDKey)
+	report := models.ImportRes{Rows: make([]models.ImportRow, 0)}
+	fail := func(row models.ImportRow, reason string, message string) {
+		row.Status, row.Reason, row.Error = models.ImportFailed, reason, message
+		report.Rows = append(report.Rows, row)
+		report.Failed++
+	}
+
+	next := 1
+	err := decodeImport(format, c.Request.Body, func(n int, record models.LinkExport, err error) error {
+		next = n + 1
+		row := models.ImportRow{Row: n, Slug: record.Slug}
+		if n > maxImportRows {
+			fail(row, reasonTooManyRows, fmt.Sprintf("import is limited to %d records", maxImportRows))
+			return errTooManyRows
+		}
+
+		if err == nil {
+			row.ShortURL, row.SlugPreserved, err = a.coreLogic.ImportLink(c, userID, record)
+		}
+		if err != nil {
+			info := logic.DescribeError(err)
+			message := err.Error()
+			if info.Code == logic.CodeInternal {
+				a.logger.Errorf("Error importing link: %v", err)
+				message = internalErrorMessage
+			}
+			fail(row, info.Reason, message)
+			return nil
+		}
+
+		row.Status = models.ImportCreated
+		report.Rows = append(report.Rows, row)
+		report.Created++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errTooManyRows) {
+		if len(report.Rows) == 0 {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fail(models.ImportRow{Row: next}, reasonInvalidImportFile, err.Error())
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+var errTooManyRows = errors.New("too many rows")
+
+// importFormat определяет формат импорта по параметру format или заголовку Content-Type.
+func importFormat(c *gin.Context) (string, int) {
+	if format := c.Query("format"); format != "" {
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", http.StatusBadRequest
+		}
+		return format, http.StatusOK
+	}
+
+	header := c.GetHeader(contentType)
+	if header == "" {
+		return models.FormatJSON, http.StatusOK
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", http.StatusUnsupportedMediaType
+	}
+	format, ok := importFormats[mediaType]
+	if !ok {
+		return "", http.StatusUnsupportedMediaType
+	}
+	return format, http.StatusOK
+}
+
+// exportEncoder пишет ссылки в одном из форматов экспорта.
+type exportEncoder interface {
+	begin() error
+	encode(link models.LinkExport) error
+	flush() error
+	end() error
+}
+
+func newExportEncoder(format string, w gin.ResponseWriter) exportEncoder {
+	switch format {
+	case models.FormatJSON:
+		return &jsonExport{w: w}
+	case models.FormatNDJSON:
+		return &ndjsonExport{w: w, enc: json.NewEncoder(w)}
+	case models.FormatCSV:
+		return &csvExport{w: w, csv: csv.NewWriter(w)}
+	default:
+		return nil
+	}
+}
+
+// jsonExport пишет ссылки массивом JSON по одному элементу.
+type jsonExport struct {
+	w     gin.ResponseWriter
+	count int
+}
+
+func (e *jsonExport) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExport) encode(link models.LinkExport) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("error encoding link: %w", err)
+	}
+	if e.count > 0 {
+		data = append([]byte(",\n"), data...)
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExport) flush() error {
+	e.w.Flush()
+	return nil
+}
+
+func (e *jsonExport) end() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// ndjsonExport пишет по одной ссылке в строке.
+type ndjsonExport struct {
+	w   gin.ResponseWriter
+	enc *json.Encoder
+}
+
+func (e *ndjsonExport) begin() error { return nil }
+
+func (e *ndjsonExport) encode(link models.LinkExport) error {
+	if err := e.enc.Encode(link); err != nil {
+		return fmt.Errorf("error encoding link: %w", err)
+	}
+	return nil
+}
+
+func (e *ndjsonExport) flush() error {
+	e.w.Flush()
+	return nil
+}
+
+func (e *ndjsonExport) end() error { return nil }
+
+type csvExport struct {
+	w   gin.ResponseWriter
+	csv *csv.Writer
+}
+
+func (e *csvExport) begin() error {
+	return e.csv.Write(csvColumns)
+}
+
+func (e *csvExport) encode(link models.LinkExport) error {
+	row, err := csvRow(link)
+	if err != nil {
+		return err
+	}
+	return e.csv.Write(row)
+}
+
+func (e *csvExport) flush() error {
+	e.csv.Flush()
+	e.w.Flush()
+	return e.csv.Error()
+}
+
+func (e *csvExport) end() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func csvRow(link models.LinkExport) ([]string, error) {
+	cells := make(map[string]string, 3)
+	for name, value := range map[string]interface{}{"utm": link.UTM, "rules": link.Rules, "variants": link.Variants} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s: %w", name, err)
+		}
+		// пустые значения оставляют ячейку пустой
+		if s := string(data); s != "null" && s != "[]" && s != "{}" {
+			cells[name] = s
+		}
+	}
+
+	redirectType := ""
+	if link.RedirectType != 0 {
+		redirectType = strconv.Itoa(link.RedirectType)
+	}
+
+	return []string{
+		link.Slug, link.ShortURL, link.OriginalURL, link.CreatedAt.Format(time.RFC3339Nano),
+		strconv.FormatInt(link.Clicks, 10), link.Title, redirectType, link.QueryMode,
+		strconv.FormatBool(link.Sticky), strconv.FormatBool(link.Interstitial),
+		cells["utm"], cells["rules"], cells["variants"],
+	}, nil
+}
+
+// decodeImport читает записи импорта и передает их fn по одной вместе с номером записи.
+// Ошибка разбора отдельной записи передается в fn, ошибка fn или разбора файла
+// прекращает чтение и возвращается вызывающему.
+func decodeImport(format string, r io.Reader, fn func(row int, record models.LinkExport, err error) error) error {
+	switch format {
+	case models.FormatCSV:
+		return decodeCSV(r, fn)
+	case models.FormatNDJSON:
+		return decodeNDJSON(r, fn)
+	default:
+		return decodeJSONArray(r, fn)
+	}
+}
+
+func decodeJSONArray(r io.Reader, fn func(int, models.LinkExport, error) error) error {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return errors.New("expected a JSON array of links")
+	}
+
+	for row := 1; dec.More(); row++ {
+		var record models.LinkExport
+		err := dec.Decode(&record)
+		var typeErr *json.UnmarshalTypeError
+		if err != nil && !errors.As(err, &typeErr) {
+			return fmt.Errorf("malformed JSON in record %d: %w", row, err)
+		}
+		if err := fn(row, record, importRecordError(err)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("malformed JSON array end: %w", err)
+	}
+	return nil
+}
+
+func decodeNDJSON(r io.Reader, fn func(int, models.LinkExport, error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxImportLine)
+
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row++
+
+		var record models.LinkExport
+		err := json.Unmarshal([]byte(line), &record)
+		if err := fn(row, record, importRecordError(err)); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading record %d: %w", row+1, err)
+	}
+	return nil
+}
+
+func decodeCSV(r io.Reader, fn func(int, models.LinkExport, error) error) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	known := make(map[string]bool, len(csvColumns))
+	for _, name := range csvColumns {
+		known[name] = true
+	}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["original_url"]; !ok {
+		return errors.New("CSV column original_url is required")
+	}
+
+	for row := 1; ; row++ {
+		cells, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var record models.LinkExport
+		if err == nil {
+			record, err = csvRecord(columns, cells)
+		} else if !errors.Is(err, csv.ErrFieldCount) {
+			return fmt.Errorf("error reading CSV record %d: %w", row, err)
+		}
+		if err := fn(row, record, importRecordError(err)); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRecord собирает запись импорта из ячеек строки CSV.
+func csvRecord(columns map[string]int, cells []string) (models.LinkExport, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(cells[i])
+		}
+		return ""
+	}
+
+	record := models.LinkExport{
+		Slug:        cell("slug"),
+		OriginalURL: cell("original_url"),
+	}
+	record.Title = cell("title")
+	record.QueryMode = cell("query_mode")
+
+	var err error
+	if v := cell("redirect_type"); v != "" {
+		if record.RedirectType, err = strconv.Atoi(v); err != nil {
+			return record, fmt.Errorf("redirect_type: %w", err)
+		}
+	}
+	for name, dst := range map[string]*bool{"sticky": &record.Sticky, "interstitial": &record.Interstitial} {
+		if v := cell(name); v != "" {
+			if *dst, err = strconv.ParseBool(v); err != nil {
+				return record, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	for name, dst := range map[string]interface{}{"utm": &record.UTM, "rules": &record.Rules, "variants": &record.Variants} {
+		if v := cell(name); v != "" {
+			if err := json.Unmarshal([]byte(v), dst); err != nil {
+				return record, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	return record, nil
+}
+
+// importRecordError относит ошибку разбора записи к некорректным записям импорта.
+func importRecordError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", logic.ErrBadImportRecord, err)
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/health"
+)
+
+// Health возвращает реестр компонентов сервиса. Хранилище зарегистрировано в нем
+// при создании App, остальные компоненты регистрирует вызывающий.
+func (a *App) Health() *health.Checker {
+	return a.health
+}
+
+// Healthz проверка живости: процесс отвечает, код всегда 200, состояние компонентов — в теле.
+// Хранилище не опрашивается, чтобы его недоступность не приводила к перезапуску сервиса.
+func (a *App) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, a.health.Live())
+}
+
+// Readyz проверка готовности: 503, если хотя бы один компонент не работает
+// или сервис завершает работу.
+func (a *App) Readyz(c *gin.Context) {
+	report := a.health.Check(c)
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
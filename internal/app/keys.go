@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+)
+
+// GetJWKS отдает открытые ключи для проверки токенов сервиса.
+func (a *App) GetJWKS(c *gin.Context) {
+	c.Header(cacheControl, "public, max-age=300")
+	c.JSON(http.StatusOK, a.keys.JWKS())
+}
+
+// Keys возвращает ключи подписи токенов, загруженные SetupRouter.
+func (a *App) Keys() *auth.Keyring {
+	return a.keys
+}
+
+// ReloadKeys перечитывает файл ключей подписи токенов.
+func (a *App) ReloadKeys() error {
+	if a.keys == nil {
+		return nil
+	}
+	if err := a.keys.Reload(); err != nil {
+		return fmt.Errorf("error reloading signing keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	// defaultV2ListLimit размер страницы списка ссылок в API v2, если limit не задан.
+	defaultV2ListLimit = 100
+
+	cursorParam      = "cursor"
+	nextCursorHeader = "X-Next-Cursor"
+	linkHeader       = "Link"
+)
+
+// deletedParams значения параметра deleted списка ссылок.
+var deletedParams = map[string]string{
+	"":      models.DeletedExclude,
+	"false": models.DeletedExclude,
+	"true":  models.DeletedOnly,
+	"all":   models.DeletedInclude,
+}
+
+// parseListQuery разбирает параметры списка ссылок пользователя из строки запроса:
+// limit, cursor, url, domain, created_from, created_to (RFC 3339), deleted (false, true, all),
+// sort (created_at, clicks) и order (asc, desc; по умолчанию desc).
+// Нулевой defaultLimit означает, что без limit возвращаются все ссылки (v1); иначе limit
+// должен быть положительным, чтобы limit=0 не обходил ограничение размера страницы.
+func parseListQuery(c *gin.Context, defaultLimit int) (models.UserLinksQuery, string, error) {
+	query := models.UserLinksQuery{
+		URL:    c.Query("url"),
+		Domain: c.Query("domain"),
+		Sort:   c.Query("sort"),
+		Limit:  defaultLimit,
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return query, "", fmt.Errorf("%w: limit must be a number", logic.ErrBadListQuery)
+		}
+		if limit == 0 && defaultLimit > 0 {
+			return query, "", fmt.Errorf("%w: limit must be positive", logic.ErrBadListQuery)
+		}
+		query.Limit = limit
+	}
+
+	for param, dst := range map[string]*time.Time{"created_from": &query.CreatedFrom, "created_to": &query.CreatedTo} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return query, "", fmt.Errorf("%w: %s must be an RFC 3339 time", logic.ErrBadListQuery, param)
+			}
+			*dst = t
+		}
+	}
+
+	deleted, ok := deletedParams[c.Query("deleted")]
+	if !ok {
+		return query, "", fmt.Errorf("%w: deleted must be true, false or all", logic.ErrBadListQuery)
+	}
+	query.Deleted = deleted
+
+	switch c.Query("order") {
+	case "", "desc":
+		query.Desc = true
+	case "asc":
+	default:
+		return query, "", fmt.Errorf("%w: order must be asc or desc", logic.ErrBadListQuery)
+	}
+
+	return query, c.Query(cursorParam), nil
+}
+
+// setNextPage сообщает курсор следующей страницы в заголовке X-Next-Cursor
+// и ссылку на нее в заголовке Link.
+func setNextPage(c *gin.Context, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	next := *c.Request.URL
+	values := next.Query()
+	values.Set(cursorParam, cursor)
+	next.RawQuery = values.Encode()
+
+	c.Header(nextCursorHeader, cursor)
+	c.Header(linkHeader, fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+}
@@ -0,0 +1,772 @@
+package app
+
+import (
+	"embed"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/health"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	docsTemplate    = "docs.html"
+	swaggerUIDir    = "templates/swagger-ui"
+	docsAssetMaxAge = "public, max-age=86400"
+	openAPIVersion  = "3.0.3"
+	componentPrefix = "#/components/schemas/"
+	imagePNG        = "image/png"
+	imageSVG        = "image/svg+xml"
+)
+
+// swaggerUIFS сборка swagger-ui-dist 5.18.2 (лицензия Apache 2.0), страница документации
+// не загружает сторонние скрипты.
+//
+//go:embed templates/swagger-ui
+var swaggerUIFS embed.FS
+
+// docsAssets файлы swagger-ui, которые отдает GetDocsAsset, и их типы содержимого.
+var docsAssets = map[string]string{
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+}
+
+// docsPage данные шаблона страницы документации.
+type docsPage struct {
+	Spec   string
+	Assets string
+}
+
+// openAPIDocument документ OpenAPI 3, описывающий HTTP API сервиса.
+type openAPIDocument struct {
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+	Info       openAPIInfo                             `json:"info"`
+	OpenAPI    string                                  `json:"openapi"`
+	Security   []map[string][]string                   `json:"security"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type openAPIOperation struct {
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Summary     string                      `json:"summary"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	// Security пустой список означает маршрут без аутентификации.
+	Security []map[string][]string `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Schema      *openAPISchema `json:"schema"`
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required"`
+}
+
+type openAPIRequestBody struct {
+	Content  map[string]openAPIMediaType `json:"content"`
+	Required bool                        `json:"required"`
+}
+
+type openAPIResponse struct {
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	Headers     map[string]openAPIHeader    `json:"headers,omitempty"`
+	Description string                      `json:"description"`
+}
+
+type openAPIHeader struct {
+	Schema      *openAPISchema `json:"schema"`
+	Description string         `json:"description,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+}
+
+// apiResponse ответ маршрута: model — модель JSON-тела, либо тело типа contentType.
+type apiResponse struct {
+	model       interface{}
+	headers     map[string]string
+	description string
+	contentType string
+}
+
+// apiParam параметр маршрута в строке запроса.
+type apiParam struct {
+	schema      *openAPISchema
+	name        string
+	description string
+}
+
+// apiRoute описание маршрута HTTP API. Путь записывается так же, как при регистрации в gin.
+type apiRoute struct {
+	request     interface{}
+	responses   map[int]apiResponse
+	method      string
+	path        string
+	summary     string
+	tag         string
+	contentType string
+	query       []apiParam
+	public      bool
+}
+
+func jsonResponse(model interface{}) apiResponse {
+	return apiResponse{model: model}
+}
+
+func emptyResponse(description string) apiResponse {
+	return apiResponse{description: description}
+}
+
+var (
+	stringSchema  = &openAPISchema{Type: "string"}
+	integerSchema = &openAPISchema{Type: "integer"}
+	binarySchema  = &openAPISchema{Type: "string", Format: "binary"}
+)
+
+// Ответы с ошибками, общие для маршрутов.
+var (
+	badRequest    = emptyResponse("Некорректный запрос")
+	notFound      = emptyResponse("Ссылка или объект не найдены")
+	gone          = emptyResponse("Ссылка удалена или отключена")
+	conflict      = emptyResponse("Объект уже существует")
+	forbidden     = emptyResponse("Запрос не из доверенной подсети или ключ API только для чтения")
+	internalError = emptyResponse("Внутренняя ошибка")
+)
+
+// listQueryParams параметры списка ссылок пользователя.
+var listQueryParams = []apiParam{
+	{name: "limit", schema: integerSchema, description: "Размер страницы, не больше 1000"},
+	{name: cursorParam, schema: stringSchema, description: "Курсор из заголовка X-Next-Cursor"},
+	{name: "url", schema: stringSchema, description: "Подстрока адреса назначения"},
+	{name: "domain", schema: stringSchema, description: "Домен адреса назначения вместе с поддоменами"},
+	{name: "created_from", schema: &openAPISchema{Type: "string", Format: "date-time"}},
+	{name: "created_to", schema: &openAPISchema{Type: "string", Format: "date-time"}},
+	{name: "deleted", schema: &openAPISchema{Type: "string", Enum: []interface{}{"false", "true", "all"}}},
+	{name: "sort", schema: &openAPISchema{
+		Type: "string", Enum: []interface{}{models.SortByCreatedAt, models.SortByClicks},
+	}},
+	{name: "order", schema: &openAPISchema{Type: "string", Enum: []interface{}{"desc", "asc"}}},
+}
+
+// listPage страница списка ссылок пользователя.
+var listPage = apiResponse{
+	model:       []models.UserLink{},
+	description: "Страница ссылок; заголовки передаются, если есть следующая страница",
+	headers: map[string]string{
+		nextCursorHeader: "Курсор следующей страницы",
+		linkHeader:       "Ссылка на следующую страницу, rel=\"next\"",
+	},
+}
+
+// v2Responses дополняет ответы маршрута API v2 ошибками в едином формате: codes — коды,
+// характерные для маршрута, 406 и 500 возможны у любого маршрута.
+func v2Responses(responses map[int]apiResponse, codes ...int) map[int]apiResponse {
+	for _, code := range append(codes, http.StatusNotAcceptable, http.StatusInternalServerError) {
+		responses[code] = apiResponse{model: models.ErrorRes{}, description: http.StatusText(code)}
+	}
+	return responses
+}
+
+// apiRoutes все маршруты, регистрируемые SetupRouter, кроме pprof.
+var apiRoutes = []apiRoute{
+	{
+		method: http.MethodGet, path: pingPath, tag: "service", public: true,
+		summary: "Проверка соединения с хранилищем",
+		responses: map[int]apiResponse{
+			http.StatusOK:                  emptyResponse("Хранилище доступно"),
+			http.StatusInternalServerError: internalError,
+		},
+	},
+	{
+		method: http.MethodGet, path: healthzPath, tag: "service", public: true,
+		summary:   "Проверка живости",
+		responses: map[int]apiResponse{http.StatusOK: jsonResponse(health.Report{})},
+	},
+	{
+		method: http.MethodGet, path: readyzPath, tag: "service", public: true,
+		summary: "Проверка готовности",
+		responses: map[int]apiResponse{
+			http.StatusOK:                 jsonResponse(health.Report{}),
+			http.StatusServiceUnavailable: jsonResponse(health.Report{}),
+		},
+	},
+	{
+		method: http.MethodGet, path: openAPIPath, tag: "service", public: true,
+		summary:   "Спецификация OpenAPI",
+		responses: map[int]apiResponse{http.StatusOK: {contentType: applicationJSON}},
+	},
+	{
+		method: http.MethodGet, path: docsPath, tag: "service", public: true,
+		summary:   "Интерактивная документация",
+		responses: map[int]apiResponse{http.StatusOK: {contentType: textHTML}},
+	},
+	{
+		method: http.MethodGet, path: docsAssetPath, tag: "service", public: true,
+		summary: "Стили и скрипт интерактивной документации",
+		responses: map[int]apiResponse{
+			http.StatusOK:       {contentType: "text/css,text/javascript", description: "Файл swagger-ui"},
+			http.StatusNotFound: emptyResponse("Файл не найден"),
+		},
+	},
+	{
+		method: http.MethodGet, path: jwksPath, tag: "service", public: true,
+		summary:   "Открытые ключи проверки токенов",
+		responses: map[int]apiResponse{http.StatusOK: jsonResponse(auth.JWKS{})},
+	},
+	{
+		method: http.MethodGet, path: "/:id", tag: "links", public: true,
+		summary: "Перейти по короткой ссылке",
+		query: []apiParam{
+			{name: previewParam, schema: stringSchema, description: "1 — показать страницу предпросмотра"},
+		},
+		responses: map[int]apiResponse{
+			http.StatusOK: {contentType: textHTML, description: "Страница предпросмотра или промежуточная страница"},
+			http.StatusTemporaryRedirect: {
+				description: "Перенаправление, код задается ссылкой: 301, 302, 307 или 308",
+				headers:     map[string]string{location: "Адрес назначения"},
+			},
+			http.StatusNotFound: notFound,
+			http.StatusGone:     gone,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/:id/qr", tag: "links", public: true,
+		summary: "QR-код короткой ссылки",
+		query: []apiParam{
+			{name: "format", schema: &openAPISchema{Type: "string", Enum: []interface{}{"png", "svg"}}},
+			{name: "size", schema: integerSchema, description: "64–2048 пикселей"},
+			{name: "level", schema: &openAPISchema{Type: "string", Enum: []interface{}{"L", "M", "Q", "H"}}},
+			{name: "margin", schema: integerSchema, description: "0–16 модулей"},
+			{name: "fg", schema: stringSchema, description: "RRGGBB"},
+			{name: "bg", schema: stringSchema, description: "RRGGBB"},
+		},
+		responses: map[int]apiResponse{
+			http.StatusOK:          {contentType: imagePNG + "," + imageSVG, headers: map[string]string{etag: ""}},
+			http.StatusNotModified: emptyResponse("Изображение не изменилось"),
+			http.StatusBadRequest:  badRequest,
+			http.StatusNotFound:    notFound,
+			http.StatusGone:        gone,
+		},
+	},
+	{
+		method: http.MethodPost, path: rootPath, tag: "links", contentType: textPlain,
+		summary: "Сократить ссылку, переданную текстом",
+		responses: map[int]apiResponse{
+			http.StatusCreated:    {contentType: textPlain, description: "Короткая ссылка"},
+			http.StatusBadRequest: badRequest,
+			http.StatusConflict:   conflict,
+		},
+	},
+	{
+		method: http.MethodPost, path: apiShortenPath, tag: "links",
+		summary: "Сократить ссылку", request: models.ShortenReq{},
+		responses: map[int]apiResponse{
+			http.StatusCreated:    jsonResponse(models.ShortenRes{}),
+			http.StatusBadRequest: badRequest,
+			http.StatusConflict:   conflict,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/shorten/batch", tag: "links",
+		summary: "Сократить несколько ссылок", request: []models.URLBatchReq{},
+		responses: map[int]apiResponse{
+			http.StatusCreated:    jsonResponse([]models.URLBatchRes{}),
+			http.StatusBadRequest: badRequest,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/shorten/batch/stream", tag: "links", contentType: applicationNDJSON,
+		summary: "Сократить поток ссылок: объект URLBatchReq в каждой строке, в ответе URLBatchStreamRes",
+		responses: map[int]apiResponse{
+			http.StatusOK:                      {contentType: applicationNDJSON, description: "Результаты строк по мере сохранения"},
+			http.StatusUnsupportedMediaType:    emptyResponse("Тело не application/x-ndjson"),
+			http.StatusHTTPVersionNotSupported: emptyResponse("Сервер не может читать тело HTTP/1.1 во время ответа"),
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/user/urls", tag: "links",
+		summary: "Ссылки пользователя, без limit — все ссылки", query: listQueryParams,
+		responses: map[int]apiResponse{
+			http.StatusOK:         listPage,
+			http.StatusNoContent:  emptyResponse("Подходящих ссылок нет"),
+			http.StatusBadRequest: badRequest,
+		},
+	},
+	{
+		method: http.MethodDelete, path: "/api/user/urls", tag: "links",
+		summary: "Удалить ссылки пользователя", request: models.DeleteUserURLsReq{},
+		responses: map[int]apiResponse{
+			http.StatusAccepted: emptyResponse("Ссылки будут удалены асинхронно"),
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/user/urls/export", tag: "links",
+		summary: "Выгрузить ссылки пользователя с параметрами",
+		query:   []apiParam{{name: "format", schema: stringSchema, description: "csv, json или ndjson, по умолчанию json"}},
+		responses: map[int]apiResponse{
+			http.StatusOK:         {contentType: textCSV + "," + applicationJSON + "," + applicationNDJSON},
+			http.StatusBadRequest: badRequest,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/user/urls/import", tag: "links",
+		summary: "Создать ссылки из выгрузки в формате csv, json или ndjson", request: []models.LinkExport{},
+		query: []apiParam{{name: "format", schema: stringSchema, description: "Формат тела, по умолчанию по Content-Type"}},
+		responses: map[int]apiResponse{
+			http.StatusOK:                   jsonResponse(models.ImportRes{}),
+			http.StatusBadRequest:           badRequest,
+			http.StatusUnsupportedMediaType: emptyResponse("Неизвестный формат тела"),
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/user/urls/:id/rules", tag: "links",
+		summary: "Правила перенаправления ссылки",
+		responses: map[int]apiResponse{
+			http.StatusOK:       jsonResponse([]models.RedirectRule{}),
+			http.StatusNotFound: notFound,
+		},
+	},
+	{
+		method: http.MethodPut, path: "/api/user/urls/:id/rules", tag: "links",
+		summary: "Заменить правила перенаправления ссылки", request: []models.RedirectRule{},
+		responses: map[int]apiResponse{
+			http.StatusNoContent:  emptyResponse("Правила сохранены"),
+			http.StatusBadRequest: badRequest,
+			http.StatusNotFound:   notFound,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/user/urls/:id/stats", tag: "links",
+		summary: "Переходы по ссылке и ее вариантам",
+		responses: map[int]apiResponse{
+			http.StatusOK:       jsonResponse(models.LinkStats{}),
+			http.StatusNotFound: notFound,
+		},
+	},
+	{
+		method: http.MethodPost, path: apiV2Path + "/shorten", tag: "v2",
+		summary: "Сократить ссылку", request: models.ShortenReq{},
+		responses: v2Responses(map[int]apiResponse{http.StatusCreated: jsonResponse(models.ShortenRes{})},
+			http.StatusBadRequest, http.StatusConflict, http.StatusUnsupportedMediaType),
+	},
+	{
+		method: http.MethodPost, path: apiV2Path + "/shorten/batch", tag: "v2",
+		summary: "Сократить несколько ссылок", request: []models.URLBatchReq{},
+		responses: v2Responses(map[int]apiResponse{http.StatusCreated: jsonResponse([]models.URLBatchRes{})},
+			http.StatusBadRequest, http.StatusConflict, http.StatusUnsupportedMediaType),
+	},
+	{
+		method: http.MethodGet, path: apiV2Path + "/user/urls", tag: "v2",
+		summary: "Ссылки пользователя, по умолчанию страница из 100 ссылок", query: listQueryParams,
+		responses: v2Responses(map[int]apiResponse{http.StatusOK: listPage}, http.StatusBadRequest),
+	},
+	{
+		method: http.MethodDelete, path: apiV2Path + "/user/urls", tag: "v2",
+		summary: "Удалить ссылки пользователя", request: models.DeleteUserURLsReq{},
+		responses: v2Responses(map[int]apiResponse{
+			http.StatusAccepted: emptyResponse("Ссылки будут удалены асинхронно"),
+		}, http.StatusBadRequest, http.StatusUnsupportedMediaType),
+	},
+	{
+		method: http.MethodGet, path: apiV2Path + "/user/urls/:id/rules", tag: "v2",
+		summary: "Правила перенаправления ссылки",
+		responses: v2Responses(map[int]apiResponse{http.StatusOK: jsonResponse([]models.RedirectRule{})},
+			http.StatusNotFound),
+	},
+	{
+		method: http.MethodPut, path: apiV2Path + "/user/urls/:id/rules", tag: "v2",
+		summary: "Заменить правила перенаправления ссылки", request: []models.RedirectRule{},
+		responses: v2Responses(map[int]apiResponse{http.StatusNoContent: emptyResponse("Правила сохранены")},
+			http.StatusBadRequest, http.StatusNotFound, http.StatusUnsupportedMediaType),
+	},
+	{
+		method: http.MethodGet, path: apiV2Path + "/user/urls/:id/stats", tag: "v2",
+		summary: "Переходы по ссылке и ее вариантам",
+		responses: v2Responses(map[int]apiResponse{http.StatusOK: jsonResponse(models.LinkStats{})},
+			http.StatusNotFound),
+	},
+	{
+		method: http.MethodGet, path: "/api/user/webhooks", tag: "webhooks",
+		summary:   "Подписки пользователя",
+		responses: map[int]apiResponse{http.StatusOK: jsonResponse([]models.Webhook{})},
+	},
+	{
+		method: http.MethodPost, path: "/api/user/webhooks", tag: "webhooks",
+		summary: "Создать подписку", request: models.WebhookReq{},
+		responses: map[int]apiResponse{
+			http.StatusCreated:    jsonResponse(models.Webhook{}),
+			http.StatusBadRequest: badRequest,
+		},
+	},
+	{
+		method: http.MethodDelete, path: "/api/user/webhooks/:id", tag: "webhooks",
+		summary: "Удалить подписку",
+		responses: map[int]apiResponse{
+			http.StatusNoContent: emptyResponse("Подписка удалена"),
+			http.StatusNotFound:  notFound,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/user/webhooks/:id/enable", tag: "webhooks",
+		summary: "Включить отключенную подписку",
+		responses: map[int]apiResponse{
+			http.StatusNoContent: emptyResponse("Подписка включена"),
+			http.StatusNotFound:  notFound,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/user/webhooks/:id/deliveries", tag: "webhooks",
+		summary: "Журнал доставок подписки",
+		responses: map[int]apiResponse{
+			http.StatusOK:       jsonResponse([]models.WebhookDelivery{}),
+			http.StatusNotFound: notFound,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/user/register", tag: "accounts",
+		summary: "Зарегистрировать аккаунт", request: models.Credentials{},
+		responses: map[int]apiResponse{
+			http.StatusCreated:    jsonResponse(models.Account{}),
+			http.StatusBadRequest: badRequest,
+			http.StatusConflict:   conflict,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/user/login", tag: "accounts",
+		summary: "Войти в аккаунт", request: models.Credentials{},
+		responses: map[int]apiResponse{
+			http.StatusOK:           jsonResponse(models.Account{}),
+			http.StatusBadRequest:   badRequest,
+			http.StatusUnauthorized: emptyResponse("Неверный логин или пароль"),
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/user/keys", tag: "api keys",
+		summary:   "Ключи API пользователя",
+		responses: map[int]apiResponse{http.StatusOK: jsonResponse([]models.APIKey{})},
+	},
+	{
+		method: http.MethodPost, path: "/api/user/keys", tag: "api keys",
+		summary: "Создать ключ API", request: models.APIKeyReq{},
+		responses: map[int]apiResponse{
+			http.StatusCreated:    jsonResponse(models.APIKeyRes{}),
+			http.StatusBadRequest: badRequest,
+		},
+	},
+	{
+		method: http.MethodDelete, path: "/api/user/keys/:id", tag: "api keys",
+		summary: "Отозвать ключ API",
+		responses: map[int]apiResponse{
+			http.StatusNoContent: emptyResponse("Ключ отозван"),
+			http.StatusNotFound:  notFound,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/internal/stats", tag: "admin",
+		summary: "Количество ссылок и пользователей",
+		responses: map[int]apiResponse{
+			http.StatusOK:        jsonResponse(models.Stats{}),
+			http.StatusForbidden: forbidden,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/api/internal/links", tag: "admin",
+		summary: "Поиск ссылок всех пользователей",
+		query: []apiParam{
+			{name: "slug", schema: stringSchema},
+			{name: "url", schema: stringSchema},
+			{name: "user_id", schema: stringSchema},
+			{name: "limit", schema: integerSchema},
+			{name: "offset", schema: integerSchema},
+		},
+		responses: map[int]apiResponse{
+			http.StatusOK:         jsonResponse([]models.Link{}),
+			http.StatusBadRequest: badRequest,
+			http.StatusForbidden:  forbidden,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/internal/links/:id/disable", tag: "admin",
+		summary: "Отключить ссылку",
+		responses: map[int]apiResponse{
+			http.StatusNoContent: emptyResponse("Ссылка отключена"),
+			http.StatusForbidden: forbidden,
+			http.StatusNotFound:  notFound,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/internal/links/:id/enable", tag: "admin",
+		summary: "Включить ссылку",
+		responses: map[int]apiResponse{
+			http.StatusNoContent: emptyResponse("Ссылка включена"),
+			http.StatusForbidden: forbidden,
+			http.StatusNotFound:  notFound,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/api/internal/users/:id/transfer", tag: "admin",
+		summary: "Передать ссылки пользователя другому", request: models.TransferReq{},
+		responses: map[int]apiResponse{
+			http.StatusOK:         jsonResponse(models.TransferRes{}),
+			http.StatusBadRequest: badRequest,
+			http.StatusForbidden:  forbidden,
+		},
+	},
+	{
+		method: http.MethodDelete, path: "/api/internal/users/:id", tag: "admin",
+		summary: "Удалить ссылки, статистику и подписки пользователя",
+		responses: map[int]apiResponse{
+			http.StatusOK:        jsonResponse(models.PurgeRes{}),
+			http.StatusForbidden: forbidden,
+		},
+	},
+}
+
+// buildOpenAPI строит документ OpenAPI по описаниям маршрутов. Схемы моделей выводятся
+// из типов Go по тегам json, поэтому изменения моделей попадают в спецификацию автоматически.
+func buildOpenAPI(routes []apiRoute) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    openAPIInfo{Title: "Shortener", Version: "1.0"},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{
+			Schemas: make(map[string]*openAPISchema),
+			SecuritySchemes: map[string]*openAPISecurityScheme{
+				"cookie": {Type: "apiKey", In: "cookie", Name: auth.CookieName},
+				"bearer": {Type: "http", Scheme: "bearer"},
+			},
+		},
+		// без cookie пользователь создается автоматически
+		Security: []map[string][]string{{"cookie": {}}, {"bearer": {}}, {}},
+	}
+
+	for _, route := range routes {
+		path, params := openAPIPathTemplate(route.path)
+		op := &openAPIOperation{
+			Summary:    route.summary,
+			Tags:       []string{route.tag},
+			Parameters: params,
+			Responses:  make(map[string]*openAPIResponse, len(route.responses)),
+		}
+		if route.public {
+			op.Security = []map[string][]string{{}}
+		}
+		for _, param := range route.query {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name: param.name, In: "query", Schema: param.schema, Description: param.description,
+			})
+		}
+
+		switch {
+		case route.contentType != "":
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  map[string]openAPIMediaType{route.contentType: {Schema: stringSchema}},
+			}
+		case route.request != nil:
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content: map[string]openAPIMediaType{
+					applicationJSON: {Schema: doc.schema(reflect.TypeOf(route.request))},
+				},
+			}
+		}
+
+		for code, res := range route.responses {
+			op.Responses[strconv.Itoa(code)] = doc.response(code, res)
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(route.method)] = op
+	}
+
+	return doc
+}
+
+func (doc *openAPIDocument) response(code int, res apiResponse) *openAPIResponse {
+	result := &openAPIResponse{Description: res.description}
+	if result.Description == "" {
+		result.Description = http.StatusText(code)
+	}
+
+	switch {
+	case res.model != nil:
+		result.Content = map[string]openAPIMediaType{applicationJSON: {Schema: doc.schema(reflect.TypeOf(res.model))}}
+	case res.contentType != "":
+		result.Content = make(map[string]openAPIMediaType)
+		for _, contentType := range strings.Split(res.contentType, ",") {
+			schema := stringSchema
+			if strings.HasPrefix(contentType, "image/") {
+				schema = binarySchema
+			}
+			result.Content[contentType] = openAPIMediaType{Schema: schema}
+		}
+	}
+
+	if len(res.headers) > 0 {
+		result.Headers = make(map[string]openAPIHeader, len(res.headers))
+		for name, description := range res.headers {
+			result.Headers[name] = openAPIHeader{Schema: stringSchema, Description: description}
+		}
+	}
+
+	return result
+}
+
+// openAPIPathTemplate переводит путь gin (/:id) в шаблон OpenAPI (/{id}) и описывает параметры пути.
+func openAPIPathTemplate(path string) (string, []openAPIParameter) {
+	parts := strings.Split(path, "/")
+	params := make([]openAPIParameter, 0)
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			name := part[1:]
+			parts[i] = "{" + name + "}"
+			params = append(params, openAPIParameter{Name: name, In: "path", Required: true, Schema: stringSchema})
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema возвращает схему типа t. Именованные структуры регистрируются в components и
+// передаются ссылкой; встроенные структуры раскрываются так же, как это делает encoding/json.
+func (doc *openAPIDocument) schema(t reflect.Type) *openAPISchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := *doc.schema(t.Elem())
+		if schema.Ref != "" {
+			return &schema
+		}
+		schema.Nullable = true
+		return &schema
+	case reflect.Struct:
+		if t == timeType {
+			return &openAPISchema{Type: "string", Format: "date-time"}
+		}
+		name := t.Name()
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			// регистрация до обхода полей защищает от бесконечной рекурсии
+			doc.Components.Schemas[name] = &openAPISchema{}
+			schema := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+			doc.addFields(schema, t)
+			sort.Strings(schema.Required)
+			doc.Components.Schemas[name] = schema
+		}
+		return &openAPISchema{Ref: componentPrefix + name}
+	case reflect.Slice, reflect.Array:
+		return &openAPISchema{Type: "array", Items: doc.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: doc.schema(t.Elem())}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	default:
+		return &openAPISchema{}
+	}
+}
+
+// addFields добавляет в схему поля структуры t. Поля без omitempty считаются обязательными.
+func (doc *openAPIDocument) addFields(schema *openAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			doc.addFields(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = doc.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// GetOpenAPI отдает спецификацию OpenAPI HTTP API.
+func (a *App) GetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, a.openAPI)
+}
+
+// GetDocs отдает страницу интерактивной документации по спецификации OpenAPI.
+func (a *App) GetDocs(c *gin.Context) {
+	c.Header(contentType, textHTML)
+	c.Status(http.StatusOK)
+	page := docsPage{Spec: openAPIPath, Assets: docsPath}
+	if err := templates.ExecuteTemplate(c.Writer, docsTemplate, page); err != nil {
+		a.logger.Errorf("Error rendering docs: %v", err)
+	}
+}
+
+// GetDocsAsset отдает встроенные в бинарный файл стили и скрипт swagger-ui.
+func (a *App) GetDocsAsset(c *gin.Context) {
+	name := c.Param("asset")
+	assetType, ok := docsAssets[name]
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	data, err := swaggerUIFS.ReadFile(path.Join(swaggerUIDir, name))
+	if err != nil {
+		a.logger.Errorf("Error reading docs asset %s: %v", name, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header(cacheControl, docsAssetMaxAge)
+	c.Data(http.StatusOK, assetType, data)
+}
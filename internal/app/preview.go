@@ -0,0 +1,92 @@
+package app
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	previewSuffix     = "+"
+	previewParam      = "preview"
+	previewTemplate   = "preview.html"
+	createdAtLayout   = "02.01.2006 15:04 MST"
+	textHTML          = "text/html; charset=utf-8"
+	defaultCountdown  = 5
+	previewParamValue = "1"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// previewPage данные шаблона страницы предпросмотра.
+type previewPage struct {
+	ShortURL     string
+	OriginalURL  string
+	Title        string
+	CreatedAt    string
+	Delay        int
+	AutoRedirect bool
+}
+
+// isPreviewRequest проверяет, запрошен ли предпросмотр вместо перенаправления:
+// /{id}+ или /{id}?preview=1. Возвращает идентификатор ссылки без суффикса.
+func isPreviewRequest(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if strings.HasSuffix(id, previewSuffix) {
+		return strings.TrimSuffix(id, previewSuffix), true
+	}
+
+	return id, c.Query(previewParam) == previewParamValue
+}
+
+func (a *App) PreviewLink(c *gin.Context, id string) {
+	preview, err := a.coreLogic.GetPreview(c, id)
+	if err != nil {
+		a.writeError(c, "Error getting link preview", err)
+		return
+	}
+
+	a.renderPreview(c, preview, false)
+}
+
+// renderPreview отображает страницу предпросмотра. При countdown страница
+// содержит обратный отсчет и автоматически переходит по адресу назначения.
+func (a *App) renderPreview(c *gin.Context, preview *models.Preview, countdown bool) {
+	page := previewPage{
+		ShortURL:    preview.ShortURL,
+		OriginalURL: preview.OriginalURL,
+		Title:       preview.Title,
+		Delay:       a.countdown(),
+	}
+	if !preview.CreatedAt.IsZero() {
+		page.CreatedAt = preview.CreatedAt.Format(createdAtLayout)
+	}
+	if countdown {
+		// автоматический переход только на http(s), чтобы не исполнить javascript: и подобные схемы
+		if u, err := url.Parse(preview.OriginalURL); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			page.AutoRedirect = true
+		}
+	}
+
+	c.Header(cacheControl, noCache)
+	c.Header(contentType, textHTML)
+	c.Status(http.StatusOK)
+	if err := templates.ExecuteTemplate(c.Writer, previewTemplate, page); err != nil {
+		a.logger.Errorf("Error rendering preview: %v", err)
+	}
+}
+
+func (a *App) countdown() int {
+	if a.config.Countdown > 0 {
+		return a.config.Countdown
+	}
+	return defaultCountdown
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/qr"
+)
+
+const (
+	qrCacheControl = "public, max-age=86400"
+	etag           = "ETag"
+	ifNoneMatch    = "If-None-Match"
+)
+
+// parseQROptions разбирает параметры QR-кода из строки запроса:
+// format, size, level, margin, fg и bg (цвета в формате RRGGBB).
+func parseQROptions(c *gin.Context) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+
+	if format := c.Query("format"); format != "" {
+		opts.Format = strings.ToLower(format)
+	}
+	if level := c.Query("level"); level != "" {
+		opts.Level = strings.ToUpper(level)
+	}
+	if fg := c.Query("fg"); fg != "" {
+		opts.Foreground = strings.TrimPrefix(fg, "#")
+	}
+	if bg := c.Query("bg"); bg != "" {
+		opts.Background = strings.TrimPrefix(bg, "#")
+	}
+	if size := c.Query("size"); size != "" {
+		v, err := strconv.Atoi(size)
+		if err != nil {
+			return opts, qr.ErrBadOptions
+		}
+		opts.Size = v
+	}
+	if margin := c.Query("margin"); margin != "" {
+		v, err := strconv.Atoi(margin)
+		if err != nil {
+			return opts, qr.ErrBadOptions
+		}
+		opts.Margin = v
+	}
+
+	return opts, opts.Validate()
+}
+
+func (a *App) GetQRCode(c *gin.Context) {
+	opts, err := parseQROptions(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	img, err := a.coreLogic.GetQRCode(c, c.Param("id"), opts)
+	if err != nil {
+		a.writeError(c, "Error generating qr code", err)
+		return
+	}
+
+	c.Header(cacheControl, qrCacheControl)
+	c.Header(etag, img.ETag)
+	if c.GetHeader(ifNoneMatch) == img.ETag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, img.ContentType, img.Data)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/rawen554/shortener/internal/middleware/compress"
 	ginLogger "github.com/rawen554/shortener/internal/middleware/logger"
+	"github.com/rawen554/shortener/internal/middleware/requestid"
 
 	"github.com/rawen554/shortener/internal/middleware/auth"
 )
@@ -17,7 +18,14 @@ func (a *App) SetupRouter() (*gin.Engine, error) {
 		pprof.Register(r)
 	}
 
-	authMiddleware, err := auth.NewAuthMiddleware(a.config.Secret, a.logger.Named("auth_middleware"))
+	keys, err := auth.LoadKeyring(a.config.JWTKeysFile, a.config.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("error loading signing keys: %w", err)
+	}
+	a.keys = keys
+	a.openAPI = buildOpenAPI(apiRoutes)
+
+	authMiddleware, err := auth.NewAuthMiddleware(a.keys, a.tokenTTL(), a.coreLogic, a.logger.Named("auth_middleware"))
 	if err != nil {
 		return nil, fmt.Errorf("error initializing auth middleware: %w", err)
 	}
@@ -25,12 +33,24 @@ func (a *App) SetupRouter() (*gin.Engine, error) {
 	subnetAuthMiddleware := auth.NewSubnetChecker(a.config.TrustedSubnet, a.logger.Named("subnet_middleware"))
 
 	r.Use(ginLogger.Logger(a.logger.Named("middleware")))
+	r.Use(requestid.RequestID())
+	r.NoRoute(noRoute)
+
+	// служебные маршруты регистрируются до middleware аутентификации и не выдают cookie
+	r.GET(pingPath, a.Ping)
+	r.GET(healthzPath, a.Healthz)
+	r.GET(readyzPath, a.Readyz)
+	r.GET(openAPIPath, a.GetOpenAPI)
+	r.GET(docsPath, a.GetDocs)
+	r.GET(docsAssetPath, compress.Compress(), a.GetDocsAsset)
+	r.GET(jwksPath, a.GetJWKS)
+
 	r.Use(authMiddleware)
 	r.Use(compress.Compress())
 
 	r.GET("/:id", a.RedirectToOriginal)
+	r.GET("/:id/qr", a.GetQRCode)
 	r.POST(rootPath, a.ShortenURL)
-	r.GET(pingPath, a.Ping)
 
 	api := r.Group("/api")
 	{
@@ -38,18 +58,61 @@ func (a *App) SetupRouter() (*gin.Engine, error) {
 		internalAPI.Use(subnetAuthMiddleware)
 		{
 			internalAPI.GET("/stats", a.GetStats)
+			internalAPI.GET("/links", a.SearchLinks)
+			internalAPI.POST("/links/:id/disable", a.DisableLink)
+			internalAPI.POST("/links/:id/enable", a.EnableLink)
+			internalAPI.POST("/users/:id/transfer", a.TransferLinks)
+			internalAPI.DELETE("/users/:id", a.PurgeUser)
 		}
 
 		shortenerAPI := api.Group("/shorten")
 		{
 			shortenerAPI.POST("", a.ShortenURL)
 			shortenerAPI.POST("/batch", a.ShortenBatch)
+			shortenerAPI.POST("/batch/stream", a.ShortenBatchStream)
 		}
 
 		userAPI := api.Group("/user/urls")
 		{
 			userAPI.GET("", a.GetUserRecords)
 			userAPI.DELETE("", a.DeleteUserRecords)
+			userAPI.GET("/export", a.ExportLinks)
+			userAPI.POST("/import", a.ImportLinks)
+			userAPI.GET("/:id/rules", a.GetLinkRules)
+			userAPI.PUT("/:id/rules", a.SetLinkRules)
+			userAPI.GET("/:id/stats", a.GetLinkStats)
+		}
+
+		webhooksAPI := api.Group("/user/webhooks")
+		{
+			webhooksAPI.GET("", a.GetWebhooks)
+			webhooksAPI.POST("", a.CreateWebhook)
+			webhooksAPI.DELETE("/:id", a.DeleteWebhook)
+			webhooksAPI.POST("/:id/enable", a.EnableWebhook)
+			webhooksAPI.GET("/:id/deliveries", a.GetWebhookDeliveries)
+		}
+
+		api.POST("/user/register", a.Register)
+		api.POST("/user/login", a.Login)
+
+		// API v2 отвечает на ошибки телом в едином формате и принимает только JSON
+		v2API := api.Group("/v2")
+		v2API.Use(negotiateJSON)
+		{
+			v2API.POST("/shorten", a.ShortenURLV2)
+			v2API.POST("/shorten/batch", a.ShortenBatchV2)
+			v2API.GET("/user/urls", a.GetUserRecordsV2)
+			v2API.DELETE("/user/urls", a.DeleteUserRecordsV2)
+			v2API.GET("/user/urls/:id/rules", a.GetLinkRulesV2)
+			v2API.PUT("/user/urls/:id/rules", a.SetLinkRulesV2)
+			v2API.GET("/user/urls/:id/stats", a.GetLinkStatsV2)
+		}
+
+		keysAPI := api.Group("/user/keys")
+		{
+			keysAPI.GET("", a.GetAPIKeys)
+			keysAPI.POST("", a.CreateAPIKey)
+			keysAPI.DELETE("/:id", a.RevokeAPIKey)
 		}
 	}
 
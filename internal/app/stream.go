@@ -0,0 +1,280 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/middleware/requestid"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	// streamChunkSize наибольшее число ссылок, сохраняемых одним PutBatch.
+	streamChunkSize = 500
+	// streamMaxInFlight наибольшее число прочитанных, но еще не сохраненных строк.
+	// Когда очередь заполнена, тело запроса не читается, пока сохранение не догонит чтение.
+	streamMaxInFlight = 4 * streamChunkSize
+	// streamChunkWait сколько неполная порция ждет новых строк перед сохранением.
+	streamChunkWait = 100 * time.Millisecond
+	// maxStreamLine наибольшая длина строки запроса.
+	maxStreamLine = 64 << 10
+)
+
+// streamLine прочитанная строка запроса: ссылка или ошибка ее разбора.
+// Ошибка с fatal прекращает обработку запроса.
+type streamLine struct {
+	err   *models.APIError
+	item  models.URLBatchReq
+	n     int
+	fatal bool
+}
+
+// ShortenBatchStream сокращает ссылки из тела application/x-ndjson (по объекту URLBatchReq
+// в строке) и отвечает NDJSON с результатом каждой строки. Строки сохраняются порциями
+// по мере чтения, результаты порции отправляются клиенту сразу после ее сохранения.
+func (a *App) ShortenBatchStream(c *gin.Context) {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader(contentType))
+	if err != nil || mediaType != applicationNDJSON {
+		c.Writer.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// без этого сервер HTTP/1.1 перестает читать тело запроса после начала ответа
+	if !enableFullDuplex(c.Writer) && c.Request.ProtoMajor == 1 {
+		c.Writer.WriteHeader(http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	// чтение прекращается, если обработчик завершился раньше, например при разрыве соединения
+	// или ошибке сохранения: тело запроса нельзя читать после возврата из обработчика
+	done := make(chan struct{})
+	readerDone := make(chan struct{})
+	lines := make(chan streamLine, streamMaxInFlight-streamChunkSize)
+	go func() {
+		defer close(readerDone)
+		readStream(c.Request.Body, lines, done)
+	}()
+	defer func() {
+		close(done)
+		a.stopReading(c, readerDone)
+	}()
+
+	c.Header(contentType, applicationNDJSON)
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	enc := json.NewEncoder(c.Writer)
+	for more := true; more; {
+		var chunk []streamLine
+		chunk, more = nextChunk(lines)
+		if len(chunk) == 0 {
+			break
+		}
+		if c.Request.Context().Err() != nil {
+			return
+		}
+
+		results, stop := a.shortenChunk(c, chunk)
+		for _, result := range results {
+			if result.Error != nil {
+				result.Error.RequestID = c.GetString(requestid.Key)
+			}
+			if err := enc.Encode(result); err != nil {
+				a.logger.Errorf(ErrorWritingBody, err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if stop {
+			return
+		}
+	}
+}
+
+// stopReading прерывает чтение тела запроса, если оно еще идет, и ждет завершения readStream.
+// Заблокированное чтение освобождается истекшим сроком чтения, а в HTTP/2 — и закрытием тела.
+func (a *App) stopReading(c *gin.Context, readerDone <-chan struct{}) {
+	select {
+	case <-readerDone:
+		return
+	default:
+	}
+
+	if err := http.NewResponseController(c.Writer).SetReadDeadline(time.Now()); err != nil {
+		a.logger.Debugf("cant set read deadline [request %s]: %v", c.GetString(requestid.Key), err)
+	}
+	if err := c.Request.Body.Close(); err != nil {
+		a.logger.Debugf("error closing request body [request %s]: %v", c.GetString(requestid.Key), err)
+	}
+	<-readerDone
+}
+
+// shortenChunk сохраняет ссылки порции одним ShortenBatch и возвращает результаты строк
+// в порядке чтения. stop сообщает, что обработку запроса нужно прекратить.
+func (a *App) shortenChunk(c *gin.Context, chunk []streamLine) ([]models.URLBatchStreamRes, bool) {
+	results := make([]models.URLBatchStreamRes, len(chunk))
+	batch := make([]models.URLBatchReq, 0, len(chunk))
+	stop := false
+	for i, line := range chunk {
+		results[i] = models.URLBatchStreamRes{Line: line.n, CorrelationID: line.item.CorrelationID, Error: line.err}
+		stop = stop || line.fatal
+		if line.err == nil {
+			batch = append(batch, line.item)
+		}
+	}
+	if len(batch) == 0 {
+		return results, stop
+	}
+
+	saved, err := a.coreLogic.ShortenBatch(c, c.GetString(auth.UserIDKey), batch)
+	if err != nil {
+		// PutBatch не транзакционный, часть ссылок порции могла сохраниться;
+		// повторная отправка строк в PostgreSQL вернет уже сохраненные ссылки
+		a.logger.Errorf("Cant put stream chunk [request %s]: %v", c.GetString(requestid.Key), err)
+		for i := range results {
+			if results[i].Error == nil {
+				results[i].Error = &models.APIError{Code: logic.DescribeError(err).Reason, Message: internalErrorMessage}
+			}
+		}
+		return results, true
+	}
+
+	next := 0
+	for i := range results {
+		if results[i].Error == nil && next < len(saved) {
+			results[i].ShortURL = saved[next].ShortURL
+			next++
+		}
+	}
+	return results, stop
+}
+
+// nextChunk собирает порцию строк: ждет первую строку, затем добавляет строки,
+// пока порция не заполнится или новые строки не перестанут поступать в течение streamChunkWait.
+// more равно false, если строк больше не будет.
+func nextChunk(lines <-chan streamLine) ([]streamLine, bool) {
+	line, ok := <-lines
+	if !ok {
+		return nil, false
+	}
+	chunk := append(make([]streamLine, 0, streamChunkSize), line)
+	if line.fatal {
+		return chunk, false
+	}
+
+	timer := time.NewTimer(streamChunkWait)
+	defer timer.Stop()
+	for len(chunk) < streamChunkSize {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return chunk, false
+			}
+			chunk = append(chunk, line)
+			if line.fatal {
+				return chunk, false
+			}
+		case <-timer.C:
+			return chunk, true
+		}
+	}
+	return chunk, true
+}
+
+// readStream читает строки NDJSON из r и передает их в lines, закрывая канал в конце тела.
+// Ошибка чтения передается последней строкой с признаком fatal.
+func readStream(r io.Reader, lines chan<- streamLine, done <-chan struct{}) {
+	defer close(lines)
+	send := func(line streamLine) bool {
+		select {
+		case lines <- line:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxStreamLine)
+	n := 0
+	for scanner.Scan() {
+		n++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if !send(parseStreamLine(n, scanner.Bytes())) {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		message := "error reading request body"
+		if errors.Is(err, bufio.ErrTooLong) {
+			message = fmt.Sprintf("line is longer than %d bytes", maxStreamLine)
+		}
+		send(streamLine{n: n + 1, fatal: true, err: &models.APIError{Code: codeInvalidRequest, Message: message}})
+	}
+}
+
+func parseStreamLine(n int, data []byte) streamLine {
+	line := streamLine{n: n}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&line.item)
+	if err == nil && !errors.Is(dec.Decode(&json.RawMessage{}), io.EOF) {
+		err = errTrailingData
+	}
+	if err != nil {
+		line.err = &models.APIError{
+			Code:    codeInvalidJSON,
+			Message: "line is not valid JSON",
+			Details: []models.ErrorDetail{describeDecodeError(err)},
+		}
+		return line
+	}
+
+	details := make([]models.ErrorDetail, 0)
+	if line.item.CorrelationID == "" {
+		details = append(details, models.ErrorDetail{Field: "correlation_id", Description: "is required"})
+	}
+	if detail := validateURL("original_url", line.item.OriginalURL); detail != nil {
+		details = append(details, *detail)
+	}
+	if len(details) > 0 {
+		line.err = &models.APIError{Code: codeInvalidRequest, Message: "invalid request", Details: details}
+		return line
+	}
+
+	if err := logic.ValidateOptions(line.item.LinkOptions); err != nil {
+		line.err = &models.APIError{Code: logic.DescribeError(err).Reason, Message: err.Error()}
+	}
+	return line
+}
+
+// enableFullDuplex разрешает читать тело запроса HTTP/1.1 одновременно с записью ответа
+// и сообщает, удалось ли это. Метод EnableFullDuplex есть у http.ResponseWriter сервера
+// начиная с Go 1.21, в HTTP/2 чтение и запись и так идут одновременно.
+func enableFullDuplex(w http.ResponseWriter) bool {
+	for {
+		switch rw := w.(type) {
+		case interface{ EnableFullDuplex() error }:
+			return rw.EnableFullDuplex() == nil
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/middleware/requestid"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+// Причины ошибок API v2, не связанные с логикой сервиса.
+const (
+	codeInvalidJSON          = "INVALID_JSON"
+	codeInvalidRequest       = "INVALID_REQUEST"
+	codeNotAcceptable        = "NOT_ACCEPTABLE"
+	codeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	codeRouteNotFound        = "ROUTE_NOT_FOUND"
+
+	internalErrorMessage = "internal error"
+	accept               = "Accept"
+)
+
+var errTrailingData = errors.New("unexpected data after JSON value")
+
+// abortV2 отвечает ошибкой в формате API v2 и прерывает обработку запроса.
+func abortV2(c *gin.Context, status int, code string, message string, details ...models.ErrorDetail) {
+	c.AbortWithStatusJSON(status, models.ErrorRes{Error: models.APIError{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(requestid.Key),
+		Details:   details,
+	}})
+}
+
+// writeErrorV2 отвечает на ошибку логики в формате API v2. Текст внутренних ошибок
+// клиенту не передается, они журналируются с сообщением message и идентификатором запроса.
+func (a *App) writeErrorV2(c *gin.Context, message string, err error) {
+	code := httpStatus(err)
+	text := err.Error()
+	if code == http.StatusInternalServerError {
+		a.logger.Errorf("%s [request %s]: %v", message, c.GetString(requestid.Key), err)
+		text = internalErrorMessage
+	}
+	abortV2(c, code, logic.DescribeError(err).Reason, text)
+}
+
+// negotiateJSON проверяет, что клиент принимает ответ в JSON и передает тело запроса в JSON.
+func negotiateJSON(c *gin.Context) {
+	if !acceptsJSON(c.GetHeader(accept)) {
+		abortV2(c, http.StatusNotAcceptable, codeNotAcceptable, "only application/json responses are supported")
+		return
+	}
+
+	if c.Request.ContentLength != 0 {
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader(contentType))
+		if err != nil || mediaType != applicationJSON {
+			abortV2(c, http.StatusUnsupportedMediaType, codeUnsupportedMediaType,
+				"request body must be application/json")
+			return
+		}
+	}
+}
+
+// acceptsJSON разбирает заголовок Accept; отсутствие заголовка означает согласие на любой тип.
+func acceptsJSON(header string) bool {
+	if header == "" {
+		return true
+	}
+	for _, item := range strings.Split(header, ",") {
+		mediaType, _, _ := strings.Cut(item, ";")
+		switch strings.TrimSpace(strings.ToLower(mediaType)) {
+		case applicationJSON, "application/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// decodeV2 читает из тела запроса ровно одно значение JSON без неизвестных полей.
+// При ошибке отвечает 400 и возвращает false.
+func decodeV2(c *gin.Context, dst interface{}) bool {
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && !errors.Is(dec.Decode(&json.RawMessage{}), io.EOF) {
+		err = errTrailingData
+	}
+	if err != nil {
+		abortV2(c, http.StatusBadRequest, codeInvalidJSON, "request body is not valid JSON", describeDecodeError(err))
+		return false
+	}
+	return true
+}
+
+func describeDecodeError(err error) models.ErrorDetail {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return models.ErrorDetail{Description: "request body is empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return models.ErrorDetail{Description: "unexpected end of JSON"}
+	case errors.As(err, &syntaxErr):
+		return models.ErrorDetail{Description: fmt.Sprintf("%v at offset %d", syntaxErr, syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		return models.ErrorDetail{
+			Field:       typeErr.Field,
+			Description: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+
+	// DisallowUnknownFields не экспортирует тип ошибки, поле извлекается из текста
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return models.ErrorDetail{Field: strings.Trim(field, `"`), Description: "unknown field"}
+	}
+	return models.ErrorDetail{Description: err.Error()}
+}
+
+// validateURL проверяет, что value — абсолютный URL со схемой http или https.
+func validateURL(field string, value string) *models.ErrorDetail {
+	if value == "" {
+		return &models.ErrorDetail{Field: field, Description: "is required"}
+	}
+	u, err := url.ParseRequestURI(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &models.ErrorDetail{Field: field, Description: "must be an absolute http or https URL"}
+	}
+	return nil
+}
+
+// noRoute отвечает на неизвестные маршруты API v2 ошибкой в формате v2.
+// Для остальных маршрутов gin отвечает стандартной страницей 404.
+func noRoute(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, apiV2Path+"/") {
+		abortV2(c, http.StatusNotFound, codeRouteNotFound, "route not found")
+	}
+}
+
+func (a *App) ShortenURLV2(c *gin.Context) {
+	var req models.ShortenReq
+	if !decodeV2(c, &req) {
+		return
+	}
+	if detail := validateURL("url", req.URL); detail != nil {
+		abortV2(c, http.StatusBadRequest, codeInvalidRequest, "invalid request", *detail)
+		return
+	}
+
+	result, err := a.coreLogic.ShortenURL(c, c.GetString(auth.UserIDKey), req.URL, req.LinkOptions)
+	if err != nil {
+		a.writeErrorV2(c, "Error saving data", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.ShortenRes{Result: result})
+}
+
+func (a *App) ShortenBatchV2(c *gin.Context) {
+	batch := make([]models.URLBatchReq, 0)
+	if !decodeV2(c, &batch) {
+		return
+	}
+
+	details := make([]models.ErrorDetail, 0)
+	if len(batch) == 0 {
+		details = append(details, models.ErrorDetail{Description: "batch is empty"})
+	}
+	for i, item := range batch {
+		if item.CorrelationID == "" {
+			details = append(details, models.ErrorDetail{
+				Field: fmt.Sprintf("[%d].correlation_id", i), Description: "is required",
+			})
+		}
+		if detail := validateURL(fmt.Sprintf("[%d].original_url", i), item.OriginalURL); detail != nil {
+			details = append(details, *detail)
+		}
+	}
+	if len(details) > 0 {
+		abortV2(c, http.StatusBadRequest, codeInvalidRequest, "invalid request", details...)
+		return
+	}
+
+	result, err := a.coreLogic.ShortenBatch(c, c.GetString(auth.UserIDKey), batch)
+	if err != nil {
+		a.writeErrorV2(c, "Cant put batch", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetUserRecordsV2 в отличие от v1 отвечает пустым списком, а не 204, если ссылок нет,
+// и по умолчанию возвращает страницу из 100 ссылок.
+func (a *App) GetUserRecordsV2(c *gin.Context) {
+	query, cursor, err := parseListQuery(c, defaultV2ListLimit)
+	if err != nil {
+		a.writeErrorV2(c, "Error parsing list query", err)
+		return
+	}
+
+	links, next, err := a.coreLogic.ListUserLinks(c, c.GetString(auth.UserIDKey), query, cursor)
+	if err != nil {
+		a.writeErrorV2(c, "Error getting all user urls", err)
+		return
+	}
+
+	setNextPage(c, next)
+	c.JSON(http.StatusOK, links)
+}
+
+func (a *App) DeleteUserRecordsV2(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	batch := make(models.DeleteUserURLsReq, 0)
+	if !decodeV2(c, &batch) {
+		return
+	}
+
+	// контекст gin переиспользуется после ответа, поэтому удаление выполняется с собственным контекстом
+	go func() {
+		if err := a.coreLogic.DeleteUserRecords(context.Background(), userID, batch); err != nil {
+			a.logger.Errorf("error deleting: %v", err)
+		}
+	}()
+
+	c.Status(http.StatusAccepted)
+}
+
+func (a *App) GetLinkRulesV2(c *gin.Context) {
+	rules, err := a.coreLogic.GetRules(c, c.GetString(auth.UserIDKey), c.Param("id"))
+	if err != nil {
+		a.writeErrorV2(c, "Error getting link rules", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (a *App) SetLinkRulesV2(c *gin.Context) {
+	rules := make([]models.RedirectRule, 0)
+	if !decodeV2(c, &rules) {
+		return
+	}
+
+	if err := a.coreLogic.SetRules(c, c.GetString(auth.UserIDKey), c.Param("id"), rules); err != nil {
+		a.writeErrorV2(c, "Error saving link rules", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (a *App) GetLinkStatsV2(c *gin.Context) {
+	stats, err := a.coreLogic.GetLinkStats(c, c.GetString(auth.UserIDKey), c.Param("id"))
+	if err != nil {
+		a.writeErrorV2(c, "Error getting link stats", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+)
+
+func (a *App) CreateWebhook(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	var req models.WebhookReq
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		a.logger.Errorf(ErrorDecodeBody, err)
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hook, err := a.coreLogic.CreateWebhook(c, userID, req)
+	if err != nil {
+		a.writeError(c, "Error creating webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+func (a *App) GetWebhooks(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	hooks, err := a.coreLogic.GetWebhooks(c, userID)
+	if err != nil {
+		a.logger.Errorf("Error getting webhooks: %v", err)
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (a *App) DeleteWebhook(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	if err := a.coreLogic.DeleteWebhook(c, userID, c.Param("id")); err != nil {
+		a.writeError(c, "Error deleting webhook", err)
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) EnableWebhook(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	if err := a.coreLogic.EnableWebhook(c, userID, c.Param("id")); err != nil {
+		a.writeError(c, "Error enabling webhook", err)
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) GetWebhookDeliveries(c *gin.Context) {
+	userID := c.GetString(auth.UserIDKey)
+
+	deliveries, err := a.coreLogic.GetWebhookDeliveries(c, userID, c.Param("id"))
+	if err != nil {
+		a.writeError(c, "Error getting webhook deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
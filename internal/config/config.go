@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"dario.cat/mergo"
 	"github.com/caarlos0/env/v6"
+	"github.com/rawen554/shortener/internal/models"
 )
 
 type ServerConfig struct {
@@ -17,13 +20,35 @@ type ServerConfig struct {
 	FileStoragePath string `json:"file_storage_path" env:"FILE_STORAGE_PATH"`
 	DatabaseDSN     string `json:"database_dsn" env:"DATABASE_DSN"`
 	Secret          string `json:"-" env:"SECRET"`
+	JWTKeysFile     string `json:"jwt_keys_file" env:"JWT_KEYS_FILE"`
 	Config          string `json:"-" env:"CONFIG"`
 	TLSCertPath     string `json:"tls_cert_path" env:"TLS_CERT_PATH"`
 	TLSKeyPath      string `json:"tls_key_path" env:"TLS_KEY_PATH"`
 	TrustedSubnet   string `json:"trusted_subnet" env:"TRUSTED_SUBNET"`
 	GRPCPort        string `json:"grpc_port" env:"GRPC_PORT"`
+	GRPCClientCA    string `json:"grpc_client_ca_path" env:"GRPC_CLIENT_CA_PATH"`
+	CacheControl    string `json:"permanent_cache_control" env:"PERMANENT_CACHE_CONTROL"`
+	RedirectType    int    `json:"default_redirect_type" env:"DEFAULT_REDIRECT_TYPE"`
+	Countdown       int    `json:"interstitial_countdown" env:"INTERSTITIAL_COUNTDOWN"`
 	EnableHTTPS     bool   `json:"enable_https" env:"ENABLE_HTTPS"`
+	SinglePort      bool   `json:"single_port" env:"SINGLE_PORT"`
 	ProfileMode     bool   `json:"profile_mode" env:"PROFILE_MODE"`
+	// AllowLoopback разрешает webhooks и проверке ссылок обращаться к адресам loopback.
+	// Адреса частных сетей запрещены всегда.
+	AllowLoopback bool `json:"allow_loopback" env:"ALLOW_LOOPBACK"`
+
+	// Фоновая проверка доступности адресов назначения, нулевой интервал отключает проверку.
+	CheckInterval  time.Duration `json:"check_interval" env:"CHECK_INTERVAL"`
+	CheckHostDelay time.Duration `json:"check_host_delay" env:"CHECK_HOST_DELAY"`
+	CheckWorkers   int           `json:"check_workers" env:"CHECK_WORKERS"`
+
+	// ShutdownDrain сколько сервис после сигнала остановки отвечает "не готов" на пробы
+	// готовности и продолжает обслуживать запросы, прежде чем остановить серверы.
+	ShutdownDrain time.Duration `json:"shutdown_drain" env:"SHUTDOWN_DRAIN"`
+
+	// Время жизни токена пользователя и срок, в течение которого истекший токен продлевается.
+	AccessTokenTTL  time.Duration `json:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `json:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
 }
 
 var config ServerConfig
@@ -36,11 +61,24 @@ func ParseFlags() (*ServerConfig, error) {
 	flag.StringVar(&config.FileStoragePath, "f", "", "file storage path")
 	flag.StringVar(&config.DatabaseDSN, "d", "", "Data Source Name (DSN)")
 	flag.StringVar(&config.Secret, "e", "b4952c3809196592c026529df00774e46bfb5be0", "Secret")
+	flag.StringVar(&config.JWTKeysFile, "jwt-keys", "", "JWT signing keys file, reloaded on SIGHUP; overrides secret")
 	flag.StringVar(&config.Config, "c", "", "Config json file path")
 	flag.StringVar(&config.TLSCertPath, "l", "./certs/cert.pem", "path to tls cert file")
 	flag.StringVar(&config.TLSKeyPath, "k", "./certs/private.pem", "path to tls key file")
 	flag.StringVar(&config.TrustedSubnet, "t", "", "trusted CIDR (ex. 192.168.0.0/24)")
 	flag.StringVar(&config.GRPCPort, "grpc", "", "will add listener to port if specified")
+	flag.BoolVar(&config.SinglePort, "single-port", false, "serve gRPC on the HTTP server address")
+	flag.StringVar(&config.GRPCClientCA, "grpc-client-ca", "", "CA file to verify gRPC client certificates (requires https)")
+	flag.IntVar(&config.RedirectType, "r", http.StatusTemporaryRedirect, "default redirect status code (301, 302, 307, 308)")
+	flag.StringVar(&config.CacheControl, "cache-control", "public, max-age=86400", "Cache-Control for permanent redirects")
+	flag.IntVar(&config.Countdown, "countdown", 5, "interstitial page countdown in seconds")
+	flag.DurationVar(&config.CheckInterval, "check-interval", 0, "destination health check interval, 0 disables checks")
+	flag.DurationVar(&config.CheckHostDelay, "check-host-delay", time.Second, "minimal delay between checks of one host")
+	flag.IntVar(&config.CheckWorkers, "check-workers", 4, "number of concurrent destination health checks")
+	flag.BoolVar(&config.AllowLoopback, "allow-loopback", false, "allow webhooks and link checks to loopback addresses")
+	flag.DurationVar(&config.ShutdownDrain, "shutdown-drain", 5*time.Second, "delay between readiness going down and server shutdown")
+	flag.DurationVar(&config.AccessTokenTTL, "access-ttl", 3*time.Hour, "user token lifetime")
+	flag.DurationVar(&config.RefreshTokenTTL, "refresh-ttl", 30*24*time.Hour, "period since issue to refresh an expired token")
 	flag.Parse()
 
 	if err := env.Parse(&config); err != nil {
@@ -63,5 +101,31 @@ func ParseFlags() (*ServerConfig, error) {
 		}
 	}
 
+	if !models.IsValidRedirectType(config.RedirectType) {
+		return nil, fmt.Errorf("unsupported default redirect type: %d", config.RedirectType)
+	}
+
+	if config.AccessTokenTTL <= 0 || config.RefreshTokenTTL < config.AccessTokenTTL {
+		return nil, fmt.Errorf("token refresh ttl %v must not be less than access ttl %v",
+			config.RefreshTokenTTL, config.AccessTokenTTL)
+	}
+
+	if config.ShutdownDrain < 0 {
+		return nil, fmt.Errorf("negative shutdown drain %v", config.ShutdownDrain)
+	}
+
+	if config.GRPCClientCA != "" && !config.EnableHTTPS {
+		return nil, fmt.Errorf("grpc client CA %s requires https to be enabled", config.GRPCClientCA)
+	}
+
+	if config.SinglePort && config.GRPCPort != "" {
+		return nil, fmt.Errorf("grpc port %s cannot be used in single port mode", config.GRPCPort)
+	}
+
+	// на общем порту сертификат клиента нельзя требовать, не отказав обычным HTTP-клиентам
+	if config.SinglePort && config.GRPCClientCA != "" {
+		return nil, fmt.Errorf("grpc client CA %s is not supported in single port mode", config.GRPCClientCA)
+	}
+
 	return &config, nil
 }
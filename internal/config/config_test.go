@@ -4,6 +4,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParseFlags(t *testing.T) {
@@ -29,8 +30,16 @@ func TestParseFlags(t *testing.T) {
 				Secret:          "b4952c3809196592c026529df00774e46bfb5be0",
 				TLSCertPath:     "./certs/cert.pem",
 				TLSKeyPath:      "./certs/private.pem",
+				CacheControl:    "public, max-age=86400",
+				RedirectType:    307,
+				Countdown:       5,
 				EnableHTTPS:     true,
 				ProfileMode:     false,
+				CheckHostDelay:  time.Second,
+				CheckWorkers:    4,
+				ShutdownDrain:   5 * time.Second,
+				AccessTokenTTL:  3 * time.Hour,
+				RefreshTokenTTL: 30 * 24 * time.Hour,
 			},
 		},
 	}
@@ -0,0 +1,5 @@
+// Пакет e2e содержит сквозные тесты, проверяющие одинаковое поведение HTTP и gRPC API
+// на всех поддерживаемых хранилищах.
+//
+// Хранилище PostgreSQL проверяется, если задана переменная окружения TEST_DATABASE_DSN.
+package e2e
@@ -0,0 +1,705 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/app"
+	"github.com/rawen554/shortener/internal/config"
+	"github.com/rawen554/shortener/internal/handlers"
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/store"
+	"github.com/rawen554/shortener/internal/store/fs"
+	"github.com/rawen554/shortener/internal/store/memory"
+	"github.com/rawen554/shortener/internal/store/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	baseURL    = "http://short.test"
+	bufSize    = 1024 * 1024
+	iPhoneUA   = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X)"
+	desktopUA  = "Mozilla/5.0 (X11; Linux x86_64)"
+	unknownID  = "ffffffff"
+	waitDelete = 2 * time.Second
+)
+
+// outcome — результат вызова, одинаково описываемый для обоих транспортов.
+type outcome string
+
+const (
+	outcomeOK              outcome = "ok"
+	outcomeInvalid         outcome = "invalid"
+	outcomeNotFound        outcome = "not_found"
+	outcomeGone            outcome = "gone"
+	outcomeConflict        outcome = "conflict"
+	outcomeForbidden       outcome = "forbidden"
+	outcomeUnauthenticated outcome = "unauthenticated"
+	outcomeInternal        outcome = "internal"
+)
+
+func httpOutcome(code int) outcome {
+	switch {
+	case code < http.StatusBadRequest:
+		return outcomeOK
+	case code == http.StatusBadRequest:
+		return outcomeInvalid
+	case code == http.StatusUnauthorized:
+		return outcomeUnauthenticated
+	case code == http.StatusForbidden:
+		return outcomeForbidden
+	case code == http.StatusNotFound:
+		return outcomeNotFound
+	case code == http.StatusConflict:
+		return outcomeConflict
+	case code == http.StatusGone:
+		return outcomeGone
+	default:
+		return outcomeInternal
+	}
+}
+
+func grpcOutcome(err error) outcome {
+	switch status.Code(err) {
+	case codes.OK:
+		return outcomeOK
+	case codes.InvalidArgument:
+		return outcomeInvalid
+	case codes.Unauthenticated:
+		return outcomeUnauthenticated
+	case codes.PermissionDenied:
+		return outcomeForbidden
+	case codes.NotFound:
+		return outcomeNotFound
+	case codes.AlreadyExists:
+		return outcomeConflict
+	case codes.FailedPrecondition:
+		return outcomeGone
+	default:
+		return outcomeInternal
+	}
+}
+
+type redirect struct {
+	URL  string
+	Code int
+}
+
+// client — операции API, выполняемые от имени одного пользователя через один из транспортов.
+type client interface {
+	shorten(url string, opts models.LinkOptions) (string, outcome)
+	shortenBatch(items []models.URLBatchReq) ([]models.URLBatchRes, outcome)
+	resolve(id string, userAgent string) (redirect, outcome)
+	userURLs() ([]models.URLRecord, outcome)
+	deleteURLs(ids []string) outcome
+	setRules(id string, rules []models.RedirectRule) outcome
+	linkStats(id string) (models.LinkStats, outcome)
+	qrCode(id string) (string, []byte, outcome)
+	stats() outcome
+}
+
+type backend struct {
+	newStore func(t *testing.T) store.Store
+	name     string
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		newStore: func(t *testing.T) store.Store {
+			t.Helper()
+			storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+			require.NoError(t, err)
+			return storage
+		},
+	},
+	{
+		name: "fs",
+		newStore: func(t *testing.T) store.Store {
+			t.Helper()
+			storage, err := fs.NewFileStorage(filepath.Join(t.TempDir(), "storage.json"))
+			require.NoError(t, err)
+			return storage
+		},
+	},
+	{
+		name: "postgres",
+		newStore: func(t *testing.T) store.Store {
+			t.Helper()
+			dsn := os.Getenv("TEST_DATABASE_DSN")
+			if dsn == "" {
+				t.Skip("TEST_DATABASE_DSN is not set")
+			}
+			storage, err := postgres.NewPostgresStore(context.Background(), dsn)
+			require.NoError(t, err)
+			return storage
+		},
+	},
+}
+
+// env — запущенный сервис: gin и gRPC поверх bufconn на общем хранилище.
+type env struct {
+	keys     *auth.Keyring
+	httpLis  *bufconn.Listener
+	grpcConn *grpc.ClientConn
+}
+
+func newEnv(t *testing.T, b backend) *env {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop().Sugar()
+
+	conf := &config.ServerConfig{
+		RedirectBaseURL: baseURL,
+		Secret:          "b4952c3809196592c026529df00774e46bfb5be0",
+		RedirectType:    http.StatusTemporaryRedirect,
+		TrustedSubnet:   "192.168.0.0/24",
+	}
+
+	storage := b.newStore(t)
+	coreLogic := logic.NewCoreLogic(conf, storage, logger)
+	a := app.NewApp(conf, coreLogic, logger)
+	r, err := a.SetupRouter()
+	require.NoError(t, err)
+
+	httpLis := bufconn.Listen(bufSize)
+	srv := &http.Server{Handler: r, ReadHeaderTimeout: time.Second}
+	go func() { _ = srv.Serve(httpLis) }()
+
+	grpcLis := bufconn.Listen(bufSize)
+	grpcServer := handlers.NewServer(conf.TrustedSubnet, a.Keys(), coreLogic, logger)
+	go func() { _ = grpcServer.Serve(grpcLis) }()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcLis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		grpcServer.Stop()
+		_ = srv.Close()
+		coreLogic.Close()
+		storage.Close()
+	})
+
+	return &env{keys: a.Keys(), httpLis: httpLis, grpcConn: conn}
+}
+
+func (e *env) token(t *testing.T) string {
+	t.Helper()
+	token, err := auth.BuildJWTString(e.keys, randomHex(t, 16), time.Hour)
+	require.NoError(t, err)
+	return token
+}
+
+func (e *env) httpClient(t *testing.T) client {
+	t.Helper()
+	return &httpClient{
+		t:     t,
+		token: e.token(t),
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return e.httpLis.DialContext(ctx)
+				},
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (e *env) grpcClient(t *testing.T) client {
+	t.Helper()
+	return &grpcClient{
+		t:      t,
+		token:  e.token(t),
+		client: pb.NewShortenerClient(e.grpcConn),
+	}
+}
+
+type httpClient struct {
+	t      *testing.T
+	client *http.Client
+	token  string
+}
+
+func (c *httpClient) do(method string, path string, body interface{}, header http.Header) *http.Response {
+	c.t.Helper()
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(c.t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, "http://bufnet"+path, reader)
+	require.NoError(c.t, err)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: c.token})
+
+	res, err := c.client.Do(req)
+	require.NoError(c.t, err)
+	c.t.Cleanup(func() { _ = res.Body.Close() })
+	return res
+}
+
+func (c *httpClient) decode(res *http.Response, v interface{}) {
+	c.t.Helper()
+	require.NoError(c.t, json.NewDecoder(res.Body).Decode(v))
+}
+
+func (c *httpClient) shorten(url string, opts models.LinkOptions) (string, outcome) {
+	res := c.do(http.MethodPost, "/api/shorten", models.ShortenReq{URL: url, LinkOptions: opts}, nil)
+	if res.StatusCode != http.StatusCreated {
+		return "", httpOutcome(res.StatusCode)
+	}
+
+	var body models.ShortenRes
+	c.decode(res, &body)
+	return body.Result, outcomeOK
+}
+
+func (c *httpClient) shortenBatch(items []models.URLBatchReq) ([]models.URLBatchRes, outcome) {
+	res := c.do(http.MethodPost, "/api/shorten/batch", items, nil)
+	if res.StatusCode != http.StatusCreated {
+		return nil, httpOutcome(res.StatusCode)
+	}
+
+	var body []models.URLBatchRes
+	c.decode(res, &body)
+	return body, outcomeOK
+}
+
+func (c *httpClient) resolve(id string, userAgent string) (redirect, outcome) {
+	res := c.do(http.MethodGet, "/"+id, nil, http.Header{"User-Agent": {userAgent}})
+	if res.StatusCode >= http.StatusBadRequest {
+		return redirect{}, httpOutcome(res.StatusCode)
+	}
+
+	return redirect{URL: res.Header.Get("Location"), Code: res.StatusCode}, outcomeOK
+}
+
+func (c *httpClient) userURLs() ([]models.URLRecord, outcome) {
+	res := c.do(http.MethodGet, "/api/user/urls", nil, nil)
+	if res.StatusCode == http.StatusNoContent {
+		return []models.URLRecord{}, outcomeOK
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, httpOutcome(res.StatusCode)
+	}
+
+	var body []models.URLRecord
+	c.decode(res, &body)
+	return body, outcomeOK
+}
+
+func (c *httpClient) deleteURLs(ids []string) outcome {
+	return httpOutcome(c.do(http.MethodDelete, "/api/user/urls", ids, nil).StatusCode)
+}
+
+func (c *httpClient) setRules(id string, rules []models.RedirectRule) outcome {
+	return httpOutcome(c.do(http.MethodPut, "/api/user/urls/"+id+"/rules", rules, nil).StatusCode)
+}
+
+func (c *httpClient) linkStats(id string) (models.LinkStats, outcome) {
+	res := c.do(http.MethodGet, "/api/user/urls/"+id+"/stats", nil, nil)
+	if res.StatusCode != http.StatusOK {
+		return models.LinkStats{}, httpOutcome(res.StatusCode)
+	}
+
+	var body models.LinkStats
+	c.decode(res, &body)
+	return body, outcomeOK
+}
+
+func (c *httpClient) qrCode(id string) (string, []byte, outcome) {
+	res := c.do(http.MethodGet, "/"+id+"/qr", nil, nil)
+	if res.StatusCode != http.StatusOK {
+		return "", nil, httpOutcome(res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(c.t, err)
+	return res.Header.Get("Content-Type"), data, outcomeOK
+}
+
+func (c *httpClient) stats() outcome {
+	return httpOutcome(c.do(http.MethodGet, "/api/internal/stats", nil, nil).StatusCode)
+}
+
+type grpcClient struct {
+	t      *testing.T
+	client pb.ShortenerClient
+	token  string
+}
+
+func (c *grpcClient) ctx() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+c.token)
+}
+
+func (c *grpcClient) shorten(url string, opts models.LinkOptions) (string, outcome) {
+	res, err := c.client.CreateShortURL(c.ctx(), &pb.CreateShortURLRequest{
+		Url:          url,
+		RedirectType: int32(opts.RedirectType),
+		QueryMode:    opts.QueryMode,
+		Utm:          opts.UTM,
+		Rules:        rulesToPB(opts.Rules),
+		Title:        opts.Title,
+		Interstitial: opts.Interstitial,
+	})
+	return res.GetResult(), grpcOutcome(err)
+}
+
+func (c *grpcClient) shortenBatch(items []models.URLBatchReq) ([]models.URLBatchRes, outcome) {
+	records := make([]*pb.BatchCreateShortURLRequestData, 0, len(items))
+	for _, item := range items {
+		records = append(records, &pb.BatchCreateShortURLRequestData{
+			OriginalUrl:   item.OriginalURL,
+			CorrelationId: item.CorrelationID,
+			RedirectType:  int32(item.RedirectType),
+		})
+	}
+
+	res, err := c.client.BatchCreateShortURL(c.ctx(), &pb.BatchCreateShortURLRequest{Records: records})
+	if err != nil {
+		return nil, grpcOutcome(err)
+	}
+
+	result := make([]models.URLBatchRes, 0, len(res.GetRecords()))
+	for _, record := range res.GetRecords() {
+		result = append(result, models.URLBatchRes{ShortURL: record.GetShortUrl(), CorrelationID: record.GetCorrelationId()})
+	}
+	return result, outcomeOK
+}
+
+func (c *grpcClient) resolve(id string, userAgent string) (redirect, outcome) {
+	res, err := c.client.GetOriginalURL(c.ctx(), &pb.GetOriginalURLRequest{Url: id, UserAgent: userAgent})
+	if err != nil {
+		return redirect{}, grpcOutcome(err)
+	}
+
+	return redirect{URL: res.GetOriginalUrl(), Code: int(res.GetRedirectType())}, outcomeOK
+}
+
+func (c *grpcClient) userURLs() ([]models.URLRecord, outcome) {
+	res, err := c.client.GetUserURLs(c.ctx(), &pb.GetUserURLsRequest{})
+	if err != nil {
+		return nil, grpcOutcome(err)
+	}
+
+	result := make([]models.URLRecord, 0, len(res.GetRecords()))
+	for _, record := range res.GetRecords() {
+		result = append(result, models.URLRecord{ShortURL: record.GetShortUrl(), OriginalURL: record.GetOriginalUrl()})
+	}
+	return result, outcomeOK
+}
+
+func (c *grpcClient) deleteURLs(ids []string) outcome {
+	_, err := c.client.DeleteUserURLsBatch(c.ctx(), &pb.DeleteUserURLsBatchRequest{Urls: ids})
+	return grpcOutcome(err)
+}
+
+func (c *grpcClient) setRules(id string, rules []models.RedirectRule) outcome {
+	_, err := c.client.SetLinkRules(c.ctx(), &pb.SetLinkRulesRequest{Url: id, Rules: rulesToPB(rules)})
+	return grpcOutcome(err)
+}
+
+func (c *grpcClient) linkStats(id string) (models.LinkStats, outcome) {
+	res, err := c.client.GetLinkStats(c.ctx(), &pb.GetLinkStatsRequest{Url: id})
+	if err != nil {
+		return models.LinkStats{}, grpcOutcome(err)
+	}
+
+	result := models.LinkStats{Clicks: res.GetClicks(), Variants: make([]models.VariantStats, 0, len(res.GetVariants()))}
+	for _, v := range res.GetVariants() {
+		result.Variants = append(result.Variants, models.VariantStats{
+			Variant: models.Variant{ID: v.GetVariant().GetId(), URL: v.GetVariant().GetUrl(), Weight: int(v.GetVariant().GetWeight())},
+			Clicks:  v.GetClicks(),
+		})
+	}
+	return result, outcomeOK
+}
+
+func (c *grpcClient) qrCode(id string) (string, []byte, outcome) {
+	res, err := c.client.GetQRCode(c.ctx(), &pb.GetQRCodeRequest{Url: id})
+	return res.GetContentType(), res.GetData(), grpcOutcome(err)
+}
+
+func (c *grpcClient) stats() outcome {
+	_, err := c.client.GetStats(c.ctx(), &pb.ServiceStatsRequest{})
+	return grpcOutcome(err)
+}
+
+func rulesToPB(rules []models.RedirectRule) []*pb.RedirectRule {
+	result := make([]*pb.RedirectRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, &pb.RedirectRule{Platform: rule.Platform, Url: rule.URL})
+	}
+	return result
+}
+
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return hex.EncodeToString(b)
+}
+
+// transcript записывает наблюдаемое поведение сценария без случайных значений:
+// идентификаторы ссылок заменяются порядковыми псевдонимами, а адреса — шаблоном.
+type transcript struct {
+	t       *testing.T
+	aliases map[string]string
+	host    string
+	lines   []string
+}
+
+func newTranscript(t *testing.T) *transcript {
+	t.Helper()
+	return &transcript{t: t, aliases: make(map[string]string), host: randomHex(t, 4) + ".example.com"}
+}
+
+// url возвращает уникальный для прогона адрес назначения, чтобы прогоны не конфликтовали в общей БД.
+func (tr *transcript) url(path string) string {
+	return "https://" + tr.host + "/" + path
+}
+
+// slug извлекает идентификатор из короткой ссылки и запоминает его псевдоним.
+func (tr *transcript) slug(shortURL string) string {
+	tr.t.Helper()
+	require.True(tr.t, strings.HasPrefix(shortURL, baseURL+"/"), "unexpected short url %q", shortURL)
+	id := strings.TrimPrefix(shortURL, baseURL+"/")
+	if _, ok := tr.aliases[id]; !ok {
+		tr.aliases[id] = fmt.Sprintf("$%d", len(tr.aliases)+1)
+	}
+	return id
+}
+
+func (tr *transcript) add(step string, result outcome, values ...interface{}) {
+	line := fmt.Sprintf("%s: %s", step, result)
+	for _, v := range values {
+		line += " " + fmt.Sprint(v)
+	}
+	for id, alias := range tr.aliases {
+		line = strings.ReplaceAll(line, id, alias)
+	}
+	tr.lines = append(tr.lines, strings.ReplaceAll(line, tr.host, "dest"))
+}
+
+type scenario struct {
+	run  func(tr *transcript, c client)
+	name string
+}
+
+var scenarios = []scenario{
+	{
+		name: "shorten and resolve",
+		run: func(tr *transcript, c client) {
+			shortURL, res := c.shorten(tr.url("default"), models.LinkOptions{})
+			require.Equal(tr.t, outcomeOK, res)
+			id := tr.slug(shortURL)
+			target, res := c.resolve(id, desktopUA)
+			assert.Equal(tr.t, redirect{URL: tr.url("default"), Code: http.StatusTemporaryRedirect}, target)
+			tr.add("resolve default", res, target)
+
+			shortURL, res = c.shorten(tr.url("permanent"), models.LinkOptions{RedirectType: http.StatusMovedPermanently})
+			require.Equal(tr.t, outcomeOK, res)
+			target, res = c.resolve(tr.slug(shortURL), desktopUA)
+			assert.Equal(tr.t, http.StatusMovedPermanently, target.Code)
+			tr.add("resolve permanent", res, target)
+
+			_, res = c.shorten(tr.url("bad"), models.LinkOptions{RedirectType: http.StatusSeeOther})
+			assert.Equal(tr.t, outcomeInvalid, res)
+			tr.add("bad redirect type", res)
+
+			_, res = c.resolve(unknownID, desktopUA)
+			assert.Equal(tr.t, outcomeNotFound, res)
+			tr.add("resolve unknown", res)
+
+			_, res = c.shorten(tr.url("default"), models.LinkOptions{})
+			tr.add("shorten duplicate", res)
+		},
+	},
+	{
+		name: "batch",
+		run: func(tr *transcript, c client) {
+			created, res := c.shortenBatch([]models.URLBatchReq{
+				{CorrelationID: "first", OriginalURL: tr.url("first")},
+				{CorrelationID: "second", OriginalURL: tr.url("second")},
+			})
+			require.Equal(tr.t, outcomeOK, res)
+			require.Len(tr.t, created, 2)
+			sort.Slice(created, func(i, j int) bool { return created[i].CorrelationID < created[j].CorrelationID })
+			for _, item := range created {
+				target, res := c.resolve(tr.slug(item.ShortURL), desktopUA)
+				tr.add("resolve "+item.CorrelationID, res, target)
+			}
+
+			_, res = c.shortenBatch([]models.URLBatchReq{
+				{CorrelationID: "bad", OriginalURL: tr.url("bad"), LinkOptions: models.LinkOptions{RedirectType: 200}},
+			})
+			assert.Equal(tr.t, outcomeInvalid, res)
+			tr.add("bad batch", res)
+		},
+	},
+	{
+		name: "user urls",
+		run: func(tr *transcript, c client) {
+			records, res := c.userURLs()
+			assert.Empty(tr.t, records)
+			tr.add("empty list", res, len(records))
+
+			for _, path := range []string{"b", "a"} {
+				shortURL, res := c.shorten(tr.url(path), models.LinkOptions{})
+				require.Equal(tr.t, outcomeOK, res)
+				tr.slug(shortURL)
+			}
+
+			records, res = c.userURLs()
+			sort.Slice(records, func(i, j int) bool { return records[i].OriginalURL < records[j].OriginalURL })
+			for _, record := range records {
+				tr.slug(record.ShortURL)
+				tr.add("record", res, record.ShortURL, record.OriginalURL)
+			}
+			assert.Len(tr.t, records, 2)
+		},
+	},
+	{
+		name: "rules and stats",
+		run: func(tr *transcript, c client) {
+			shortURL, res := c.shorten(tr.url("web"), models.LinkOptions{})
+			require.Equal(tr.t, outcomeOK, res)
+			id := tr.slug(shortURL)
+
+			res = c.setRules(id, []models.RedirectRule{{Platform: models.PlatformIOS, URL: tr.url("ios")}})
+			assert.Equal(tr.t, outcomeOK, res)
+			tr.add("set rules", res)
+
+			res = c.setRules(id, []models.RedirectRule{{Platform: "plan9", URL: tr.url("plan9")}})
+			assert.Equal(tr.t, outcomeInvalid, res)
+			tr.add("bad rules", res)
+
+			res = c.setRules(unknownID, []models.RedirectRule{{Platform: models.PlatformIOS, URL: tr.url("ios")}})
+			assert.Equal(tr.t, outcomeNotFound, res)
+			tr.add("rules of unknown link", res)
+
+			for _, ua := range []string{iPhoneUA, desktopUA} {
+				target, res := c.resolve(id, ua)
+				tr.add("resolve", res, target)
+			}
+
+			stats, res := c.linkStats(id)
+			assert.Equal(tr.t, int64(2), stats.Clicks)
+			tr.add("stats", res, stats.Clicks, len(stats.Variants))
+
+			_, res = c.linkStats(unknownID)
+			tr.add("stats of unknown link", res)
+		},
+	},
+	{
+		name: "delete",
+		run: func(tr *transcript, c client) {
+			shortURL, res := c.shorten(tr.url("deleted"), models.LinkOptions{})
+			require.Equal(tr.t, outcomeOK, res)
+			id := tr.slug(shortURL)
+
+			res = c.deleteURLs([]string{id})
+			tr.add("delete", res)
+
+			// в HTTP удаление выполняется асинхронно
+			var last outcome
+			require.Eventually(tr.t, func() bool {
+				_, last = c.resolve(id, desktopUA)
+				return last != outcomeOK
+			}, waitDelete, 10*time.Millisecond)
+			// memory и fs удаляют запись, postgres помечает её удалённой
+			assert.Contains(tr.t, []outcome{outcomeGone, outcomeNotFound}, last)
+			tr.add("resolve deleted", last)
+
+			_, _, res = c.qrCode(id)
+			tr.add("qr of deleted", res)
+		},
+	},
+	{
+		name: "qr code",
+		run: func(tr *transcript, c client) {
+			shortURL, res := c.shorten(tr.url("qr"), models.LinkOptions{})
+			require.Equal(tr.t, outcomeOK, res)
+
+			contentType, data, res := c.qrCode(tr.slug(shortURL))
+			assert.NotEmpty(tr.t, data)
+			tr.add("qr", res, contentType)
+
+			_, _, res = c.qrCode(unknownID)
+			tr.add("qr of unknown link", res)
+		},
+	},
+	{
+		name: "service stats outside trusted subnet",
+		run: func(tr *transcript, c client) {
+			res := c.stats()
+			assert.Equal(tr.t, outcomeForbidden, res)
+			tr.add("stats", res)
+		},
+	},
+}
+
+// TestParity выполняет одинаковые сценарии через HTTP и gRPC и сравнивает наблюдаемое поведение.
+func TestParity(t *testing.T) {
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			for _, sc := range scenarios {
+				sc := sc
+				t.Run(sc.name, func(t *testing.T) {
+					transcripts := make(map[string][]string)
+					for name, newClient := range map[string]func(e *env, t *testing.T) client{
+						"http": (*env).httpClient,
+						"grpc": (*env).grpcClient,
+					} {
+						tr := newTranscript(t)
+						sc.run(tr, newClient(newEnv(t, b), t))
+						transcripts[name] = tr.lines
+					}
+
+					assert.Equal(t, transcripts["http"], transcripts["grpc"])
+				})
+			}
+		})
+	}
+}
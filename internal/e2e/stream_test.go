@@ -0,0 +1,74 @@
+package e2e
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGRPCStreaming проверяет потоковое создание ссылок и их постраничный обход с возобновлением по курсору.
+func TestGRPCStreaming(t *testing.T) {
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			e := newEnv(t, b)
+			c := e.grpcClient(t).(*grpcClient)
+			tr := newTranscript(t)
+
+			created, err := c.client.StreamCreateShortURLs(c.ctx())
+			require.NoError(t, err)
+
+			prefix := randomHex(t, 4)
+			requests := []*pb.BatchCreateShortURLRequestData{
+				{CorrelationId: prefix + "a", OriginalUrl: tr.url("a")},
+				{CorrelationId: prefix + "b", OriginalUrl: tr.url("b"), RedirectType: 200},
+				{CorrelationId: prefix + "c", OriginalUrl: tr.url("c")},
+				{CorrelationId: prefix + "d", OriginalUrl: tr.url("d")},
+			}
+			for _, req := range requests {
+				require.NoError(t, created.Send(req))
+
+				// подтверждение приходит до отправки следующей ссылки
+				ack, err := created.Recv()
+				require.NoError(t, err)
+				assert.Equal(t, req.GetCorrelationId(), ack.GetCorrelationId())
+				if req.GetRedirectType() != 0 {
+					assert.Empty(t, ack.GetShortUrl())
+					assert.Equal(t, "INVALID_LINK_OPTIONS", ack.GetErrorReason())
+					continue
+				}
+				assert.Equal(t, baseURL+"/"+req.GetCorrelationId(), ack.GetShortUrl())
+				assert.Empty(t, ack.GetErrorReason())
+			}
+			require.NoError(t, created.CloseSend())
+			_, err = created.Recv()
+			assert.ErrorIs(t, err, io.EOF)
+
+			list := func(cursor string, limit int) ([]string, string) {
+				stream, err := c.client.ListUserURLs(c.ctx(), &pb.ListUserURLsRequest{Cursor: cursor})
+				require.NoError(t, err)
+
+				var urls []string
+				for len(urls) < limit {
+					res, err := stream.Recv()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					require.NoError(t, err)
+					urls = append(urls, res.GetRecord().GetOriginalUrl())
+					cursor = res.GetCursor()
+				}
+				return urls, cursor
+			}
+
+			first, cursor := list("", 2)
+			assert.Equal(t, []string{tr.url("a"), tr.url("c")}, first)
+			rest, _ := list(cursor, 10)
+			assert.Equal(t, []string{tr.url("d")}, rest)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/models"
+	"go.uber.org/zap"
+)
+
+type AdminService struct {
+	pb.UnimplementedAdminServer
+	logger    *zap.SugaredLogger
+	coreLogic *logic.CoreLogic
+}
+
+func NewAdminService(logger *zap.SugaredLogger, coreLogic *logic.CoreLogic) *AdminService {
+	return &AdminService{logger: logger, coreLogic: coreLogic}
+}
+
+func (as *AdminService) SearchLinks(
+	ctx context.Context,
+	req *pb.SearchLinksRequest,
+) (*pb.SearchLinksResponse, error) {
+	links, err := as.coreLogic.SearchLinks(ctx, models.LinkFilter{
+		Slug:   req.GetSlug(),
+		URL:    req.GetUrl(),
+		UserID: req.GetUserId(),
+		Limit:  int(req.GetLimit()),
+		Offset: int(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, statusError(as.logger, "searchLinks", "", err)
+	}
+
+	result := make([]*pb.AdminLink, 0, len(links))
+	for _, link := range links {
+		result = append(result, &pb.AdminLink{
+			ShortUrl:    link.ShortURL,
+			OriginalUrl: link.OriginalURL,
+			UserId:      link.UserID,
+			Disabled:    link.Disabled,
+			CreatedAt:   link.CreatedAt.Unix(),
+		})
+	}
+
+	return &pb.SearchLinksResponse{Links: result}, nil
+}
+
+func (as *AdminService) SetLinkDisabled(
+	ctx context.Context,
+	req *pb.SetLinkDisabledRequest,
+) (*pb.SetLinkDisabledResponse, error) {
+	if err := as.coreLogic.SetLinkDisabled(ctx, req.GetUrl(), req.GetDisabled()); err != nil {
+		return nil, statusError(as.logger, "setLinkDisabled", req.GetUrl(), err)
+	}
+	as.audit(ctx, "setLinkDisabled", "link %s disabled=%t", req.GetUrl(), req.GetDisabled())
+
+	return &pb.SetLinkDisabledResponse{}, nil
+}
+
+func (as *AdminService) TransferLinks(
+	ctx context.Context,
+	req *pb.TransferLinksRequest,
+) (*pb.TransferLinksResponse, error) {
+	count, err := as.coreLogic.TransferLinks(ctx, req.GetFromUserId(), req.GetToUserId())
+	if err != nil {
+		return nil, statusError(as.logger, "transferLinks", "", err)
+	}
+	as.audit(ctx, "transferLinks", "%d links from %s to %s", count, req.GetFromUserId(), req.GetToUserId())
+
+	return &pb.TransferLinksResponse{Transferred: int64(count)}, nil
+}
+
+func (as *AdminService) PurgeUser(
+	ctx context.Context,
+	req *pb.PurgeUserRequest,
+) (*pb.PurgeUserResponse, error) {
+	count, err := as.coreLogic.PurgeUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, statusError(as.logger, "purgeUser", "", err)
+	}
+	as.audit(ctx, "purgeUser", "%d links of %s", count, req.GetUserId())
+
+	return &pb.PurgeUserResponse{Deleted: int64(count)}, nil
+}
+
+// audit журналирует изменение, выполненное администратором. При взаимном TLS
+// администратор определяется субъектом сертификата клиента, иначе — адресом соединения.
+func (as *AdminService) audit(ctx context.Context, method string, format string, args ...interface{}) {
+	actor, ok := ClientIdentity(ctx)
+	if !ok {
+		actor = peerIP(ctx)
+	}
+	as.logger.Infof("%s by %q: %s", method, actor, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/rawen554/shortener/internal/logic"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcCodes сопоставляет виды ошибок логики кодам gRPC.
+var grpcCodes = map[logic.ErrorCode]codes.Code{
+	logic.CodeInvalidArgument: codes.InvalidArgument,
+	logic.CodeNotFound:        codes.NotFound,
+	logic.CodeGone:            codes.FailedPrecondition,
+	logic.CodeConflict:        codes.AlreadyExists,
+	logic.CodeUnauthenticated: codes.Unauthenticated,
+}
+
+// linkOptionFields поля запроса, к которым относятся ошибки параметров ссылки и перехода.
+var linkOptionFields = []struct {
+	err   error
+	field string
+}{
+	{logic.ErrBadRedirectType, "redirect_type"},
+	{logic.ErrBadQueryMode, "query_mode"},
+	{logic.ErrBadUTM, "utm"},
+	{logic.ErrBadRules, "rules"},
+	{logic.ErrBadVariants, "variants"},
+	{logic.ErrBadRedirectQuery, "query"},
+}
+
+// statusError преобразует ошибку логики в статус gRPC. Статус содержит errdetails.ErrorInfo
+// с причиной ошибки, а в зависимости от вида — BadRequest, ResourceInfo или PreconditionFailure
+// для ресурса resource. Внутренние ошибки журналируются и не раскрываются клиенту.
+func statusError(logger *zap.SugaredLogger, method string, resource string, err error) error {
+	info := logic.DescribeError(err)
+	code, ok := grpcCodes[info.Code]
+	if !ok {
+		logger.Errorf("%s service err: %v", method, err)
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	details := []proto.Message{
+		&errdetails.ErrorInfo{Reason: info.Reason, Domain: logic.ErrorDomain},
+	}
+	switch info.Code {
+	case logic.CodeInvalidArgument:
+		violation := &errdetails.BadRequest_FieldViolation{Description: err.Error()}
+		for _, item := range linkOptionFields {
+			if errors.Is(err, item.err) {
+				violation.Field = item.field
+				break
+			}
+		}
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{violation},
+		})
+	case logic.CodeNotFound, logic.CodeConflict:
+		if resource != "" {
+			details = append(details, &errdetails.ResourceInfo{
+				ResourceType: "link",
+				ResourceName: resource,
+				Description:  err.Error(),
+			})
+		}
+	case logic.CodeGone:
+		details = append(details, &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{
+				{Type: info.Reason, Subject: resource, Description: err.Error()},
+			},
+		})
+	}
+
+	st, detailsErr := status.New(code, err.Error()).WithDetails(details...)
+	if detailsErr != nil {
+		logger.Errorf("error attaching error details: %v", detailsErr)
+		return status.Error(code, err.Error())
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/qr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantDetail interface{}
+		name       string
+		wantReason string
+		wantField  string
+		wantCode   codes.Code
+	}{
+		{
+			name:       "not found",
+			err:        fmt.Errorf("error getting link: %w", logic.ErrNotFound),
+			wantCode:   codes.NotFound,
+			wantReason: "NOT_FOUND",
+			wantDetail: &errdetails.ResourceInfo{},
+		},
+		{
+			name:       "deleted",
+			err:        logic.ErrIsDeleted,
+			wantCode:   codes.FailedPrecondition,
+			wantReason: "LINK_DELETED",
+			wantDetail: &errdetails.PreconditionFailure{},
+		},
+		{
+			name:       "disabled",
+			err:        logic.ErrIsDisabled,
+			wantCode:   codes.FailedPrecondition,
+			wantReason: "LINK_DISABLED",
+			wantDetail: &errdetails.PreconditionFailure{},
+		},
+		{
+			name:       "conflict",
+			err:        logic.ErrConflict,
+			wantCode:   codes.AlreadyExists,
+			wantReason: "ALREADY_EXISTS",
+			wantDetail: &errdetails.ResourceInfo{},
+		},
+		{
+			name:       "bad link options",
+			err:        fmt.Errorf("%w: 303", logic.ErrBadRedirectType),
+			wantCode:   codes.InvalidArgument,
+			wantReason: "INVALID_LINK_OPTIONS",
+			wantDetail: &errdetails.BadRequest{},
+			wantField:  "redirect_type",
+		},
+		{
+			name:       "bad qr options",
+			err:        qr.ErrBadOptions,
+			wantCode:   codes.InvalidArgument,
+			wantReason: "INVALID_QR_OPTIONS",
+			wantDetail: &errdetails.BadRequest{},
+		},
+		{
+			name:       "bad redirect query",
+			err:        fmt.Errorf("%w: invalid semicolon separator in query", logic.ErrBadRedirectQuery),
+			wantCode:   codes.InvalidArgument,
+			wantReason: "INVALID_QUERY",
+			wantDetail: &errdetails.BadRequest{},
+			wantField:  "query",
+		},
+		{
+			name:     "internal",
+			err:      errors.New("connection refused"),
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(statusError(zap.NewNop().Sugar(), "test", "abcd", tt.err))
+			require.True(t, ok)
+			assert.Equal(t, tt.wantCode, st.Code())
+
+			if tt.wantCode == codes.Internal {
+				assert.NotContains(t, st.Message(), "connection refused")
+				assert.Empty(t, st.Details())
+				return
+			}
+
+			details := st.Details()
+			require.Len(t, details, 2)
+			info, ok := details[0].(*errdetails.ErrorInfo)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantReason, info.GetReason())
+			assert.Equal(t, logic.ErrorDomain, info.GetDomain())
+			assert.IsType(t, tt.wantDetail, details[1])
+
+			switch detail := details[1].(type) {
+			case *errdetails.BadRequest:
+				assert.Equal(t, tt.wantField, detail.GetFieldViolations()[0].GetField())
+			case *errdetails.ResourceInfo:
+				assert.Equal(t, "abcd", detail.GetResourceName())
+			case *errdetails.PreconditionFailure:
+				assert.Equal(t, tt.wantReason, detail.GetViolations()[0].GetType())
+			}
+		})
+	}
+}
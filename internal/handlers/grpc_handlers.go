@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
 
 	pb "github.com/rawen554/shortener/internal/handlers/proto"
 	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
 	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/qr"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -21,14 +27,40 @@ func NewService(logger *zap.SugaredLogger, coreLogic *logic.CoreLogic) *GRPCServ
 	return &GRPCService{logger: logger, coreLogic: coreLogic}
 }
 
+// requestUserID возвращает пользователя, аутентифицированного перехватчиком.
+// Поле user_id запроса необязательно, но если указано, должно совпадать с ним.
+func requestUserID(ctx context.Context, fromRequest string) (string, error) {
+	id, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "authorization required")
+	}
+	if fromRequest != "" && fromRequest != id {
+		return "", status.Error(codes.PermissionDenied, "user_id does not match authenticated user")
+	}
+	return id, nil
+}
+
 func (gh *GRPCService) CreateShortURL(
 	ctx context.Context,
 	req *pb.CreateShortURLRequest,
 ) (*pb.CreateShortURLResponse, error) {
-	url, err := gh.coreLogic.ShortenURL(ctx, req.GetUserId(), req.GetUrl())
+	opts := models.LinkOptions{
+		RedirectType: int(req.GetRedirectType()),
+		QueryMode:    req.GetQueryMode(),
+		UTM:          req.GetUtm(),
+		Rules:        rulesFromPB(req.GetRules()),
+		Variants:     variantsFromPB(req.GetVariants()),
+		Sticky:       req.GetSticky(),
+		Title:        req.GetTitle(),
+		Interstitial: req.GetInterstitial(),
+	}
+	userID, err := requestUserID(ctx, req.GetUserId())
 	if err != nil {
-		gh.logger.Error("shortenURL service err", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, err.Error())
+		return nil, err
+	}
+	url, err := gh.coreLogic.ShortenURL(ctx, userID, req.GetUrl(), opts)
+	if err != nil {
+		return nil, statusError(gh.logger, "shortenURL", req.GetUrl(), err)
 	}
 	return &pb.CreateShortURLResponse{Result: url}, nil
 }
@@ -39,12 +71,15 @@ func (gh *GRPCService) BatchCreateShortURL(
 ) (*pb.BatchCreateShortURLResponse, error) {
 	items := []models.URLBatchReq{}
 	for _, item := range req.GetRecords() {
-		items = append(items, models.URLBatchReq{OriginalURL: item.OriginalUrl, CorrelationID: item.CorrelationId})
+		items = append(items, batchItemFromPB(item))
 	}
-	res, err := gh.coreLogic.ShortenBatch(ctx, req.GetUserId(), items)
+	userID, err := requestUserID(ctx, req.GetUserId())
 	if err != nil {
-		gh.logger.Error("shortenBatch service err", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, err.Error())
+		return nil, err
+	}
+	res, err := gh.coreLogic.ShortenBatch(ctx, userID, items)
+	if err != nil {
+		return nil, statusError(gh.logger, "shortenBatch", "", err)
 	}
 
 	result := []*pb.BatchCreateShortURLResponseData{}
@@ -60,27 +95,101 @@ func (gh *GRPCService) BatchCreateShortURL(
 	return &pb.BatchCreateShortURLResponse{Records: result}, nil
 }
 
-func (gh *GRPCService) GetByShort(
+func batchItemFromPB(item *pb.BatchCreateShortURLRequestData) models.URLBatchReq {
+	return models.URLBatchReq{
+		LinkOptions: models.LinkOptions{
+			RedirectType: int(item.GetRedirectType()),
+			QueryMode:    item.GetQueryMode(),
+			UTM:          item.GetUtm(),
+			Rules:        rulesFromPB(item.GetRules()),
+			Variants:     variantsFromPB(item.GetVariants()),
+			Sticky:       item.GetSticky(),
+			Title:        item.GetTitle(),
+			Interstitial: item.GetInterstitial(),
+		},
+		OriginalURL:   item.GetOriginalUrl(),
+		CorrelationID: item.GetCorrelationId(),
+	}
+}
+
+// StreamCreateShortURLs сохраняет ссылки по мере получения и подтверждает каждую в порядке запросов.
+// Ссылка с некорректными параметрами отклоняется в подтверждении, не прерывая поток;
+// внутренняя ошибка завершает поток.
+func (gh *GRPCService) StreamCreateShortURLs(stream pb.Shortener_StreamCreateShortURLsServer) error {
+	ctx := stream.Context()
+	userID, err := requestUserID(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for {
+		item, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ack := &pb.StreamCreateShortURLResponse{CorrelationId: item.GetCorrelationId()}
+		res, err := gh.coreLogic.ShortenBatch(ctx, userID, []models.URLBatchReq{batchItemFromPB(item)})
+		if err != nil {
+			info := logic.DescribeError(err)
+			if info.Code == logic.CodeInternal {
+				return statusError(gh.logger, "streamCreateShortURLs", "", err)
+			}
+			ack.ErrorReason = info.Reason
+			ack.Error = err.Error()
+		} else {
+			ack.ShortUrl = res[0].ShortURL
+		}
+
+		if err := stream.Send(ack); err != nil {
+			return err
+		}
+	}
+}
+
+func (gh *GRPCService) GetOriginalURL(
 	ctx context.Context,
 	req *pb.GetOriginalURLRequest,
 ) (*pb.GetOriginalURLResponse, error) {
-	originalURL, err := gh.coreLogic.GetOriginalURL(ctx, req.GetUrl())
+	query, err := url.ParseQuery(req.GetQuery())
 	if err != nil {
-		gh.logger.Error("redirectToOriginal service err", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, err.Error())
+		err = fmt.Errorf("%w: %v", logic.ErrBadRedirectQuery, err)
+		return nil, statusError(gh.logger, "redirectToOriginal", req.GetUrl(), err)
 	}
 
-	return &pb.GetOriginalURLResponse{OriginalUrl: originalURL}, nil
+	redirect, err := gh.coreLogic.GetOriginalURL(ctx, &models.RedirectReq{
+		ShortURL:  req.GetUrl(),
+		Query:     query,
+		UserAgent: req.GetUserAgent(),
+		Variant:   req.GetVariant(),
+	})
+	if err != nil {
+		return nil, statusError(gh.logger, "redirectToOriginal", req.GetUrl(), err)
+	}
+
+	return &pb.GetOriginalURLResponse{
+		OriginalUrl:  redirect.URL,
+		RedirectType: int32(redirect.RedirectType),
+		Variant:      redirect.Variant,
+		Sticky:       redirect.Sticky,
+		Interstitial: redirect.Preview != nil,
+	}, nil
 }
 
 func (gh *GRPCService) GetUserURLs(
 	ctx context.Context,
 	req *pb.GetUserURLsRequest,
 ) (*pb.GetUserURLsResponse, error) {
-	urls, err := gh.coreLogic.GetUserRecords(ctx, req.GetUserId())
+	userID, err := requestUserID(ctx, req.GetUserId())
 	if err != nil {
-		gh.logger.Error("getUserRecords service err", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, err.Error())
+		return nil, err
+	}
+	urls, err := gh.coreLogic.GetUserRecords(ctx, userID)
+	if err != nil && !errors.Is(err, logic.ErrNoContent) {
+		return nil, statusError(gh.logger, "getUserRecords", "", err)
 	}
 	result := []*pb.ShortenData{}
 	for _, item := range urls {
@@ -89,13 +198,41 @@ func (gh *GRPCService) GetUserURLs(
 	return &pb.GetUserURLsResponse{Records: result}, nil
 }
 
+// ListUserURLs передает ссылки пользователя по одной, не загружая весь список в память.
+// Обход можно возобновить с курсора последней полученной записи.
+func (gh *GRPCService) ListUserURLs(req *pb.ListUserURLsRequest, stream pb.Shortener_ListUserURLsServer) error {
+	ctx := stream.Context()
+	userID, err := requestUserID(ctx, req.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	err = gh.coreLogic.IterateUserRecords(ctx, userID, req.GetCursor(), func(record models.URLRecord, cursor string) error {
+		return stream.Send(&pb.ListUserURLsResponse{
+			Record: &pb.ShortenData{ShortUrl: record.ShortURL, OriginalUrl: record.OriginalURL},
+			Cursor: cursor,
+		})
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return statusError(gh.logger, "listUserURLs", "", err)
+	}
+
+	return nil
+}
+
 func (gh *GRPCService) DeleteUserURLsBatch(
 	ctx context.Context,
 	req *pb.DeleteUserURLsBatchRequest,
 ) (*pb.DeleteUserURLsBatchResponse, error) {
-	if err := gh.coreLogic.DeleteUserRecords(ctx, req.GetUserId(), req.GetUrls()); err != nil {
-		gh.logger.Error("DeleteUserURLsBatch service err", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, err.Error())
+	userID, err := requestUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if err := gh.coreLogic.DeleteUserRecords(ctx, userID, req.GetUrls()); err != nil {
+		return nil, statusError(gh.logger, "deleteUserURLsBatch", "", err)
 	}
 	return &pb.DeleteUserURLsBatchResponse{}, nil
 }
@@ -106,9 +243,106 @@ func (gh *GRPCService) GetStats(
 ) (*pb.ServiceStatsResponse, error) {
 	stats, err := gh.coreLogic.GetStats(ctx)
 	if err != nil {
-		gh.logger.Error("getStats service err", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, err.Error())
+		return nil, statusError(gh.logger, "getStats", "", err)
 	}
 
 	return &pb.ServiceStatsResponse{Urls: int64(stats.URLs), Users: int64(stats.Users)}, nil
 }
+
+func (gh *GRPCService) SetLinkRules(
+	ctx context.Context,
+	req *pb.SetLinkRulesRequest,
+) (*pb.SetLinkRulesResponse, error) {
+	userID, err := requestUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if err := gh.coreLogic.SetRules(ctx, userID, req.GetUrl(), rulesFromPB(req.GetRules())); err != nil {
+		return nil, statusError(gh.logger, "setLinkRules", req.GetUrl(), err)
+	}
+
+	return &pb.SetLinkRulesResponse{}, nil
+}
+
+func rulesFromPB(rules []*pb.RedirectRule) []models.RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	result := make([]models.RedirectRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, models.RedirectRule{Platform: rule.GetPlatform(), URL: rule.GetUrl()})
+	}
+
+	return result
+}
+
+func (gh *GRPCService) GetLinkStats(
+	ctx context.Context,
+	req *pb.GetLinkStatsRequest,
+) (*pb.GetLinkStatsResponse, error) {
+	userID, err := requestUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	stats, err := gh.coreLogic.GetLinkStats(ctx, userID, req.GetUrl())
+	if err != nil {
+		return nil, statusError(gh.logger, "getLinkStats", req.GetUrl(), err)
+	}
+
+	variants := make([]*pb.VariantStats, 0, len(stats.Variants))
+	for _, v := range stats.Variants {
+		variants = append(variants, &pb.VariantStats{
+			Variant: &pb.Variant{Id: v.ID, Url: v.URL, Weight: int32(v.Weight)},
+			Clicks:  v.Clicks,
+		})
+	}
+
+	return &pb.GetLinkStatsResponse{Clicks: stats.Clicks, Variants: variants}, nil
+}
+
+func variantsFromPB(variants []*pb.Variant) []models.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	result := make([]models.Variant, 0, len(variants))
+	for _, v := range variants {
+		result = append(result, models.Variant{ID: v.GetId(), URL: v.GetUrl(), Weight: int(v.GetWeight())})
+	}
+
+	return result
+}
+
+func (gh *GRPCService) GetQRCode(
+	ctx context.Context,
+	req *pb.GetQRCodeRequest,
+) (*pb.GetQRCodeResponse, error) {
+	opts := qr.DefaultOptions()
+	if req.GetFormat() != "" {
+		opts.Format = req.GetFormat()
+	}
+	if req.GetSize() != 0 {
+		opts.Size = int(req.GetSize())
+	}
+	if req.GetLevel() != "" {
+		opts.Level = req.GetLevel()
+	}
+	// нулевое поле отличается от незаданного: margin 0 убирает поля вокруг кода
+	if req.Margin != nil {
+		opts.Margin = int(req.GetMargin())
+	}
+	if req.GetForeground() != "" {
+		opts.Foreground = req.GetForeground()
+	}
+	if req.GetBackground() != "" {
+		opts.Background = req.GetBackground()
+	}
+
+	img, err := gh.coreLogic.GetQRCode(ctx, req.GetUrl(), opts)
+	if err != nil {
+		return nil, statusError(gh.logger, "getQRCode", req.GetUrl(), err)
+	}
+
+	return &pb.GetQRCodeResponse{ContentType: img.ContentType, Data: img.Data}, nil
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rawen554/shortener/internal/config"
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGRPCService_GetQRCodeMargin(t *testing.T) {
+	storage, err := memory.NewMemoryStorage(map[string]models.URLRecordMemory{
+		"abc": {OriginalURL: "https://ya.ru"},
+	})
+	require.NoError(t, err)
+	coreLogic := logic.NewCoreLogic(&config.ServerConfig{RedirectBaseURL: "http://localhost:8080"}, storage, zap.NewNop().Sugar())
+	defer coreLogic.Close()
+	gh := NewService(zap.NewNop().Sugar(), coreLogic)
+
+	qrCode := func(margin *int32) []byte {
+		res, err := gh.GetQRCode(context.Background(), &pb.GetQRCodeRequest{Url: "abc", Format: "svg", Margin: margin})
+		require.NoError(t, err)
+		return res.GetData()
+	}
+
+	byDefault := qrCode(nil)
+	assert.Equal(t, byDefault, qrCode(proto.Int32(4)))
+	// margin 0 задан явно и отличается от незаданного
+	assert.NotEqual(t, byDefault, qrCode(proto.Int32(0)))
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/health"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthWatchInterval как часто Watch перепроверяет состояние сервиса.
+const healthWatchInterval = time.Second
+
+// HealthService реализует grpc.health.v1.Health поверх реестра компонентов сервиса.
+// Пустое имя сервиса и имена сервисов shortener сообщают готовность всего сервиса.
+type HealthService struct {
+	healthpb.UnimplementedHealthServer
+	checker  *health.Checker
+	services map[string]struct{}
+	interval time.Duration
+}
+
+func NewHealthService(checker *health.Checker) *HealthService {
+	return &HealthService{
+		checker: checker,
+		services: map[string]struct{}{
+			"":                                   {},
+			pb.Shortener_ServiceDesc.ServiceName: {},
+			pb.Admin_ServiceDesc.ServiceName:     {},
+		},
+		interval: healthWatchInterval,
+	}
+}
+
+func (hs *HealthService) Check(
+	ctx context.Context,
+	req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	if _, ok := hs.services[req.GetService()]; !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: hs.servingStatus(ctx)}, nil
+}
+
+// Watch отправляет текущее состояние и затем каждое его изменение до отмены вызова.
+func (hs *HealthService) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(hs.interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		current := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		if _, ok := hs.services[req.GetService()]; ok {
+			current = hs.servingStatus(ctx)
+		}
+		if current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (hs *HealthService) servingStatus(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if hs.checker.Check(ctx).Ready() {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
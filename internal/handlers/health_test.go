@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rawen554/shortener/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestHealthService(t *testing.T) {
+	checker := health.NewChecker()
+	grpcComponent := checker.Component("grpc")
+
+	hs := NewHealthService(checker)
+	hs.interval = 10 * time.Millisecond
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := healthpb.NewHealthClient(conn)
+
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, codes.Code) {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		return res.GetStatus(), status.Code(err)
+	}
+
+	got, code := check("")
+	assert.Equal(t, codes.OK, code)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, got, "component is starting")
+
+	_, code = check("unknown.Service")
+	assert.Equal(t, codes.NotFound, code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "shortener.Shortener"})
+	require.NoError(t, err)
+
+	res, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+
+	grpcComponent.SetReady()
+	res, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+	got, _ = check("shortener.Admin")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, got)
+
+	checker.Shutdown()
+	res, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+}
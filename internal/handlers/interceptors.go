@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// authorizationKey ключ метаданных с токеном или ключом API, аналог заголовка Authorization.
+	authorizationKey = "authorization"
+)
+
+// readOnlyMethods методы, доступные ключам API только для чтения.
+var readOnlyMethods = map[string]struct{}{
+	pb.Shortener_GetOriginalURL_FullMethodName: {},
+	pb.Shortener_GetUserURLs_FullMethodName:    {},
+	pb.Shortener_GetStats_FullMethodName:       {},
+	pb.Shortener_GetLinkStats_FullMethodName:   {},
+	pb.Shortener_GetQRCode_FullMethodName:      {},
+}
+
+// anonymousMethods методы, не требующие аутентификации пользователя.
+var anonymousMethods = map[string]struct{}{
+	pb.Shortener_GetOriginalURL_FullMethodName: {},
+	pb.Shortener_GetStats_FullMethodName:       {},
+	pb.Shortener_GetQRCode_FullMethodName:      {},
+}
+
+// NewSubnetInterceptor пропускает вызовы сервисов и методов protected только из доверенной подсети.
+// Элемент protected — имя сервиса ("package.Service") или полное имя метода ("/package.Service/Method").
+// Проверяется адрес соединения клиента.
+func NewSubnetInterceptor(
+	trustedSubnet string,
+	logger *zap.SugaredLogger,
+	protected ...string,
+) grpc.UnaryServerInterceptor {
+	check := newSubnetCheck(trustedSubnet, logger, protected)
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewSubnetStreamInterceptor — потоковый вариант NewSubnetInterceptor.
+func NewSubnetStreamInterceptor(
+	trustedSubnet string,
+	logger *zap.SugaredLogger,
+	protected ...string,
+) grpc.StreamServerInterceptor {
+	check := newSubnetCheck(trustedSubnet, logger, protected)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func newSubnetCheck(
+	trustedSubnet string,
+	logger *zap.SugaredLogger,
+	protected []string,
+) func(ctx context.Context, fullMethod string) error {
+	netMask := auth.ParseTrustedSubnet(trustedSubnet, logger)
+
+	return func(ctx context.Context, fullMethod string) error {
+		if !matchMethod(fullMethod, protected) {
+			return nil
+		}
+
+		if err := auth.CheckRealIP(netMask, peerIP(ctx)); err != nil {
+			identity, _ := ClientIdentity(ctx)
+			logger.Errorf("internal request %s from %q: %v", fullMethod, identity, err)
+			return status.Error(codes.PermissionDenied, "access denied")
+		}
+
+		return nil
+	}
+}
+
+// peerIP возвращает IP-адрес соединения клиента.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// Authenticator аутентифицирует вызовы gRPC по метаданным authorization в формате "Bearer <token>",
+// где token — JWT сервиса или ключ API, и сохраняет пользователя в контексте.
+// Вызовы без метаданных допускаются только для методов, не требующих пользователя.
+type Authenticator struct {
+	keys    *auth.Keyring
+	apiKeys auth.APIKeyVerifier
+	logger  *zap.SugaredLogger
+}
+
+func NewAuthenticator(keys *auth.Keyring, apiKeys auth.APIKeyVerifier, logger *zap.SugaredLogger) *Authenticator {
+	return &Authenticator{keys: keys, apiKeys: apiKeys, logger: logger}
+}
+
+// Unary возвращает перехватчик унарных вызовов.
+func (a *Authenticator) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream возвращает перехватчик потоковых вызовов.
+func (a *Authenticator) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	// пользователь нужен только методам Shortener: административные методы защищены доверенной
+	// подсетью, а проверка состояния и reflection доступны без аутентификации
+	if !matchMethod(fullMethod, []string{pb.Shortener_ServiceDesc.ServiceName}) {
+		return ctx, nil
+	}
+
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(authorizationKey); len(values) > 0 {
+			header = values[0]
+		}
+	}
+	if header == "" {
+		if _, ok := anonymousMethods[fullMethod]; ok {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "authorization required")
+	}
+
+	token, ok := auth.BearerToken(header)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "bad authorization metadata")
+	}
+
+	if isJWT(token) && a.keys != nil {
+		userID, err := auth.GetUserID(token, a.keys)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		}
+		return auth.WithUserID(ctx, userID), nil
+	}
+
+	if a.apiKeys == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+	key, err := a.apiKeys.VerifyAPIKey(ctx, token)
+	if err != nil {
+		a.logger.Errorf("error verifying api key: %v", err)
+		return nil, status.Error(codes.Internal, "error verifying api key")
+	}
+	if key == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+
+	if _, ok := readOnlyMethods[fullMethod]; key.Scope == models.ScopeRead && !ok {
+		return nil, status.Error(codes.PermissionDenied, "api key is read-only")
+	}
+
+	return auth.WithUserID(ctx, key.UserID), nil
+}
+
+// isJWT отличает JWT, состоящий из трех частей через точку, от ключа API.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// serverStream подменяет контекст потока контекстом с пользователем.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// matchMethod проверяет, что метод вида /package.Service/Method совпадает с одним из методов
+// или относится к одному из сервисов.
+func matchMethod(fullMethod string, methods []string) bool {
+	for _, method := range methods {
+		if fullMethod == method || strings.HasPrefix(fullMethod, "/"+method+"/") {
+			return true
+		}
+	}
+	return false
+}
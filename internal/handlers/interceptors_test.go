@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewSubnetInterceptor(t *testing.T) {
+	interceptor := NewSubnetInterceptor(
+		"192.168.0.0/24",
+		zap.NewNop().Sugar(),
+		"shortener.Admin",
+		pb.Shortener_GetStats_FullMethodName,
+	)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		peerIP   string
+		wantCode codes.Code
+	}{
+		{name: "trusted ip", method: "/shortener.Admin/PurgeUser", peerIP: "192.168.0.10", wantCode: codes.OK},
+		{name: "untrusted ip", method: "/shortener.Admin/PurgeUser", peerIP: "10.0.0.1", wantCode: codes.PermissionDenied},
+		{name: "no peer", method: "/shortener.Admin/PurgeUser", wantCode: codes.PermissionDenied},
+		{name: "stats from trusted ip", method: pb.Shortener_GetStats_FullMethodName, peerIP: "192.168.0.10", wantCode: codes.OK},
+		{
+			name:     "stats from untrusted ip",
+			method:   pb.Shortener_GetStats_FullMethodName,
+			peerIP:   "10.0.0.1",
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "other method", method: pb.Shortener_GetUserURLs_FullMethodName, wantCode: codes.OK},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.peerIP != "" {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peerIP), Port: 50000}})
+			}
+			// заголовок x-real-ip больше не учитывается
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-real-ip", "192.168.0.10"))
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+type stubVerifier map[string]*models.APIKey
+
+func (v stubVerifier) VerifyAPIKey(ctx context.Context, secret string) (*models.APIKey, error) {
+	return v[secret], nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	keys, err := auth.NewSecretKeyring("secret")
+	require.NoError(t, err)
+	forged, err := auth.NewSecretKeyring("forged")
+	require.NoError(t, err)
+
+	token, err := auth.BuildJWTString(keys, "jwt-user", time.Hour)
+	require.NoError(t, err)
+	expired, err := auth.BuildJWTString(keys, "jwt-user", -time.Hour)
+	require.NoError(t, err)
+	forgedToken, err := auth.BuildJWTString(forged, "jwt-user", time.Hour)
+	require.NoError(t, err)
+
+	authenticator := NewAuthenticator(keys, stubVerifier{
+		"read":  {UserID: "reader", Scope: models.ScopeRead},
+		"write": {UserID: "writer", Scope: models.ScopeWrite},
+	}, zap.NewNop().Sugar())
+	interceptor := authenticator.Unary()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		userID, _ := auth.UserIDFromContext(ctx)
+		return userID, nil
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantUser      string
+		wantCode      codes.Code
+	}{
+		{name: "no credentials", method: pb.Shortener_CreateShortURL_FullMethodName, wantCode: codes.Unauthenticated},
+		{name: "no credentials on public method", method: pb.Shortener_GetOriginalURL_FullMethodName},
+		{name: "no credentials on admin method", method: pb.Admin_PurgeUser_FullMethodName},
+		{name: "no credentials on health check", method: "/grpc.health.v1.Health/Check"},
+		{
+			name:          "jwt",
+			method:        pb.Shortener_CreateShortURL_FullMethodName,
+			authorization: "Bearer " + token,
+			wantUser:      "jwt-user",
+		},
+		{
+			name:          "expired jwt",
+			method:        pb.Shortener_CreateShortURL_FullMethodName,
+			authorization: "Bearer " + expired,
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "forged jwt",
+			method:        pb.Shortener_GetUserURLs_FullMethodName,
+			authorization: "Bearer " + forgedToken,
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "write key",
+			method:        pb.Shortener_CreateShortURL_FullMethodName,
+			authorization: "Bearer write",
+			wantUser:      "writer",
+		},
+		{
+			name:          "read key on read method",
+			method:        pb.Shortener_GetUserURLs_FullMethodName,
+			authorization: "Bearer read",
+			wantUser:      "reader",
+		},
+		{
+			name:          "read key on write method",
+			method:        pb.Shortener_CreateShortURL_FullMethodName,
+			authorization: "Bearer read",
+			wantCode:      codes.PermissionDenied,
+		},
+		{
+			name:          "unknown key",
+			method:        pb.Shortener_GetUserURLs_FullMethodName,
+			authorization: "Bearer unknown",
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "not bearer",
+			method:        pb.Shortener_GetUserURLs_FullMethodName,
+			authorization: "Basic write",
+			wantCode:      codes.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationKey, tt.authorization))
+			}
+
+			got, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, tt.wantUser, got)
+			}
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationKey, "Bearer write"))
+		var got string
+		err := authenticator.Stream()(nil, &stubServerStream{ctx: ctx}, &grpc.StreamServerInfo{
+			FullMethod: pb.Shortener_CreateShortURL_FullMethodName,
+		}, func(srv interface{}, stream grpc.ServerStream) error {
+			got, _ = auth.UserIDFromContext(stream.Context())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "writer", got)
+	})
+}
+
+type stubServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stubServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRequestUserID(t *testing.T) {
+	ctx := auth.WithUserID(context.Background(), "user")
+
+	got, err := requestUserID(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "user", got)
+
+	got, err = requestUserID(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, "user", got)
+
+	_, err = requestUserID(ctx, "another")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = requestUserID(context.Background(), "user")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// grpcContentType префикс Content-Type вызовов gRPC.
+const grpcContentType = "application/grpc"
+
+// NewMixedHandler обслуживает HTTP и gRPC на одном порту: запросы HTTP/2 с Content-Type
+// application/grpc передаются grpcServer, остальные — httpHandler. Без TLS HTTP/2 принимается
+// в открытом виде (h2c), с TLS протокол согласуется через ALPN самим http.Server.
+func NewMixedHandler(grpcServer *grpc.Server, httpHandler http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType) {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
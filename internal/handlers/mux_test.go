@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewMixedHandler(t *testing.T) {
+	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "http "+r.URL.Path)
+	})
+
+	for _, useTLS := range []bool{false, true} {
+		useTLS := useTLS
+		name := "h2c"
+		if useTLS {
+			name = "tls"
+		}
+		t.Run(name, func(t *testing.T) {
+			grpcServer := NewServer("", nil, nil, zap.NewNop().Sugar())
+			healthpb.RegisterHealthServer(grpcServer, NewHealthService(health.NewChecker()))
+
+			srv := httptest.NewUnstartedServer(NewMixedHandler(grpcServer, httpHandler))
+			creds := insecure.NewCredentials()
+			if useTLS {
+				srv.EnableHTTP2 = true
+				srv.StartTLS()
+				roots := x509.NewCertPool()
+				roots.AddCert(srv.Certificate())
+				creds = credentials.NewTLS(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12})
+			} else {
+				srv.Start()
+			}
+			t.Cleanup(srv.Close)
+
+			res, err := srv.Client().Get(srv.URL + "/ping")
+			require.NoError(t, err)
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, "http /ping", string(body))
+
+			conn, err := grpc.Dial(strings.TrimPrefix(strings.TrimPrefix(srv.URL, "http://"), "https://"),
+				grpc.WithTransportCredentials(creds))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = conn.Close() })
+
+			check, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check.GetStatus())
+
+			// вызов проходит через перехватчики сервера: без доверенной подсети GetStats запрещен
+			_, err = pb.NewShortenerClient(conn).GetStats(context.Background(), &pb.ServiceStatsRequest{})
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+}
@@ -20,15 +20,21 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// ServiceStatsRequest represents a request from client.
-type ServiceStatsRequest struct {
+// SearchLinksRequest represents a request from client.
+type SearchLinksRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Slug   string `protobuf:"bytes,1,opt,name=slug,proto3" json:"slug,omitempty"`                   // Case-insensitive slug substring.
+	Url    string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`                     // Case-insensitive destination substring.
+	UserId string `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"` // Exact owner id.
+	Limit  int32  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                // 100 by default, 1000 at most.
+	Offset int32  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
 }
 
-func (x *ServiceStatsRequest) Reset() {
-	*x = ServiceStatsRequest{}
+func (x *SearchLinksRequest) Reset() {
+	*x = SearchLinksRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[0]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -36,13 +42,13 @@ func (x *ServiceStatsRequest) Reset() {
 	}
 }
 
-func (x *ServiceStatsRequest) String() string {
+func (x *SearchLinksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ServiceStatsRequest) ProtoMessage() {}
+func (*SearchLinksRequest) ProtoMessage() {}
 
-func (x *ServiceStatsRequest) ProtoReflect() protoreflect.Message {
+func (x *SearchLinksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[0]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -54,23 +60,61 @@ func (x *ServiceStatsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ServiceStatsRequest.ProtoReflect.Descriptor instead.
-func (*ServiceStatsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SearchLinksRequest.ProtoReflect.Descriptor instead.
+func (*SearchLinksRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{0}
 }
 
-// ServiceStatsResponse represents a response from server.
-type ServiceStatsResponse struct {
+func (x *SearchLinksRequest) GetSlug() string {
+	if x != nil {
+		return x.Slug
+	}
+	return ""
+}
+
+func (x *SearchLinksRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *SearchLinksRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SearchLinksRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchLinksRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// AdminLink represents repeated data in SearchLinksResponse.
+type AdminLink struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Urls  int64 `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`   // Overall urls count.
-	Users int64 `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"` // Overall users count.
+	ShortUrl    string `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl string `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	UserId      string `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Disabled    bool   `protobuf:"varint,4,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	CreatedAt   int64  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix time in seconds.
 }
 
-func (x *ServiceStatsResponse) Reset() {
-	*x = ServiceStatsResponse{}
+func (x *AdminLink) Reset() {
+	*x = AdminLink{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -78,13 +122,13 @@ func (x *ServiceStatsResponse) Reset() {
 	}
 }
 
-func (x *ServiceStatsResponse) String() string {
+func (x *AdminLink) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ServiceStatsResponse) ProtoMessage() {}
+func (*AdminLink) ProtoMessage() {}
 
-func (x *ServiceStatsResponse) ProtoReflect() protoreflect.Message {
+func (x *AdminLink) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -96,37 +140,57 @@ func (x *ServiceStatsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ServiceStatsResponse.ProtoReflect.Descriptor instead.
-func (*ServiceStatsResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use AdminLink.ProtoReflect.Descriptor instead.
+func (*AdminLink) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *ServiceStatsResponse) GetUrls() int64 {
+func (x *AdminLink) GetShortUrl() string {
 	if x != nil {
-		return x.Urls
+		return x.ShortUrl
 	}
-	return 0
+	return ""
 }
 
-func (x *ServiceStatsResponse) GetUsers() int64 {
+func (x *AdminLink) GetOriginalUrl() string {
 	if x != nil {
-		return x.Users
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *AdminLink) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AdminLink) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+func (x *AdminLink) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
 	}
 	return 0
 }
 
-// CreateShortURLRequest represents a request from client.
-type CreateShortURLRequest struct {
+// SearchLinksResponse represents a response from server.
+type SearchLinksResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Url    string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"` // Original URL.
+	Links []*AdminLink `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"` // Newest first.
 }
 
-func (x *CreateShortURLRequest) Reset() {
-	*x = CreateShortURLRequest{}
+func (x *SearchLinksResponse) Reset() {
+	*x = SearchLinksResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -134,13 +198,13 @@ func (x *CreateShortURLRequest) Reset() {
 	}
 }
 
-func (x *CreateShortURLRequest) String() string {
+func (x *SearchLinksResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateShortURLRequest) ProtoMessage() {}
+func (*SearchLinksResponse) ProtoMessage() {}
 
-func (x *CreateShortURLRequest) ProtoReflect() protoreflect.Message {
+func (x *SearchLinksResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -152,36 +216,30 @@ func (x *CreateShortURLRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateShortURLRequest.ProtoReflect.Descriptor instead.
-func (*CreateShortURLRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SearchLinksResponse.ProtoReflect.Descriptor instead.
+func (*SearchLinksResponse) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *CreateShortURLRequest) GetUserId() string {
-	if x != nil {
-		return x.UserId
-	}
-	return ""
-}
-
-func (x *CreateShortURLRequest) GetUrl() string {
+func (x *SearchLinksResponse) GetLinks() []*AdminLink {
 	if x != nil {
-		return x.Url
+		return x.Links
 	}
-	return ""
+	return nil
 }
 
-// CreateShortURLResponse represents a response from server.
-type CreateShortURLResponse struct {
+// SetLinkDisabledRequest represents a request from client.
+type SetLinkDisabledRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Result string `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Url      string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"` // Short URL.
+	Disabled bool   `protobuf:"varint,2,opt,name=disabled,proto3" json:"disabled,omitempty"`
 }
 
-func (x *CreateShortURLResponse) Reset() {
-	*x = CreateShortURLResponse{}
+func (x *SetLinkDisabledRequest) Reset() {
+	*x = SetLinkDisabledRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -189,13 +247,13 @@ func (x *CreateShortURLResponse) Reset() {
 	}
 }
 
-func (x *CreateShortURLResponse) String() string {
+func (x *SetLinkDisabledRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateShortURLResponse) ProtoMessage() {}
+func (*SetLinkDisabledRequest) ProtoMessage() {}
 
-func (x *CreateShortURLResponse) ProtoReflect() protoreflect.Message {
+func (x *SetLinkDisabledRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -207,30 +265,34 @@ func (x *CreateShortURLResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateShortURLResponse.ProtoReflect.Descriptor instead.
-func (*CreateShortURLResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetLinkDisabledRequest.ProtoReflect.Descriptor instead.
+func (*SetLinkDisabledRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *CreateShortURLResponse) GetResult() string {
+func (x *SetLinkDisabledRequest) GetUrl() string {
 	if x != nil {
-		return x.Result
+		return x.Url
 	}
 	return ""
 }
 
-// BatchCreateShortURLRequestData represents repeated data in BatchCreateShortURLRequest
-type BatchCreateShortURLRequestData struct {
+func (x *SetLinkDisabledRequest) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+// SetLinkDisabledResponse represents a response from server.
+type SetLinkDisabledResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	OriginalUrl   string `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
-	CorrelationId string `protobuf:"bytes,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
 }
 
-func (x *BatchCreateShortURLRequestData) Reset() {
-	*x = BatchCreateShortURLRequestData{}
+func (x *SetLinkDisabledResponse) Reset() {
+	*x = SetLinkDisabledResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -238,13 +300,13 @@ func (x *BatchCreateShortURLRequestData) Reset() {
 	}
 }
 
-func (x *BatchCreateShortURLRequestData) String() string {
+func (x *SetLinkDisabledResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BatchCreateShortURLRequestData) ProtoMessage() {}
+func (*SetLinkDisabledResponse) ProtoMessage() {}
 
-func (x *BatchCreateShortURLRequestData) ProtoReflect() protoreflect.Message {
+func (x *SetLinkDisabledResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -256,37 +318,23 @@ func (x *BatchCreateShortURLRequestData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BatchCreateShortURLRequestData.ProtoReflect.Descriptor instead.
-func (*BatchCreateShortURLRequestData) Descriptor() ([]byte, []int) {
+// Deprecated: Use SetLinkDisabledResponse.ProtoReflect.Descriptor instead.
+func (*SetLinkDisabledResponse) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *BatchCreateShortURLRequestData) GetOriginalUrl() string {
-	if x != nil {
-		return x.OriginalUrl
-	}
-	return ""
-}
-
-func (x *BatchCreateShortURLRequestData) GetCorrelationId() string {
-	if x != nil {
-		return x.CorrelationId
-	}
-	return ""
-}
-
-// BatchCreateShortURLRequest represents a request from client.
-type BatchCreateShortURLRequest struct {
+// TransferLinksRequest represents a request from client.
+type TransferLinksRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Records []*BatchCreateShortURLRequestData `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
-	UserId  string                            `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	FromUserId string `protobuf:"bytes,1,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
+	ToUserId   string `protobuf:"bytes,2,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
 }
 
-func (x *BatchCreateShortURLRequest) Reset() {
-	*x = BatchCreateShortURLRequest{}
+func (x *TransferLinksRequest) Reset() {
+	*x = TransferLinksRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -294,13 +342,13 @@ func (x *BatchCreateShortURLRequest) Reset() {
 	}
 }
 
-func (x *BatchCreateShortURLRequest) String() string {
+func (x *TransferLinksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BatchCreateShortURLRequest) ProtoMessage() {}
+func (*TransferLinksRequest) ProtoMessage() {}
 
-func (x *BatchCreateShortURLRequest) ProtoReflect() protoreflect.Message {
+func (x *TransferLinksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -312,37 +360,36 @@ func (x *BatchCreateShortURLRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BatchCreateShortURLRequest.ProtoReflect.Descriptor instead.
-func (*BatchCreateShortURLRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use TransferLinksRequest.ProtoReflect.Descriptor instead.
+func (*TransferLinksRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *BatchCreateShortURLRequest) GetRecords() []*BatchCreateShortURLRequestData {
+func (x *TransferLinksRequest) GetFromUserId() string {
 	if x != nil {
-		return x.Records
+		return x.FromUserId
 	}
-	return nil
+	return ""
 }
 
-func (x *BatchCreateShortURLRequest) GetUserId() string {
+func (x *TransferLinksRequest) GetToUserId() string {
 	if x != nil {
-		return x.UserId
+		return x.ToUserId
 	}
 	return ""
 }
 
-// BatchCreateShortURLResponseData represents repeated data in BatchCreateShortURLResponse
-type BatchCreateShortURLResponseData struct {
+// TransferLinksResponse represents a response from server.
+type TransferLinksResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ShortUrl      string `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
-	CorrelationId string `protobuf:"bytes,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	Transferred int64 `protobuf:"varint,1,opt,name=transferred,proto3" json:"transferred,omitempty"` // Number of transferred links.
 }
 
-func (x *BatchCreateShortURLResponseData) Reset() {
-	*x = BatchCreateShortURLResponseData{}
+func (x *TransferLinksResponse) Reset() {
+	*x = TransferLinksResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -350,13 +397,13 @@ func (x *BatchCreateShortURLResponseData) Reset() {
 	}
 }
 
-func (x *BatchCreateShortURLResponseData) String() string {
+func (x *TransferLinksResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BatchCreateShortURLResponseData) ProtoMessage() {}
+func (*TransferLinksResponse) ProtoMessage() {}
 
-func (x *BatchCreateShortURLResponseData) ProtoReflect() protoreflect.Message {
+func (x *TransferLinksResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -368,36 +415,29 @@ func (x *BatchCreateShortURLResponseData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BatchCreateShortURLResponseData.ProtoReflect.Descriptor instead.
-func (*BatchCreateShortURLResponseData) Descriptor() ([]byte, []int) {
+// Deprecated: Use TransferLinksResponse.ProtoReflect.Descriptor instead.
+func (*TransferLinksResponse) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *BatchCreateShortURLResponseData) GetShortUrl() string {
-	if x != nil {
-		return x.ShortUrl
-	}
-	return ""
-}
-
-func (x *BatchCreateShortURLResponseData) GetCorrelationId() string {
+func (x *TransferLinksResponse) GetTransferred() int64 {
 	if x != nil {
-		return x.CorrelationId
+		return x.Transferred
 	}
-	return ""
+	return 0
 }
 
-// BatchCreateShortURLResponse represents a response from server.
-type BatchCreateShortURLResponse struct {
+// PurgeUserRequest represents a request from client.
+type PurgeUserRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Records []*BatchCreateShortURLResponseData `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 }
 
-func (x *BatchCreateShortURLResponse) Reset() {
-	*x = BatchCreateShortURLResponse{}
+func (x *PurgeUserRequest) Reset() {
+	*x = PurgeUserRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -405,13 +445,13 @@ func (x *BatchCreateShortURLResponse) Reset() {
 	}
 }
 
-func (x *BatchCreateShortURLResponse) String() string {
+func (x *PurgeUserRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BatchCreateShortURLResponse) ProtoMessage() {}
+func (*PurgeUserRequest) ProtoMessage() {}
 
-func (x *BatchCreateShortURLResponse) ProtoReflect() protoreflect.Message {
+func (x *PurgeUserRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -423,30 +463,29 @@ func (x *BatchCreateShortURLResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BatchCreateShortURLResponse.ProtoReflect.Descriptor instead.
-func (*BatchCreateShortURLResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use PurgeUserRequest.ProtoReflect.Descriptor instead.
+func (*PurgeUserRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *BatchCreateShortURLResponse) GetRecords() []*BatchCreateShortURLResponseData {
+func (x *PurgeUserRequest) GetUserId() string {
 	if x != nil {
-		return x.Records
+		return x.UserId
 	}
-	return nil
+	return ""
 }
 
-// GetOriginalURLRequest represents a request from client.
-type GetOriginalURLRequest struct {
+// PurgeUserResponse represents a response from server.
+type PurgeUserResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Url    string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"` // Short URL.
+	Deleted int64 `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"` // Number of deleted links.
 }
 
-func (x *GetOriginalURLRequest) Reset() {
-	*x = GetOriginalURLRequest{}
+func (x *PurgeUserResponse) Reset() {
+	*x = PurgeUserResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -454,13 +493,13 @@ func (x *GetOriginalURLRequest) Reset() {
 	}
 }
 
-func (x *GetOriginalURLRequest) String() string {
+func (x *PurgeUserResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetOriginalURLRequest) ProtoMessage() {}
+func (*PurgeUserResponse) ProtoMessage() {}
 
-func (x *GetOriginalURLRequest) ProtoReflect() protoreflect.Message {
+func (x *PurgeUserResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -472,36 +511,35 @@ func (x *GetOriginalURLRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetOriginalURLRequest.ProtoReflect.Descriptor instead.
-func (*GetOriginalURLRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use PurgeUserResponse.ProtoReflect.Descriptor instead.
+func (*PurgeUserResponse) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *GetOriginalURLRequest) GetUserId() string {
-	if x != nil {
-		return x.UserId
-	}
-	return ""
-}
-
-func (x *GetOriginalURLRequest) GetUrl() string {
+func (x *PurgeUserResponse) GetDeleted() int64 {
 	if x != nil {
-		return x.Url
+		return x.Deleted
 	}
-	return ""
+	return 0
 }
 
-// GetOriginalURLResponse represents a response from server.
-type GetOriginalURLResponse struct {
+// GetQRCodeRequest represents a request from client.
+type GetQRCodeRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	OriginalUrl string `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Url        string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`               // Short URL.
+	Format     string `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`         // png (default) or svg.
+	Size       int32  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`            // Image side in pixels, 256 by default.
+	Level      string `protobuf:"bytes,4,opt,name=level,proto3" json:"level,omitempty"`           // Error correction level: L, M (default), Q, H.
+	Margin     *int32 `protobuf:"varint,5,opt,name=margin,proto3,oneof" json:"margin,omitempty"`  // Quiet zone in modules, 4 if not set; 0 disables it.
+	Foreground string `protobuf:"bytes,6,opt,name=foreground,proto3" json:"foreground,omitempty"` // RRGGBB, 000000 by default.
+	Background string `protobuf:"bytes,7,opt,name=background,proto3" json:"background,omitempty"` // RRGGBB, ffffff by default.
 }
 
-func (x *GetOriginalURLResponse) Reset() {
-	*x = GetOriginalURLResponse{}
+func (x *GetQRCodeRequest) Reset() {
+	*x = GetQRCodeRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_proto_shortener_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -509,13 +547,13 @@ func (x *GetOriginalURLResponse) Reset() {
 	}
 }
 
-func (x *GetOriginalURLResponse) String() string {
+func (x *GetQRCodeRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetOriginalURLResponse) ProtoMessage() {}
+func (*GetQRCodeRequest) ProtoMessage() {}
 
-func (x *GetOriginalURLResponse) ProtoReflect() protoreflect.Message {
+func (x *GetQRCodeRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -527,43 +565,86 @@ func (x *GetOriginalURLResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetOriginalURLResponse.ProtoReflect.Descriptor instead.
-func (*GetOriginalURLResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetQRCodeRequest.ProtoReflect.Descriptor instead.
+func (*GetQRCodeRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *GetOriginalURLResponse) GetOriginalUrl() string {
+func (x *GetQRCodeRequest) GetUrl() string {
 	if x != nil {
-		return x.OriginalUrl
+		return x.Url
 	}
 	return ""
 }
 
-// GetUserURLsRequest represents a request from client.
-type GetUserURLsRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *GetQRCodeRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
 
-	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+func (x *GetQRCodeRequest) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
 }
 
-func (x *GetUserURLsRequest) Reset() {
-	*x = GetUserURLsRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_shortener_proto_msgTypes[10]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *GetQRCodeRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
 	}
+	return ""
 }
 
-func (x *GetUserURLsRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *GetQRCodeRequest) GetMargin() int32 {
+	if x != nil && x.Margin != nil {
+		return *x.Margin
+	}
+	return 0
 }
 
-func (*GetUserURLsRequest) ProtoMessage() {}
+func (x *GetQRCodeRequest) GetForeground() string {
+	if x != nil {
+		return x.Foreground
+	}
+	return ""
+}
 
-func (x *GetUserURLsRequest) ProtoReflect() protoreflect.Message {
+func (x *GetQRCodeRequest) GetBackground() string {
+	if x != nil {
+		return x.Background
+	}
+	return ""
+}
+
+// GetQRCodeResponse represents a response from server.
+type GetQRCodeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ContentType string `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Data        []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *GetQRCodeResponse) Reset() {
+	*x = GetQRCodeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetQRCodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQRCodeResponse) ProtoMessage() {}
+
+func (x *GetQRCodeResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_shortener_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -575,9 +656,1230 @@ func (x *GetUserURLsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
+// Deprecated: Use GetQRCodeResponse.ProtoReflect.Descriptor instead.
+func (*GetQRCodeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetQRCodeResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *GetQRCodeResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// Variant represents a weighted A/B destination.
+type Variant struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url    string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Weight int32  `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"` // Positive relative weight.
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Variant) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Variant) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Variant) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+// VariantStats represents repeated data in GetLinkStatsResponse.
+type VariantStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Variant *Variant `protobuf:"bytes,1,opt,name=variant,proto3" json:"variant,omitempty"`
+	Clicks  int64    `protobuf:"varint,2,opt,name=clicks,proto3" json:"clicks,omitempty"`
+}
+
+func (x *VariantStats) Reset() {
+	*x = VariantStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VariantStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VariantStats) ProtoMessage() {}
+
+func (x *VariantStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VariantStats.ProtoReflect.Descriptor instead.
+func (*VariantStats) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *VariantStats) GetVariant() *Variant {
+	if x != nil {
+		return x.Variant
+	}
+	return nil
+}
+
+func (x *VariantStats) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+// GetLinkStatsRequest represents a request from client.
+type GetLinkStatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Url    string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"` // Short URL.
+}
+
+func (x *GetLinkStatsRequest) Reset() {
+	*x = GetLinkStatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLinkStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkStatsRequest) ProtoMessage() {}
+
+func (x *GetLinkStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetLinkStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetLinkStatsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetLinkStatsRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+// GetLinkStatsResponse represents a response from server.
+type GetLinkStatsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Clicks   int64           `protobuf:"varint,1,opt,name=clicks,proto3" json:"clicks,omitempty"` // Overall clicks count.
+	Variants []*VariantStats `protobuf:"bytes,2,rep,name=variants,proto3" json:"variants,omitempty"`
+}
+
+func (x *GetLinkStatsResponse) Reset() {
+	*x = GetLinkStatsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLinkStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkStatsResponse) ProtoMessage() {}
+
+func (x *GetLinkStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetLinkStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetLinkStatsResponse) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+func (x *GetLinkStatsResponse) GetVariants() []*VariantStats {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+// RedirectRule represents a platform targeted redirect.
+type RedirectRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Platform string `protobuf:"bytes,1,opt,name=platform,proto3" json:"platform,omitempty"` // ios, android, windows, macos, linux, other, mobile or desktop.
+	Url      string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`           // Destination for the platform.
+}
+
+func (x *RedirectRule) Reset() {
+	*x = RedirectRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RedirectRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedirectRule) ProtoMessage() {}
+
+func (x *RedirectRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedirectRule.ProtoReflect.Descriptor instead.
+func (*RedirectRule) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RedirectRule) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *RedirectRule) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+// SetLinkRulesRequest represents a request from client.
+type SetLinkRulesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string          `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Url    string          `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`     // Short URL.
+	Rules  []*RedirectRule `protobuf:"bytes,3,rep,name=rules,proto3" json:"rules,omitempty"` // Ordered rules, first match wins.
+}
+
+func (x *SetLinkRulesRequest) Reset() {
+	*x = SetLinkRulesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetLinkRulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkRulesRequest) ProtoMessage() {}
+
+func (x *SetLinkRulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLinkRulesRequest.ProtoReflect.Descriptor instead.
+func (*SetLinkRulesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SetLinkRulesRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetLinkRulesRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *SetLinkRulesRequest) GetRules() []*RedirectRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+// SetLinkRulesResponse represents a response from server.
+type SetLinkRulesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetLinkRulesResponse) Reset() {
+	*x = SetLinkRulesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetLinkRulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkRulesResponse) ProtoMessage() {}
+
+func (x *SetLinkRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLinkRulesResponse.ProtoReflect.Descriptor instead.
+func (*SetLinkRulesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{17}
+}
+
+// ServiceStatsRequest represents a request from client.
+type ServiceStatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ServiceStatsRequest) Reset() {
+	*x = ServiceStatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServiceStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceStatsRequest) ProtoMessage() {}
+
+func (x *ServiceStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceStatsRequest.ProtoReflect.Descriptor instead.
+func (*ServiceStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{18}
+}
+
+// ServiceStatsResponse represents a response from server.
+type ServiceStatsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Urls  int64 `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`   // Overall urls count.
+	Users int64 `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"` // Overall users count.
+}
+
+func (x *ServiceStatsResponse) Reset() {
+	*x = ServiceStatsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServiceStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceStatsResponse) ProtoMessage() {}
+
+func (x *ServiceStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceStatsResponse.ProtoReflect.Descriptor instead.
+func (*ServiceStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ServiceStatsResponse) GetUrls() int64 {
+	if x != nil {
+		return x.Urls
+	}
+	return 0
+}
+
+func (x *ServiceStatsResponse) GetUsers() int64 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+// CreateShortURLRequest represents a request from client.
+type CreateShortURLRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId       string            `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Url          string            `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`                                                                                         // Original URL.
+	RedirectType int32             `protobuf:"varint,3,opt,name=redirect_type,json=redirectType,proto3" json:"redirect_type,omitempty"`                                                  // Redirect status code (301, 302, 307, 308), 0 for service default.
+	QueryMode    string            `protobuf:"bytes,4,opt,name=query_mode,json=queryMode,proto3" json:"query_mode,omitempty"`                                                            // Incoming query passthrough mode: "", preserve, override, append.
+	Utm          map[string]string `protobuf:"bytes,5,rep,name=utm,proto3" json:"utm,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // UTM templates without utm_ prefix, {slug} and {date} are substituted.
+	Rules        []*RedirectRule   `protobuf:"bytes,6,rep,name=rules,proto3" json:"rules,omitempty"`                                                                                     // Ordered platform redirect rules, first match wins.
+	Variants     []*Variant        `protobuf:"bytes,7,rep,name=variants,proto3" json:"variants,omitempty"`                                                                               // Weighted A/B destinations.
+	Sticky       bool              `protobuf:"varint,8,opt,name=sticky,proto3" json:"sticky,omitempty"`                                                                                  // Keep the chosen variant for a visitor.
+	Title        string            `protobuf:"bytes,9,opt,name=title,proto3" json:"title,omitempty"`                                                                                     // Owner-facing title shown on the preview page.
+	Interstitial bool              `protobuf:"varint,10,opt,name=interstitial,proto3" json:"interstitial,omitempty"`                                                                     // Always show the interstitial page before redirect.
+}
+
+func (x *CreateShortURLRequest) Reset() {
+	*x = CreateShortURLRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateShortURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShortURLRequest) ProtoMessage() {}
+
+func (x *CreateShortURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShortURLRequest.ProtoReflect.Descriptor instead.
+func (*CreateShortURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CreateShortURLRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CreateShortURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CreateShortURLRequest) GetRedirectType() int32 {
+	if x != nil {
+		return x.RedirectType
+	}
+	return 0
+}
+
+func (x *CreateShortURLRequest) GetQueryMode() string {
+	if x != nil {
+		return x.QueryMode
+	}
+	return ""
+}
+
+func (x *CreateShortURLRequest) GetUtm() map[string]string {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
+func (x *CreateShortURLRequest) GetRules() []*RedirectRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *CreateShortURLRequest) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *CreateShortURLRequest) GetSticky() bool {
+	if x != nil {
+		return x.Sticky
+	}
+	return false
+}
+
+func (x *CreateShortURLRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateShortURLRequest) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+// CreateShortURLResponse represents a response from server.
+type CreateShortURLResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Result string `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+}
+
+func (x *CreateShortURLResponse) Reset() {
+	*x = CreateShortURLResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateShortURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShortURLResponse) ProtoMessage() {}
+
+func (x *CreateShortURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShortURLResponse.ProtoReflect.Descriptor instead.
+func (*CreateShortURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CreateShortURLResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+// BatchCreateShortURLRequestData represents repeated data in BatchCreateShortURLRequest
+type BatchCreateShortURLRequestData struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	OriginalUrl   string            `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	CorrelationId string            `protobuf:"bytes,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	RedirectType  int32             `protobuf:"varint,3,opt,name=redirect_type,json=redirectType,proto3" json:"redirect_type,omitempty"`                                                  // Redirect status code (301, 302, 307, 308), 0 for service default.
+	QueryMode     string            `protobuf:"bytes,4,opt,name=query_mode,json=queryMode,proto3" json:"query_mode,omitempty"`                                                            // Incoming query passthrough mode: "", preserve, override, append.
+	Utm           map[string]string `protobuf:"bytes,5,rep,name=utm,proto3" json:"utm,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // UTM templates without utm_ prefix, {slug} and {date} are substituted.
+	Rules         []*RedirectRule   `protobuf:"bytes,6,rep,name=rules,proto3" json:"rules,omitempty"`                                                                                     // Ordered platform redirect rules, first match wins.
+	Variants      []*Variant        `protobuf:"bytes,7,rep,name=variants,proto3" json:"variants,omitempty"`                                                                               // Weighted A/B destinations.
+	Sticky        bool              `protobuf:"varint,8,opt,name=sticky,proto3" json:"sticky,omitempty"`                                                                                  // Keep the chosen variant for a visitor.
+	Title         string            `protobuf:"bytes,9,opt,name=title,proto3" json:"title,omitempty"`                                                                                     // Owner-facing title shown on the preview page.
+	Interstitial  bool              `protobuf:"varint,10,opt,name=interstitial,proto3" json:"interstitial,omitempty"`                                                                     // Always show the interstitial page before redirect.
+}
+
+func (x *BatchCreateShortURLRequestData) Reset() {
+	*x = BatchCreateShortURLRequestData{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchCreateShortURLRequestData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateShortURLRequestData) ProtoMessage() {}
+
+func (x *BatchCreateShortURLRequestData) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateShortURLRequestData.ProtoReflect.Descriptor instead.
+func (*BatchCreateShortURLRequestData) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *BatchCreateShortURLRequestData) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *BatchCreateShortURLRequestData) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *BatchCreateShortURLRequestData) GetRedirectType() int32 {
+	if x != nil {
+		return x.RedirectType
+	}
+	return 0
+}
+
+func (x *BatchCreateShortURLRequestData) GetQueryMode() string {
+	if x != nil {
+		return x.QueryMode
+	}
+	return ""
+}
+
+func (x *BatchCreateShortURLRequestData) GetUtm() map[string]string {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
+func (x *BatchCreateShortURLRequestData) GetRules() []*RedirectRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *BatchCreateShortURLRequestData) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *BatchCreateShortURLRequestData) GetSticky() bool {
+	if x != nil {
+		return x.Sticky
+	}
+	return false
+}
+
+func (x *BatchCreateShortURLRequestData) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *BatchCreateShortURLRequestData) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+// BatchCreateShortURLRequest represents a request from client.
+type BatchCreateShortURLRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Records []*BatchCreateShortURLRequestData `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	UserId  string                            `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+}
+
+func (x *BatchCreateShortURLRequest) Reset() {
+	*x = BatchCreateShortURLRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchCreateShortURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateShortURLRequest) ProtoMessage() {}
+
+func (x *BatchCreateShortURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateShortURLRequest.ProtoReflect.Descriptor instead.
+func (*BatchCreateShortURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BatchCreateShortURLRequest) GetRecords() []*BatchCreateShortURLRequestData {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *BatchCreateShortURLRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+// BatchCreateShortURLResponseData represents repeated data in BatchCreateShortURLResponse
+type BatchCreateShortURLResponseData struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ShortUrl      string `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	CorrelationId string `protobuf:"bytes,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+}
+
+func (x *BatchCreateShortURLResponseData) Reset() {
+	*x = BatchCreateShortURLResponseData{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchCreateShortURLResponseData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateShortURLResponseData) ProtoMessage() {}
+
+func (x *BatchCreateShortURLResponseData) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateShortURLResponseData.ProtoReflect.Descriptor instead.
+func (*BatchCreateShortURLResponseData) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BatchCreateShortURLResponseData) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *BatchCreateShortURLResponseData) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+// BatchCreateShortURLResponse represents a response from server.
+type BatchCreateShortURLResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Records []*BatchCreateShortURLResponseData `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+}
+
+func (x *BatchCreateShortURLResponse) Reset() {
+	*x = BatchCreateShortURLResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchCreateShortURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateShortURLResponse) ProtoMessage() {}
+
+func (x *BatchCreateShortURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateShortURLResponse.ProtoReflect.Descriptor instead.
+func (*BatchCreateShortURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BatchCreateShortURLResponse) GetRecords() []*BatchCreateShortURLResponseData {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+// StreamCreateShortURLResponse acknowledges a link of StreamCreateShortURLs, in the order of requests.
+type StreamCreateShortURLResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CorrelationId string `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	ShortUrl      string `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`          // Empty if the link was rejected.
+	ErrorReason   string `protobuf:"bytes,3,opt,name=error_reason,json=errorReason,proto3" json:"error_reason,omitempty"` // Reason of the rejection as in google.rpc.ErrorInfo, e.g. INVALID_LINK_OPTIONS.
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                                // Human readable description of the rejection.
+}
+
+func (x *StreamCreateShortURLResponse) Reset() {
+	*x = StreamCreateShortURLResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamCreateShortURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamCreateShortURLResponse) ProtoMessage() {}
+
+func (x *StreamCreateShortURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamCreateShortURLResponse.ProtoReflect.Descriptor instead.
+func (*StreamCreateShortURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *StreamCreateShortURLResponse) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *StreamCreateShortURLResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *StreamCreateShortURLResponse) GetErrorReason() string {
+	if x != nil {
+		return x.ErrorReason
+	}
+	return ""
+}
+
+func (x *StreamCreateShortURLResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// GetOriginalURLRequest represents a request from client.
+type GetOriginalURLRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId    string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Url       string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`                              // Short URL.
+	Query     string `protobuf:"bytes,3,opt,name=query,proto3" json:"query,omitempty"`                          // Raw query string of the incoming request.
+	UserAgent string `protobuf:"bytes,4,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"` // User-Agent of the client, used by platform rules.
+	Variant   string `protobuf:"bytes,5,opt,name=variant,proto3" json:"variant,omitempty"`                      // Variant previously served to the visitor.
+}
+
+func (x *GetOriginalURLRequest) Reset() {
+	*x = GetOriginalURLRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetOriginalURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOriginalURLRequest) ProtoMessage() {}
+
+func (x *GetOriginalURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOriginalURLRequest.ProtoReflect.Descriptor instead.
+func (*GetOriginalURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *GetOriginalURLRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetOriginalURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *GetOriginalURLRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *GetOriginalURLRequest) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *GetOriginalURLRequest) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+// GetOriginalURLResponse represents a response from server.
+type GetOriginalURLResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	OriginalUrl  string `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	RedirectType int32  `protobuf:"varint,2,opt,name=redirect_type,json=redirectType,proto3" json:"redirect_type,omitempty"` // Redirect status code to use.
+	Variant      string `protobuf:"bytes,3,opt,name=variant,proto3" json:"variant,omitempty"`                                // Served variant, empty if the link has no variants.
+	Sticky       bool   `protobuf:"varint,4,opt,name=sticky,proto3" json:"sticky,omitempty"`                                 // Client should keep the variant for the visitor.
+	Interstitial bool   `protobuf:"varint,5,opt,name=interstitial,proto3" json:"interstitial,omitempty"`                     // Client should show the interstitial page before redirect.
+}
+
+func (x *GetOriginalURLResponse) Reset() {
+	*x = GetOriginalURLResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetOriginalURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOriginalURLResponse) ProtoMessage() {}
+
+func (x *GetOriginalURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOriginalURLResponse.ProtoReflect.Descriptor instead.
+func (*GetOriginalURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GetOriginalURLResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *GetOriginalURLResponse) GetRedirectType() int32 {
+	if x != nil {
+		return x.RedirectType
+	}
+	return 0
+}
+
+func (x *GetOriginalURLResponse) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *GetOriginalURLResponse) GetSticky() bool {
+	if x != nil {
+		return x.Sticky
+	}
+	return false
+}
+
+func (x *GetOriginalURLResponse) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+// GetUserURLsRequest represents a request from client.
+type GetUserURLsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+}
+
+func (x *GetUserURLsRequest) Reset() {
+	*x = GetUserURLsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserURLsRequest) ProtoMessage() {}
+
+func (x *GetUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
 // Deprecated: Use GetUserURLsRequest.ProtoReflect.Descriptor instead.
 func (*GetUserURLsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{10}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *GetUserURLsRequest) GetUserId() string {
@@ -600,7 +1902,7 @@ type ShortenData struct {
 func (x *ShortenData) Reset() {
 	*x = ShortenData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_shortener_proto_msgTypes[11]
+		mi := &file_proto_shortener_proto_msgTypes[30]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -613,7 +1915,7 @@ func (x *ShortenData) String() string {
 func (*ShortenData) ProtoMessage() {}
 
 func (x *ShortenData) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[11]
+	mi := &file_proto_shortener_proto_msgTypes[30]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -626,7 +1928,7 @@ func (x *ShortenData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenData.ProtoReflect.Descriptor instead.
 func (*ShortenData) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{11}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *ShortenData) GetShortUrl() string {
@@ -655,7 +1957,7 @@ type GetUserURLsResponse struct {
 func (x *GetUserURLsResponse) Reset() {
 	*x = GetUserURLsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_shortener_proto_msgTypes[12]
+		mi := &file_proto_shortener_proto_msgTypes[31]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -668,7 +1970,7 @@ func (x *GetUserURLsResponse) String() string {
 func (*GetUserURLsResponse) ProtoMessage() {}
 
 func (x *GetUserURLsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[12]
+	mi := &file_proto_shortener_proto_msgTypes[31]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -681,14 +1983,126 @@ func (x *GetUserURLsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetUserURLsResponse.ProtoReflect.Descriptor instead.
 func (*GetUserURLsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{12}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *GetUserURLsResponse) GetRecords() []*ShortenData {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+// ListUserURLsRequest represents a request from client.
+type ListUserURLsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"` // Cursor of the last received record to resume after, empty to start from the beginning.
+}
+
+func (x *ListUserURLsRequest) Reset() {
+	*x = ListUserURLsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserURLsRequest) ProtoMessage() {}
+
+func (x *ListUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserURLsRequest.ProtoReflect.Descriptor instead.
+func (*ListUserURLsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ListUserURLsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListUserURLsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+// ListUserURLsResponse represents a single record streamed by server.
+type ListUserURLsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Record *ShortenData `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	Cursor string       `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"` // Opaque position of the record.
+}
+
+func (x *ListUserURLsResponse) Reset() {
+	*x = ListUserURLsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_shortener_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListUserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserURLsResponse) ProtoMessage() {}
+
+func (x *ListUserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserURLsResponse.ProtoReflect.Descriptor instead.
+func (*ListUserURLsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListUserURLsResponse) GetRecord() *ShortenData {
+	if x != nil {
+		return x.Record
+	}
+	return nil
 }
 
-func (x *GetUserURLsResponse) GetRecords() []*ShortenData {
+func (x *ListUserURLsResponse) GetCursor() string {
 	if x != nil {
-		return x.Records
+		return x.Cursor
 	}
-	return nil
+	return ""
 }
 
 // DeleteUserURLsBatchRequest represents a request from client.
@@ -704,7 +2118,7 @@ type DeleteUserURLsBatchRequest struct {
 func (x *DeleteUserURLsBatchRequest) Reset() {
 	*x = DeleteUserURLsBatchRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_shortener_proto_msgTypes[13]
+		mi := &file_proto_shortener_proto_msgTypes[34]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -717,7 +2131,7 @@ func (x *DeleteUserURLsBatchRequest) String() string {
 func (*DeleteUserURLsBatchRequest) ProtoMessage() {}
 
 func (x *DeleteUserURLsBatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[13]
+	mi := &file_proto_shortener_proto_msgTypes[34]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -730,7 +2144,7 @@ func (x *DeleteUserURLsBatchRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteUserURLsBatchRequest.ProtoReflect.Descriptor instead.
 func (*DeleteUserURLsBatchRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{13}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *DeleteUserURLsBatchRequest) GetUserId() string {
@@ -757,7 +2171,7 @@ type DeleteUserURLsBatchResponse struct {
 func (x *DeleteUserURLsBatchResponse) Reset() {
 	*x = DeleteUserURLsBatchResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_shortener_proto_msgTypes[14]
+		mi := &file_proto_shortener_proto_msgTypes[35]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -770,7 +2184,7 @@ func (x *DeleteUserURLsBatchResponse) String() string {
 func (*DeleteUserURLsBatchResponse) ProtoMessage() {}
 
 func (x *DeleteUserURLsBatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[14]
+	mi := &file_proto_shortener_proto_msgTypes[35]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -783,7 +2197,7 @@ func (x *DeleteUserURLsBatchResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteUserURLsBatchResponse.ProtoReflect.Descriptor instead.
 func (*DeleteUserURLsBatchResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{14}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{35}
 }
 
 var File_proto_shortener_proto protoreflect.FileDescriptor
@@ -791,26 +2205,166 @@ var File_proto_shortener_proto protoreflect.FileDescriptor
 var file_proto_shortener_proto_rawDesc = []byte{
 	0x0a, 0x15, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65,
 	0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e,
-	0x65, 0x72, 0x22, 0x15, 0x0a, 0x13, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61,
-	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x40, 0x0a, 0x14, 0x53, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
-	0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x22, 0x42, 0x0a, 0x15, 0x43,
+	0x65, 0x72, 0x22, 0x81, 0x01, 0x0a, 0x12, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x4c, 0x69, 0x6e,
+	0x6b, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x6c, 0x75,
+	0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x6c, 0x75, 0x67, 0x12, 0x10, 0x0a,
+	0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12,
+	0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69,
+	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06,
+	0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x22, 0x9f, 0x01, 0x0a, 0x09, 0x41, 0x64, 0x6d, 0x69, 0x6e,
+	0x4c, 0x69, 0x6e, 0x6b, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72,
+	0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72,
+	0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72,
+	0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61,
+	0x6c, 0x55, 0x72, 0x6c, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1a, 0x0a,
+	0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x41, 0x0a, 0x13, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x2a, 0x0a, 0x05, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e,
+	0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x05, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x22, 0x46, 0x0a, 0x16, 0x53,
+	0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x44, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62,
+	0x6c, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62,
+	0x6c, 0x65, 0x64, 0x22, 0x19, 0x0a, 0x17, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x44, 0x69,
+	0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x56,
+	0x0a, 0x14, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x20, 0x0a, 0x0c, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x75,
+	0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x72,
+	0x6f, 0x6d, 0x55, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x0a, 0x74, 0x6f, 0x5f, 0x75,
+	0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x6f,
+	0x55, 0x73, 0x65, 0x72, 0x49, 0x64, 0x22, 0x39, 0x0a, 0x15, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66,
+	0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x20, 0x0a, 0x0b, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x72, 0x65,
+	0x64, 0x22, 0x2b, 0x0a, 0x10, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x22, 0x2d,
+	0x0a, 0x11, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0xce, 0x01,
+	0x0a, 0x10, 0x47, 0x65, 0x74, 0x51, 0x52, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x75, 0x72, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x1b, 0x0a, 0x06, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x06, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e,
+	0x88, 0x01, 0x01, 0x12, 0x1e, 0x0a, 0x0a, 0x66, 0x6f, 0x72, 0x65, 0x67, 0x72, 0x6f, 0x75, 0x6e,
+	0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x6f, 0x72, 0x65, 0x67, 0x72, 0x6f,
+	0x75, 0x6e, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x67, 0x72, 0x6f, 0x75, 0x6e,
+	0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x62, 0x61, 0x63, 0x6b, 0x67, 0x72, 0x6f,
+	0x75, 0x6e, 0x64, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x22, 0x4a,
+	0x0a, 0x11, 0x47, 0x65, 0x74, 0x51, 0x52, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65,
+	0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x43, 0x0a, 0x07, 0x56, 0x61,
+	0x72, 0x69, 0x61, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22,
+	0x54, 0x0a, 0x0c, 0x56, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12,
+	0x2c, 0x0a, 0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x12, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x56, 0x61, 0x72,
+	0x69, 0x61, 0x6e, 0x74, 0x52, 0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x6c, 0x69, 0x63, 0x6b, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x63,
+	0x6c, 0x69, 0x63, 0x6b, 0x73, 0x22, 0x40, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07,
+	0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75,
+	0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x63, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x4c, 0x69,
+	0x6e, 0x6b, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x63, 0x6c, 0x69, 0x63, 0x6b, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x63, 0x6c, 0x69, 0x63, 0x6b, 0x73, 0x12, 0x33, 0x0a, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x6e, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x73, 0x68, 0x6f, 0x72,
+	0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x56, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x52, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x73, 0x22, 0x3c, 0x0a, 0x0c,
+	0x52, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x6f, 0x0a, 0x13, 0x53, 0x65,
+	0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72,
+	0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x2d, 0x0a, 0x05,
+	0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x73, 0x68,
+	0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x52, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74,
+	0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x22, 0x16, 0x0a, 0x14, 0x53,
+	0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x15, 0x0a, 0x13, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74,
+	0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x40, 0x0a, 0x14, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x22, 0xac, 0x03, 0x0a,
+	0x15, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12,
+	0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72,
+	0x6c, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x72, 0x65, 0x64, 0x69, 0x72, 0x65,
+	0x63, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f,
+	0x6d, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x71, 0x75, 0x65, 0x72,
+	0x79, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x3b, 0x0a, 0x03, 0x75, 0x74, 0x6d, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x29, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x43,
 	0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a,
-	0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22,
-	0x30, 0x0a, 0x16, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52,
-	0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73,
-	0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c,
-	0x74, 0x22, 0x6a, 0x0a, 0x1e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
-	0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x44,
-	0x61, 0x74, 0x61, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f,
-	0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69,
-	0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
-	0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x7a, 0x0a,
+	0x75, 0x65, 0x73, 0x74, 0x2e, 0x55, 0x74, 0x6d, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x03, 0x75,
+	0x74, 0x6d, 0x12, 0x2d, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x17, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x52, 0x65,
+	0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65,
+	0x73, 0x12, 0x2e, 0x0a, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x73, 0x18, 0x07, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e,
+	0x56, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x52, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x69, 0x63, 0x6b, 0x79, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x06, 0x73, 0x74, 0x69, 0x63, 0x6b, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x69, 0x74,
+	0x6c, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x12,
+	0x22, 0x0a, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x74, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x74, 0x69, 0x74,
+	0x69, 0x61, 0x6c, 0x1a, 0x36, 0x0a, 0x08, 0x55, 0x74, 0x6d, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x30, 0x0a, 0x16, 0x43,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0xdd, 0x03,
+	0x0a, 0x1e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f,
+	0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61,
+	0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c,
+	0x55, 0x72, 0x6c, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x72,
+	0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65,
+	0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0c, 0x72, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12,
+	0x1d, 0x0a, 0x0a, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x71, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x44,
+	0x0a, 0x03, 0x75, 0x74, 0x6d, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x32, 0x2e, 0x73, 0x68,
+	0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x2e, 0x55, 0x74, 0x6d, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x03, 0x75, 0x74, 0x6d, 0x12, 0x2d, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e,
+	0x52, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75,
+	0x6c, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x73, 0x18,
+	0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65,
+	0x72, 0x2e, 0x56, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x52, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x6e, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x69, 0x63, 0x6b, 0x79, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x06, 0x73, 0x74, 0x69, 0x63, 0x6b, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x74,
+	0x69, 0x74, 0x6c, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c,
+	0x65, 0x12, 0x22, 0x0a, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x74, 0x69, 0x74, 0x69, 0x61,
+	0x6c, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x74,
+	0x69, 0x74, 0x69, 0x61, 0x6c, 0x1a, 0x36, 0x0a, 0x08, 0x55, 0x74, 0x6d, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x7a, 0x0a,
 	0x1a, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72,
 	0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x43, 0x0a, 0x07, 0x72,
 	0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x73,
@@ -831,70 +2385,151 @@ var file_proto_shortener_proto_rawDesc = []byte{
 	0x32, 0x2a, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74,
 	0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c,
 	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x44, 0x61, 0x74, 0x61, 0x52, 0x07, 0x72, 0x65,
-	0x63, 0x6f, 0x72, 0x64, 0x73, 0x22, 0x42, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x4f, 0x72, 0x69, 0x67,
-	0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17,
+	0x63, 0x6f, 0x72, 0x64, 0x73, 0x22, 0x9b, 0x01, 0x0a, 0x1c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x1b, 0x0a,
+	0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x14, 0x0a,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x22, 0x91, 0x01, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x4f, 0x72, 0x69, 0x67, 0x69,
+	0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a,
+	0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x65, 0x72,
+	0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x12, 0x1d,
+	0x0a, 0x0a, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x75, 0x73, 0x65, 0x72, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x12, 0x18, 0x0a,
+	0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x22, 0xb6, 0x01, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x4f,
+	0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75,
+	0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e,
+	0x61, 0x6c, 0x55, 0x72, 0x6c, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63,
+	0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x72, 0x65,
+	0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x61, 0x72,
+	0x69, 0x61, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x69, 0x63, 0x6b, 0x79, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x73, 0x74, 0x69, 0x63, 0x6b, 0x79, 0x12, 0x22, 0x0a, 0x0c,
+	0x69, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x74, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x74, 0x69, 0x74, 0x69, 0x61, 0x6c,
+	0x22, 0x2d, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x22,
+	0x4d, 0x0a, 0x0b, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1b,
+	0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x6f,
+	0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x22, 0x47,
+	0x0a, 0x13, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x30, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e,
+	0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x44, 0x61, 0x74, 0x61, 0x52, 0x07,
+	0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x22, 0x46, 0x0a, 0x13, 0x4c, 0x69, 0x73, 0x74, 0x55,
+	0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17,
 	0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x3b, 0x0a, 0x16, 0x47, 0x65, 0x74,
-	0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f,
-	0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69,
-	0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x22, 0x2d, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65,
-	0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07,
-	0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75,
-	0x73, 0x65, 0x72, 0x49, 0x64, 0x22, 0x4d, 0x0a, 0x0b, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e,
-	0x44, 0x61, 0x74, 0x61, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72,
-	0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72,
-	0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72,
-	0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61,
-	0x6c, 0x55, 0x72, 0x6c, 0x22, 0x47, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55,
-	0x52, 0x4c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x30, 0x0a, 0x07, 0x72,
-	0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x73,
-	0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e,
-	0x44, 0x61, 0x74, 0x61, 0x52, 0x07, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x22, 0x49, 0x0a,
-	0x1a, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42,
-	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x75,
-	0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73,
-	0x65, 0x72, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x03,
-	0x28, 0x09, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x22, 0x1d, 0x0a, 0x1b, 0x44, 0x65, 0x6c, 0x65,
-	0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xa0, 0x04, 0x0a, 0x09, 0x53, 0x68, 0x6f, 0x72,
-	0x74, 0x65, 0x6e, 0x65, 0x72, 0x12, 0x55, 0x0a, 0x0e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53,
-	0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x12, 0x20, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65,
-	0x6e, 0x65, 0x72, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55,
-	0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x73, 0x68, 0x6f, 0x72,
+	0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f,
+	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22,
+	0x5e, 0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2e, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65,
+	0x6e, 0x65, 0x72, 0x2e, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x44, 0x61, 0x74, 0x61, 0x52,
+	0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f,
+	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22,
+	0x49, 0x0a, 0x1a, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c,
+	0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a,
+	0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x22, 0x1d, 0x0a, 0x1b, 0x44, 0x65,
+	0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xce, 0x07, 0x0a, 0x09, 0x53, 0x68,
+	0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x12, 0x55, 0x0a, 0x0e, 0x43, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x12, 0x20, 0x2e, 0x73, 0x68, 0x6f, 0x72,
 	0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72,
-	0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x55, 0x0a, 0x0e,
-	0x47, 0x65, 0x74, 0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x12, 0x20,
-	0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x4f, 0x72,
-	0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x21, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74,
-	0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52,
-	0x4c, 0x73, 0x12, 0x1d, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47,
-	0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65,
-	0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x64, 0x0a, 0x13, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
-	0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x12, 0x25, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74,
-	0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
-	0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x26, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74, 0x63,
-	0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x64, 0x0a, 0x13, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x25,
-	0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65,
-	0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73,
-	0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a,
-	0x08, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72,
-	0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61,
-	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x73, 0x68, 0x6f, 0x72,
-	0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61,
-	0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x19, 0x5a, 0x17, 0x69, 0x6e,
-	0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x68, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x72, 0x73, 0x2f,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x73, 0x68,
+	0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68,
+	0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x55,
+	0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c,
+	0x12, 0x20, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74,
+	0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x21, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47,
+	0x65, 0x74, 0x4f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72,
+	0x55, 0x52, 0x4c, 0x73, 0x12, 0x1d, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72,
+	0x2e, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e,
+	0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x64, 0x0a, 0x13, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x12, 0x25, 0x2e, 0x73, 0x68, 0x6f,
+	0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x26, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52,
+	0x4c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x6f, 0x0a, 0x15, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52,
+	0x4c, 0x73, 0x12, 0x29, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55,
+	0x52, 0x4c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x1a, 0x27, 0x2e,
+	0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x52, 0x4c, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x51, 0x0a, 0x0c, 0x4c, 0x69,
+	0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x12, 0x1e, 0x2e, 0x73, 0x68, 0x6f,
+	0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55,
+	0x52, 0x4c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x73, 0x68, 0x6f,
+	0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55,
+	0x52, 0x4c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x64, 0x0a,
+	0x13, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x12, 0x25, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72,
+	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x73, 0x68,
+	0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73,
+	0x65, 0x72, 0x55, 0x52, 0x4c, 0x73, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12,
+	0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1f, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x4f, 0x0a, 0x0c, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x75, 0x6c, 0x65, 0x73,
+	0x12, 0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x74,
+	0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1f, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x74,
+	0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x4f, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x12, 0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65,
+	0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1f, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65,
+	0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x46, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x51, 0x52, 0x43, 0x6f, 0x64, 0x65, 0x12,
+	0x1b, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x51,
+	0x52, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x73,
+	0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x51, 0x52, 0x43, 0x6f,
+	0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xcb, 0x02, 0x0a, 0x05, 0x41,
+	0x64, 0x6d, 0x69, 0x6e, 0x12, 0x4c, 0x0a, 0x0b, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x4c, 0x69,
+	0x6e, 0x6b, 0x73, 0x12, 0x1d, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e,
+	0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53,
+	0x65, 0x61, 0x72, 0x63, 0x68, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x58, 0x0a, 0x0f, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x44, 0x69, 0x73,
+	0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x21, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65,
+	0x72, 0x2e, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x44, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65,
+	0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74,
+	0x65, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x44, 0x69, 0x73, 0x61,
+	0x62, 0x6c, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x52, 0x0a, 0x0d,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x12, 0x1f, 0x2e,
+	0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66,
+	0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20,
+	0x2e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x4c, 0x69, 0x6e, 0x6b, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x46, 0x0a, 0x09, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72, 0x12, 0x1b, 0x2e,
+	0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55,
+	0x73, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x73, 0x68, 0x6f,
+	0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2e, 0x50, 0x75, 0x72, 0x67, 0x65, 0x55, 0x73, 0x65, 0x72,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x19, 0x5a, 0x17, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x68, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x72, 0x73, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -909,45 +2544,97 @@ func file_proto_shortener_proto_rawDescGZIP() []byte {
 	return file_proto_shortener_proto_rawDescData
 }
 
-var file_proto_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_proto_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 38)
 var file_proto_shortener_proto_goTypes = []interface{}{
-	(*ServiceStatsRequest)(nil),             // 0: shortener.ServiceStatsRequest
-	(*ServiceStatsResponse)(nil),            // 1: shortener.ServiceStatsResponse
-	(*CreateShortURLRequest)(nil),           // 2: shortener.CreateShortURLRequest
-	(*CreateShortURLResponse)(nil),          // 3: shortener.CreateShortURLResponse
-	(*BatchCreateShortURLRequestData)(nil),  // 4: shortener.BatchCreateShortURLRequestData
-	(*BatchCreateShortURLRequest)(nil),      // 5: shortener.BatchCreateShortURLRequest
-	(*BatchCreateShortURLResponseData)(nil), // 6: shortener.BatchCreateShortURLResponseData
-	(*BatchCreateShortURLResponse)(nil),     // 7: shortener.BatchCreateShortURLResponse
-	(*GetOriginalURLRequest)(nil),           // 8: shortener.GetOriginalURLRequest
-	(*GetOriginalURLResponse)(nil),          // 9: shortener.GetOriginalURLResponse
-	(*GetUserURLsRequest)(nil),              // 10: shortener.GetUserURLsRequest
-	(*ShortenData)(nil),                     // 11: shortener.ShortenData
-	(*GetUserURLsResponse)(nil),             // 12: shortener.GetUserURLsResponse
-	(*DeleteUserURLsBatchRequest)(nil),      // 13: shortener.DeleteUserURLsBatchRequest
-	(*DeleteUserURLsBatchResponse)(nil),     // 14: shortener.DeleteUserURLsBatchResponse
+	(*SearchLinksRequest)(nil),              // 0: shortener.SearchLinksRequest
+	(*AdminLink)(nil),                       // 1: shortener.AdminLink
+	(*SearchLinksResponse)(nil),             // 2: shortener.SearchLinksResponse
+	(*SetLinkDisabledRequest)(nil),          // 3: shortener.SetLinkDisabledRequest
+	(*SetLinkDisabledResponse)(nil),         // 4: shortener.SetLinkDisabledResponse
+	(*TransferLinksRequest)(nil),            // 5: shortener.TransferLinksRequest
+	(*TransferLinksResponse)(nil),           // 6: shortener.TransferLinksResponse
+	(*PurgeUserRequest)(nil),                // 7: shortener.PurgeUserRequest
+	(*PurgeUserResponse)(nil),               // 8: shortener.PurgeUserResponse
+	(*GetQRCodeRequest)(nil),                // 9: shortener.GetQRCodeRequest
+	(*GetQRCodeResponse)(nil),               // 10: shortener.GetQRCodeResponse
+	(*Variant)(nil),                         // 11: shortener.Variant
+	(*VariantStats)(nil),                    // 12: shortener.VariantStats
+	(*GetLinkStatsRequest)(nil),             // 13: shortener.GetLinkStatsRequest
+	(*GetLinkStatsResponse)(nil),            // 14: shortener.GetLinkStatsResponse
+	(*RedirectRule)(nil),                    // 15: shortener.RedirectRule
+	(*SetLinkRulesRequest)(nil),             // 16: shortener.SetLinkRulesRequest
+	(*SetLinkRulesResponse)(nil),            // 17: shortener.SetLinkRulesResponse
+	(*ServiceStatsRequest)(nil),             // 18: shortener.ServiceStatsRequest
+	(*ServiceStatsResponse)(nil),            // 19: shortener.ServiceStatsResponse
+	(*CreateShortURLRequest)(nil),           // 20: shortener.CreateShortURLRequest
+	(*CreateShortURLResponse)(nil),          // 21: shortener.CreateShortURLResponse
+	(*BatchCreateShortURLRequestData)(nil),  // 22: shortener.BatchCreateShortURLRequestData
+	(*BatchCreateShortURLRequest)(nil),      // 23: shortener.BatchCreateShortURLRequest
+	(*BatchCreateShortURLResponseData)(nil), // 24: shortener.BatchCreateShortURLResponseData
+	(*BatchCreateShortURLResponse)(nil),     // 25: shortener.BatchCreateShortURLResponse
+	(*StreamCreateShortURLResponse)(nil),    // 26: shortener.StreamCreateShortURLResponse
+	(*GetOriginalURLRequest)(nil),           // 27: shortener.GetOriginalURLRequest
+	(*GetOriginalURLResponse)(nil),          // 28: shortener.GetOriginalURLResponse
+	(*GetUserURLsRequest)(nil),              // 29: shortener.GetUserURLsRequest
+	(*ShortenData)(nil),                     // 30: shortener.ShortenData
+	(*GetUserURLsResponse)(nil),             // 31: shortener.GetUserURLsResponse
+	(*ListUserURLsRequest)(nil),             // 32: shortener.ListUserURLsRequest
+	(*ListUserURLsResponse)(nil),            // 33: shortener.ListUserURLsResponse
+	(*DeleteUserURLsBatchRequest)(nil),      // 34: shortener.DeleteUserURLsBatchRequest
+	(*DeleteUserURLsBatchResponse)(nil),     // 35: shortener.DeleteUserURLsBatchResponse
+	nil,                                     // 36: shortener.CreateShortURLRequest.UtmEntry
+	nil,                                     // 37: shortener.BatchCreateShortURLRequestData.UtmEntry
 }
 var file_proto_shortener_proto_depIdxs = []int32{
-	4,  // 0: shortener.BatchCreateShortURLRequest.records:type_name -> shortener.BatchCreateShortURLRequestData
-	6,  // 1: shortener.BatchCreateShortURLResponse.records:type_name -> shortener.BatchCreateShortURLResponseData
-	11, // 2: shortener.GetUserURLsResponse.records:type_name -> shortener.ShortenData
-	2,  // 3: shortener.Shortener.CreateShortURL:input_type -> shortener.CreateShortURLRequest
-	8,  // 4: shortener.Shortener.GetOriginalURL:input_type -> shortener.GetOriginalURLRequest
-	10, // 5: shortener.Shortener.GetUserURLs:input_type -> shortener.GetUserURLsRequest
-	5,  // 6: shortener.Shortener.BatchCreateShortURL:input_type -> shortener.BatchCreateShortURLRequest
-	13, // 7: shortener.Shortener.DeleteUserURLsBatch:input_type -> shortener.DeleteUserURLsBatchRequest
-	0,  // 8: shortener.Shortener.GetStats:input_type -> shortener.ServiceStatsRequest
-	3,  // 9: shortener.Shortener.CreateShortURL:output_type -> shortener.CreateShortURLResponse
-	9,  // 10: shortener.Shortener.GetOriginalURL:output_type -> shortener.GetOriginalURLResponse
-	12, // 11: shortener.Shortener.GetUserURLs:output_type -> shortener.GetUserURLsResponse
-	7,  // 12: shortener.Shortener.BatchCreateShortURL:output_type -> shortener.BatchCreateShortURLResponse
-	14, // 13: shortener.Shortener.DeleteUserURLsBatch:output_type -> shortener.DeleteUserURLsBatchResponse
-	1,  // 14: shortener.Shortener.GetStats:output_type -> shortener.ServiceStatsResponse
-	9,  // [9:15] is the sub-list for method output_type
-	3,  // [3:9] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	1,  // 0: shortener.SearchLinksResponse.links:type_name -> shortener.AdminLink
+	11, // 1: shortener.VariantStats.variant:type_name -> shortener.Variant
+	12, // 2: shortener.GetLinkStatsResponse.variants:type_name -> shortener.VariantStats
+	15, // 3: shortener.SetLinkRulesRequest.rules:type_name -> shortener.RedirectRule
+	36, // 4: shortener.CreateShortURLRequest.utm:type_name -> shortener.CreateShortURLRequest.UtmEntry
+	15, // 5: shortener.CreateShortURLRequest.rules:type_name -> shortener.RedirectRule
+	11, // 6: shortener.CreateShortURLRequest.variants:type_name -> shortener.Variant
+	37, // 7: shortener.BatchCreateShortURLRequestData.utm:type_name -> shortener.BatchCreateShortURLRequestData.UtmEntry
+	15, // 8: shortener.BatchCreateShortURLRequestData.rules:type_name -> shortener.RedirectRule
+	11, // 9: shortener.BatchCreateShortURLRequestData.variants:type_name -> shortener.Variant
+	22, // 10: shortener.BatchCreateShortURLRequest.records:type_name -> shortener.BatchCreateShortURLRequestData
+	24, // 11: shortener.BatchCreateShortURLResponse.records:type_name -> shortener.BatchCreateShortURLResponseData
+	30, // 12: shortener.GetUserURLsResponse.records:type_name -> shortener.ShortenData
+	30, // 13: shortener.ListUserURLsResponse.record:type_name -> shortener.ShortenData
+	20, // 14: shortener.Shortener.CreateShortURL:input_type -> shortener.CreateShortURLRequest
+	27, // 15: shortener.Shortener.GetOriginalURL:input_type -> shortener.GetOriginalURLRequest
+	29, // 16: shortener.Shortener.GetUserURLs:input_type -> shortener.GetUserURLsRequest
+	23, // 17: shortener.Shortener.BatchCreateShortURL:input_type -> shortener.BatchCreateShortURLRequest
+	22, // 18: shortener.Shortener.StreamCreateShortURLs:input_type -> shortener.BatchCreateShortURLRequestData
+	32, // 19: shortener.Shortener.ListUserURLs:input_type -> shortener.ListUserURLsRequest
+	34, // 20: shortener.Shortener.DeleteUserURLsBatch:input_type -> shortener.DeleteUserURLsBatchRequest
+	18, // 21: shortener.Shortener.GetStats:input_type -> shortener.ServiceStatsRequest
+	16, // 22: shortener.Shortener.SetLinkRules:input_type -> shortener.SetLinkRulesRequest
+	13, // 23: shortener.Shortener.GetLinkStats:input_type -> shortener.GetLinkStatsRequest
+	9,  // 24: shortener.Shortener.GetQRCode:input_type -> shortener.GetQRCodeRequest
+	0,  // 25: shortener.Admin.SearchLinks:input_type -> shortener.SearchLinksRequest
+	3,  // 26: shortener.Admin.SetLinkDisabled:input_type -> shortener.SetLinkDisabledRequest
+	5,  // 27: shortener.Admin.TransferLinks:input_type -> shortener.TransferLinksRequest
+	7,  // 28: shortener.Admin.PurgeUser:input_type -> shortener.PurgeUserRequest
+	21, // 29: shortener.Shortener.CreateShortURL:output_type -> shortener.CreateShortURLResponse
+	28, // 30: shortener.Shortener.GetOriginalURL:output_type -> shortener.GetOriginalURLResponse
+	31, // 31: shortener.Shortener.GetUserURLs:output_type -> shortener.GetUserURLsResponse
+	25, // 32: shortener.Shortener.BatchCreateShortURL:output_type -> shortener.BatchCreateShortURLResponse
+	26, // 33: shortener.Shortener.StreamCreateShortURLs:output_type -> shortener.StreamCreateShortURLResponse
+	33, // 34: shortener.Shortener.ListUserURLs:output_type -> shortener.ListUserURLsResponse
+	35, // 35: shortener.Shortener.DeleteUserURLsBatch:output_type -> shortener.DeleteUserURLsBatchResponse
+	19, // 36: shortener.Shortener.GetStats:output_type -> shortener.ServiceStatsResponse
+	17, // 37: shortener.Shortener.SetLinkRules:output_type -> shortener.SetLinkRulesResponse
+	14, // 38: shortener.Shortener.GetLinkStats:output_type -> shortener.GetLinkStatsResponse
+	10, // 39: shortener.Shortener.GetQRCode:output_type -> shortener.GetQRCodeResponse
+	2,  // 40: shortener.Admin.SearchLinks:output_type -> shortener.SearchLinksResponse
+	4,  // 41: shortener.Admin.SetLinkDisabled:output_type -> shortener.SetLinkDisabledResponse
+	6,  // 42: shortener.Admin.TransferLinks:output_type -> shortener.TransferLinksResponse
+	8,  // 43: shortener.Admin.PurgeUser:output_type -> shortener.PurgeUserResponse
+	29, // [29:44] is the sub-list for method output_type
+	14, // [14:29] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_proto_shortener_proto_init() }
@@ -957,7 +2644,7 @@ func file_proto_shortener_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_proto_shortener_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ServiceStatsRequest); i {
+			switch v := v.(*SearchLinksRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -969,7 +2656,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ServiceStatsResponse); i {
+			switch v := v.(*AdminLink); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -981,7 +2668,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateShortURLRequest); i {
+			switch v := v.(*SearchLinksResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -993,7 +2680,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateShortURLResponse); i {
+			switch v := v.(*SetLinkDisabledRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1005,7 +2692,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BatchCreateShortURLRequestData); i {
+			switch v := v.(*SetLinkDisabledResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1017,7 +2704,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BatchCreateShortURLRequest); i {
+			switch v := v.(*TransferLinksRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1029,7 +2716,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BatchCreateShortURLResponseData); i {
+			switch v := v.(*TransferLinksResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1041,7 +2728,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BatchCreateShortURLResponse); i {
+			switch v := v.(*PurgeUserRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1053,7 +2740,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetOriginalURLRequest); i {
+			switch v := v.(*PurgeUserResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1065,7 +2752,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetOriginalURLResponse); i {
+			switch v := v.(*GetQRCodeRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1077,7 +2764,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetUserURLsRequest); i {
+			switch v := v.(*GetQRCodeResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1089,7 +2776,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ShortenData); i {
+			switch v := v.(*Variant); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1101,7 +2788,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetUserURLsResponse); i {
+			switch v := v.(*VariantStats); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1113,7 +2800,7 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeleteUserURLsBatchRequest); i {
+			switch v := v.(*GetLinkStatsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1125,6 +2812,258 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 		file_proto_shortener_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLinkStatsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RedirectRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetLinkRulesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetLinkRulesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ServiceStatsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ServiceStatsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateShortURLRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateShortURLResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchCreateShortURLRequestData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchCreateShortURLRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchCreateShortURLResponseData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchCreateShortURLResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamCreateShortURLResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetOriginalURLRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[28].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetOriginalURLResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[29].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetUserURLsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[30].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShortenData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[31].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetUserURLsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[32].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListUserURLsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[33].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListUserURLsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[34].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteUserURLsBatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_shortener_proto_msgTypes[35].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*DeleteUserURLsBatchResponse); i {
 			case 0:
 				return &v.state
@@ -1137,15 +3076,16 @@ func file_proto_shortener_proto_init() {
 			}
 		}
 	}
+	file_proto_shortener_proto_msgTypes[9].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_shortener_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   15,
+			NumMessages:   38,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_shortener_proto_goTypes,
 		DependencyIndexes: file_proto_shortener_proto_depIdxs,
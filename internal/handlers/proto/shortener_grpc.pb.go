@@ -19,12 +19,17 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Shortener_CreateShortURL_FullMethodName      = "/shortener.Shortener/CreateShortURL"
-	Shortener_GetOriginalURL_FullMethodName      = "/shortener.Shortener/GetOriginalURL"
-	Shortener_GetUserURLs_FullMethodName         = "/shortener.Shortener/GetUserURLs"
-	Shortener_BatchCreateShortURL_FullMethodName = "/shortener.Shortener/BatchCreateShortURL"
-	Shortener_DeleteUserURLsBatch_FullMethodName = "/shortener.Shortener/DeleteUserURLsBatch"
-	Shortener_GetStats_FullMethodName            = "/shortener.Shortener/GetStats"
+	Shortener_CreateShortURL_FullMethodName        = "/shortener.Shortener/CreateShortURL"
+	Shortener_GetOriginalURL_FullMethodName        = "/shortener.Shortener/GetOriginalURL"
+	Shortener_GetUserURLs_FullMethodName           = "/shortener.Shortener/GetUserURLs"
+	Shortener_BatchCreateShortURL_FullMethodName   = "/shortener.Shortener/BatchCreateShortURL"
+	Shortener_StreamCreateShortURLs_FullMethodName = "/shortener.Shortener/StreamCreateShortURLs"
+	Shortener_ListUserURLs_FullMethodName          = "/shortener.Shortener/ListUserURLs"
+	Shortener_DeleteUserURLsBatch_FullMethodName   = "/shortener.Shortener/DeleteUserURLsBatch"
+	Shortener_GetStats_FullMethodName              = "/shortener.Shortener/GetStats"
+	Shortener_SetLinkRules_FullMethodName          = "/shortener.Shortener/SetLinkRules"
+	Shortener_GetLinkStats_FullMethodName          = "/shortener.Shortener/GetLinkStats"
+	Shortener_GetQRCode_FullMethodName             = "/shortener.Shortener/GetQRCode"
 )
 
 // ShortenerClient is the client API for Shortener service.
@@ -35,8 +40,13 @@ type ShortenerClient interface {
 	GetOriginalURL(ctx context.Context, in *GetOriginalURLRequest, opts ...grpc.CallOption) (*GetOriginalURLResponse, error)
 	GetUserURLs(ctx context.Context, in *GetUserURLsRequest, opts ...grpc.CallOption) (*GetUserURLsResponse, error)
 	BatchCreateShortURL(ctx context.Context, in *BatchCreateShortURLRequest, opts ...grpc.CallOption) (*BatchCreateShortURLResponse, error)
+	StreamCreateShortURLs(ctx context.Context, opts ...grpc.CallOption) (Shortener_StreamCreateShortURLsClient, error)
+	ListUserURLs(ctx context.Context, in *ListUserURLsRequest, opts ...grpc.CallOption) (Shortener_ListUserURLsClient, error)
 	DeleteUserURLsBatch(ctx context.Context, in *DeleteUserURLsBatchRequest, opts ...grpc.CallOption) (*DeleteUserURLsBatchResponse, error)
 	GetStats(ctx context.Context, in *ServiceStatsRequest, opts ...grpc.CallOption) (*ServiceStatsResponse, error)
+	SetLinkRules(ctx context.Context, in *SetLinkRulesRequest, opts ...grpc.CallOption) (*SetLinkRulesResponse, error)
+	GetLinkStats(ctx context.Context, in *GetLinkStatsRequest, opts ...grpc.CallOption) (*GetLinkStatsResponse, error)
+	GetQRCode(ctx context.Context, in *GetQRCodeRequest, opts ...grpc.CallOption) (*GetQRCodeResponse, error)
 }
 
 type shortenerClient struct {
@@ -83,6 +93,69 @@ func (c *shortenerClient) BatchCreateShortURL(ctx context.Context, in *BatchCrea
 	return out, nil
 }
 
+func (c *shortenerClient) StreamCreateShortURLs(ctx context.Context, opts ...grpc.CallOption) (Shortener_StreamCreateShortURLsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[0], Shortener_StreamCreateShortURLs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerStreamCreateShortURLsClient{stream}
+	return x, nil
+}
+
+type Shortener_StreamCreateShortURLsClient interface {
+	Send(*BatchCreateShortURLRequestData) error
+	Recv() (*StreamCreateShortURLResponse, error)
+	grpc.ClientStream
+}
+
+type shortenerStreamCreateShortURLsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerStreamCreateShortURLsClient) Send(m *BatchCreateShortURLRequestData) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shortenerStreamCreateShortURLsClient) Recv() (*StreamCreateShortURLResponse, error) {
+	m := new(StreamCreateShortURLResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortenerClient) ListUserURLs(ctx context.Context, in *ListUserURLsRequest, opts ...grpc.CallOption) (Shortener_ListUserURLsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[1], Shortener_ListUserURLs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerListUserURLsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Shortener_ListUserURLsClient interface {
+	Recv() (*ListUserURLsResponse, error)
+	grpc.ClientStream
+}
+
+type shortenerListUserURLsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerListUserURLsClient) Recv() (*ListUserURLsResponse, error) {
+	m := new(ListUserURLsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *shortenerClient) DeleteUserURLsBatch(ctx context.Context, in *DeleteUserURLsBatchRequest, opts ...grpc.CallOption) (*DeleteUserURLsBatchResponse, error) {
 	out := new(DeleteUserURLsBatchResponse)
 	err := c.cc.Invoke(ctx, Shortener_DeleteUserURLsBatch_FullMethodName, in, out, opts...)
@@ -101,6 +174,33 @@ func (c *shortenerClient) GetStats(ctx context.Context, in *ServiceStatsRequest,
 	return out, nil
 }
 
+func (c *shortenerClient) SetLinkRules(ctx context.Context, in *SetLinkRulesRequest, opts ...grpc.CallOption) (*SetLinkRulesResponse, error) {
+	out := new(SetLinkRulesResponse)
+	err := c.cc.Invoke(ctx, Shortener_SetLinkRules_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetLinkStats(ctx context.Context, in *GetLinkStatsRequest, opts ...grpc.CallOption) (*GetLinkStatsResponse, error) {
+	out := new(GetLinkStatsResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetLinkStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetQRCode(ctx context.Context, in *GetQRCodeRequest, opts ...grpc.CallOption) (*GetQRCodeResponse, error) {
+	out := new(GetQRCodeResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetQRCode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility
@@ -109,8 +209,13 @@ type ShortenerServer interface {
 	GetOriginalURL(context.Context, *GetOriginalURLRequest) (*GetOriginalURLResponse, error)
 	GetUserURLs(context.Context, *GetUserURLsRequest) (*GetUserURLsResponse, error)
 	BatchCreateShortURL(context.Context, *BatchCreateShortURLRequest) (*BatchCreateShortURLResponse, error)
+	StreamCreateShortURLs(Shortener_StreamCreateShortURLsServer) error
+	ListUserURLs(*ListUserURLsRequest, Shortener_ListUserURLsServer) error
 	DeleteUserURLsBatch(context.Context, *DeleteUserURLsBatchRequest) (*DeleteUserURLsBatchResponse, error)
 	GetStats(context.Context, *ServiceStatsRequest) (*ServiceStatsResponse, error)
+	SetLinkRules(context.Context, *SetLinkRulesRequest) (*SetLinkRulesResponse, error)
+	GetLinkStats(context.Context, *GetLinkStatsRequest) (*GetLinkStatsResponse, error)
+	GetQRCode(context.Context, *GetQRCodeRequest) (*GetQRCodeResponse, error)
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -130,12 +235,27 @@ func (UnimplementedShortenerServer) GetUserURLs(context.Context, *GetUserURLsReq
 func (UnimplementedShortenerServer) BatchCreateShortURL(context.Context, *BatchCreateShortURLRequest) (*BatchCreateShortURLResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BatchCreateShortURL not implemented")
 }
+func (UnimplementedShortenerServer) StreamCreateShortURLs(Shortener_StreamCreateShortURLsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCreateShortURLs not implemented")
+}
+func (UnimplementedShortenerServer) ListUserURLs(*ListUserURLsRequest, Shortener_ListUserURLsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListUserURLs not implemented")
+}
 func (UnimplementedShortenerServer) DeleteUserURLsBatch(context.Context, *DeleteUserURLsBatchRequest) (*DeleteUserURLsBatchResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserURLsBatch not implemented")
 }
 func (UnimplementedShortenerServer) GetStats(context.Context, *ServiceStatsRequest) (*ServiceStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
 }
+func (UnimplementedShortenerServer) SetLinkRules(context.Context, *SetLinkRulesRequest) (*SetLinkRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLinkRules not implemented")
+}
+func (UnimplementedShortenerServer) GetLinkStats(context.Context, *GetLinkStatsRequest) (*GetLinkStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkStats not implemented")
+}
+func (UnimplementedShortenerServer) GetQRCode(context.Context, *GetQRCodeRequest) (*GetQRCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQRCode not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 
 // UnsafeShortenerServer may be embedded to opt out of forward compatibility for this service.
@@ -221,6 +341,53 @@ func _Shortener_BatchCreateShortURL_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_StreamCreateShortURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServer).StreamCreateShortURLs(&shortenerStreamCreateShortURLsServer{stream})
+}
+
+type Shortener_StreamCreateShortURLsServer interface {
+	Send(*StreamCreateShortURLResponse) error
+	Recv() (*BatchCreateShortURLRequestData, error)
+	grpc.ServerStream
+}
+
+type shortenerStreamCreateShortURLsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerStreamCreateShortURLsServer) Send(m *StreamCreateShortURLResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shortenerStreamCreateShortURLsServer) Recv() (*BatchCreateShortURLRequestData, error) {
+	m := new(BatchCreateShortURLRequestData)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Shortener_ListUserURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListUserURLsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenerServer).ListUserURLs(m, &shortenerListUserURLsServer{stream})
+}
+
+type Shortener_ListUserURLsServer interface {
+	Send(*ListUserURLsResponse) error
+	grpc.ServerStream
+}
+
+type shortenerListUserURLsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerListUserURLsServer) Send(m *ListUserURLsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Shortener_DeleteUserURLsBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteUserURLsBatchRequest)
 	if err := dec(in); err != nil {
@@ -257,6 +424,60 @@ func _Shortener_GetStats_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_SetLinkRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLinkRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SetLinkRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SetLinkRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SetLinkRules(ctx, req.(*SetLinkRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetLinkStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLinkStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetLinkStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetLinkStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetLinkStats(ctx, req.(*GetLinkStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetQRCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQRCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetQRCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetQRCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetQRCode(ctx, req.(*GetQRCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -288,6 +509,231 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStats",
 			Handler:    _Shortener_GetStats_Handler,
 		},
+		{
+			MethodName: "SetLinkRules",
+			Handler:    _Shortener_SetLinkRules_Handler,
+		},
+		{
+			MethodName: "GetLinkStats",
+			Handler:    _Shortener_GetLinkStats_Handler,
+		},
+		{
+			MethodName: "GetQRCode",
+			Handler:    _Shortener_GetQRCode_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamCreateShortURLs",
+			Handler:       _Shortener_StreamCreateShortURLs_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ListUserURLs",
+			Handler:       _Shortener_ListUserURLs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/shortener.proto",
+}
+
+const (
+	Admin_SearchLinks_FullMethodName     = "/shortener.Admin/SearchLinks"
+	Admin_SetLinkDisabled_FullMethodName = "/shortener.Admin/SetLinkDisabled"
+	Admin_TransferLinks_FullMethodName   = "/shortener.Admin/TransferLinks"
+	Admin_PurgeUser_FullMethodName       = "/shortener.Admin/PurgeUser"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	SearchLinks(ctx context.Context, in *SearchLinksRequest, opts ...grpc.CallOption) (*SearchLinksResponse, error)
+	SetLinkDisabled(ctx context.Context, in *SetLinkDisabledRequest, opts ...grpc.CallOption) (*SetLinkDisabledResponse, error)
+	TransferLinks(ctx context.Context, in *TransferLinksRequest, opts ...grpc.CallOption) (*TransferLinksResponse, error)
+	PurgeUser(ctx context.Context, in *PurgeUserRequest, opts ...grpc.CallOption) (*PurgeUserResponse, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) SearchLinks(ctx context.Context, in *SearchLinksRequest, opts ...grpc.CallOption) (*SearchLinksResponse, error) {
+	out := new(SearchLinksResponse)
+	err := c.cc.Invoke(ctx, Admin_SearchLinks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetLinkDisabled(ctx context.Context, in *SetLinkDisabledRequest, opts ...grpc.CallOption) (*SetLinkDisabledResponse, error) {
+	out := new(SetLinkDisabledResponse)
+	err := c.cc.Invoke(ctx, Admin_SetLinkDisabled_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) TransferLinks(ctx context.Context, in *TransferLinksRequest, opts ...grpc.CallOption) (*TransferLinksResponse, error) {
+	out := new(TransferLinksResponse)
+	err := c.cc.Invoke(ctx, Admin_TransferLinks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PurgeUser(ctx context.Context, in *PurgeUserRequest, opts ...grpc.CallOption) (*PurgeUserResponse, error) {
+	out := new(PurgeUserResponse)
+	err := c.cc.Invoke(ctx, Admin_PurgeUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility
+type AdminServer interface {
+	SearchLinks(context.Context, *SearchLinksRequest) (*SearchLinksResponse, error)
+	SetLinkDisabled(context.Context, *SetLinkDisabledRequest) (*SetLinkDisabledResponse, error)
+	TransferLinks(context.Context, *TransferLinksRequest) (*TransferLinksResponse, error)
+	PurgeUser(context.Context, *PurgeUserRequest) (*PurgeUserResponse, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServer struct {
+}
+
+func (UnimplementedAdminServer) SearchLinks(context.Context, *SearchLinksRequest) (*SearchLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchLinks not implemented")
+}
+func (UnimplementedAdminServer) SetLinkDisabled(context.Context, *SetLinkDisabledRequest) (*SetLinkDisabledResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLinkDisabled not implemented")
+}
+func (UnimplementedAdminServer) TransferLinks(context.Context, *TransferLinksRequest) (*TransferLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferLinks not implemented")
+}
+func (UnimplementedAdminServer) PurgeUser(context.Context, *PurgeUserRequest) (*PurgeUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeUser not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_SearchLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SearchLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SearchLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SearchLinks(ctx, req.(*SearchLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetLinkDisabled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLinkDisabledRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetLinkDisabled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SetLinkDisabled_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetLinkDisabled(ctx, req.(*SetLinkDisabledRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_TransferLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).TransferLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_TransferLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).TransferLinks(ctx, req.(*TransferLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PurgeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PurgeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_PurgeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PurgeUser(ctx, req.(*PurgeUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shortener.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchLinks",
+			Handler:    _Admin_SearchLinks_Handler,
+		},
+		{
+			MethodName: "SetLinkDisabled",
+			Handler:    _Admin_SetLinkDisabled_Handler,
+		},
+		{
+			MethodName: "TransferLinks",
+			Handler:    _Admin_TransferLinks_Handler,
+		},
+		{
+			MethodName: "PurgeUser",
+			Handler:    _Admin_PurgeUser_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shortener.proto",
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/rawen554/shortener/internal/logic"
+	"github.com/rawen554/shortener/internal/middleware/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer создает gRPC-сервер с сервисами Shortener и Admin. Вызовы аутентифицируются
+// ключами keys и ключами API, а методы администрирования и GetStats доступны
+// только из доверенной подсети trustedSubnet.
+func NewServer(
+	trustedSubnet string,
+	keys *auth.Keyring,
+	coreLogic *logic.CoreLogic,
+	logger *zap.SugaredLogger,
+	opts ...grpc.ServerOption,
+) *grpc.Server {
+	protected := []string{pb.Admin_ServiceDesc.ServiceName, pb.Shortener_GetStats_FullMethodName}
+	authenticator := NewAuthenticator(keys, coreLogic, logger.Named("grpc_auth"))
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(
+			NewSubnetInterceptor(trustedSubnet, logger.Named("subnet_interceptor"), protected...),
+			authenticator.Unary(),
+		),
+		grpc.ChainStreamInterceptor(
+			NewSubnetStreamInterceptor(trustedSubnet, logger.Named("subnet_interceptor"), protected...),
+			authenticator.Stream(),
+		),
+	)
+	grpcServer := grpc.NewServer(opts...)
+	reflection.Register(grpcServer)
+
+	pb.RegisterShortenerServer(grpcServer, NewService(logger, coreLogic))
+	pb.RegisterAdminServer(grpcServer, NewAdminService(logger, coreLogic))
+
+	return grpcServer
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+var errNoCACerts = errors.New("no certificates found")
+
+// NewServerCredentials создает TLS-учетные данные gRPC-сервера из сертификата и ключа.
+// Если задан clientCAPath, клиенты обязаны предъявить сертификат, подписанный одним
+// из удостоверяющих центров файла (взаимный TLS).
+func NewServerCredentials(certPath string, keyPath string, clientCAPath string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading tls key pair: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAPath != "" {
+		data, err := os.ReadFile(clientCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("error parsing client CA file %s: %w", clientCAPath, errNoCACerts)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// ClientIdentity возвращает субъект проверенного сертификата клиента, например "CN=ops,O=Shortener".
+// Без взаимного TLS возвращается пустая строка и false.
+func ClientIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	return info.State.VerifiedChains[0][0].Subject.String(), true
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/rawen554/shortener/internal/handlers/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func issueCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, dir string, name string) (certPath string, keyPath string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	certPath = filepath.Join(dir, name+".pem")
+	keyPath = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certPath, keyPath
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestNewServerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	caPath, _ := ca.write(t, dir, "ca")
+	server := issueCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+	certPath, keyPath := server.write(t, dir, "server")
+	client := issueCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "ops", Organization: []string{"Shortener"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name         string
+		clientCA     string
+		clientCerts  []tls.Certificate
+		wantIdentity string
+		wantCalled   bool
+	}{
+		{name: "tls", wantCalled: true},
+		{name: "tls with client certificate", clientCerts: []tls.Certificate{client.tlsCertificate()}, wantCalled: true},
+		{
+			name:         "mutual tls",
+			clientCA:     caPath,
+			clientCerts:  []tls.Certificate{client.tlsCertificate()},
+			wantIdentity: "CN=ops,O=Shortener",
+			wantCalled:   true,
+		},
+		{name: "mutual tls without client certificate", clientCA: caPath},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := NewServerCredentials(certPath, keyPath, tt.clientCA)
+			require.NoError(t, err)
+
+			var (
+				called   bool
+				identity string
+			)
+			capture := func(
+				ctx context.Context,
+				req interface{},
+				info *grpc.UnaryServerInfo,
+				handler grpc.UnaryHandler,
+			) (interface{}, error) {
+				called = true
+				identity, _ = ClientIdentity(ctx)
+				return handler(ctx, req)
+			}
+
+			lis := bufconn.Listen(1024 * 1024)
+			srv := NewServer("", nil, nil, zap.NewNop().Sugar(), grpc.Creds(creds), grpc.ChainUnaryInterceptor(capture))
+			go func() { _ = srv.Serve(lis) }()
+			t.Cleanup(srv.Stop)
+
+			conn, err := grpc.DialContext(context.Background(), "localhost",
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return lis.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+					RootCAs:      roots,
+					Certificates: tt.clientCerts,
+					ServerName:   "localhost",
+					MinVersion:   tls.VersionTLS12,
+				})),
+			)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = conn.Close() })
+
+			// без доверенной подсети GetStats отклоняется, но вызов доходит до перехватчиков
+			_, err = pb.NewShortenerClient(conn).GetStats(context.Background(), &pb.ServiceStatsRequest{})
+			if tt.wantCalled {
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			} else {
+				assert.Equal(t, codes.Unavailable, status.Code(err))
+			}
+			assert.Equal(t, tt.wantCalled, called)
+			assert.Equal(t, tt.wantIdentity, identity)
+		})
+	}
+
+	t.Run("bad client CA", func(t *testing.T) {
+		_, err := NewServerCredentials(certPath, keyPath, keyPath)
+		assert.ErrorIs(t, err, errNoCACerts)
+	})
+}
@@ -0,0 +1,187 @@
+// Модуль собирает состояние компонентов сервиса для проверок живости и готовности.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status состояние компонента или сервиса в целом.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusStarting Status = "starting"
+	StatusDown     Status = "down"
+	// StatusShuttingDown сервис завершает работу и не принимает новые запросы.
+	StatusShuttingDown Status = "shutting_down"
+)
+
+// checkTimeout ограничение на время одной активной проверки.
+const checkTimeout = time.Second * 2
+
+// ComponentStatus состояние одного компонента.
+type ComponentStatus struct {
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report состояние сервиса и его компонентов.
+type Report struct {
+	Components map[string]ComponentStatus `json:"components"`
+	Status     Status                     `json:"status"`
+}
+
+// Ready сообщает, готов ли сервис принимать запросы.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Component компонент, который сам сообщает о своем состоянии, например фоновый обработчик.
+// Новый компонент находится в состоянии StatusStarting.
+type Component struct {
+	mux    *sync.Mutex
+	err    error
+	status Status
+}
+
+// SetReady отмечает компонент работающим.
+func (c *Component) SetReady() {
+	c.set(StatusOK, nil)
+}
+
+// SetDown отмечает компонент остановленным; err — причина остановки, может быть nil.
+func (c *Component) SetDown(err error) {
+	c.set(StatusDown, err)
+}
+
+func (c *Component) set(status Status, err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.status = status
+	c.err = err
+}
+
+func (c *Component) state() ComponentStatus {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	result := ComponentStatus{Status: c.status}
+	if c.err != nil {
+		result.Error = c.err.Error()
+	}
+	return result
+}
+
+type checkResult struct {
+	err  error
+	name string
+}
+
+// Checker реестр компонентов сервиса. Компоненты бывают двух видов: активные проверки,
+// выполняемые при каждом запросе состояния (например, ping хранилища), и компоненты,
+// которые сами сообщают о своем состоянии.
+type Checker struct {
+	mux          *sync.RWMutex
+	checks       map[string]func(ctx context.Context) error
+	components   map[string]*Component
+	shuttingDown atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		mux:        &sync.RWMutex{},
+		checks:     make(map[string]func(ctx context.Context) error),
+		components: make(map[string]*Component),
+	}
+}
+
+// AddCheck регистрирует активную проверку компонента name.
+func (c *Checker) AddCheck(name string, check func(ctx context.Context) error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.checks[name] = check
+}
+
+// Component регистрирует компонент name или возвращает уже зарегистрированный.
+func (c *Checker) Component(name string) *Component {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if component, ok := c.components[name]; ok {
+		return component
+	}
+	component := &Component{mux: &sync.Mutex{}, status: StatusStarting}
+	c.components[name] = component
+	return component
+}
+
+// Shutdown переводит сервис в состояние завершения работы: дальше он отчитывается как неготовый.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check выполняет активные проверки и собирает состояние всех компонентов.
+// Сервис готов, если все компоненты работают и он не завершает работу.
+// Проверки, не завершившиеся за checkTimeout или до отмены ctx, считаются неработающими.
+func (c *Checker) Check(ctx context.Context) Report {
+	return c.report(ctx, true)
+}
+
+// Live собирает состояние компонентов без активных проверок. Подходит для проверки живости:
+// недоступность хранилища не должна приводить к перезапуску сервиса.
+func (c *Checker) Live() Report {
+	return c.report(context.Background(), false)
+}
+
+func (c *Checker) report(ctx context.Context, active bool) Report {
+	c.mux.RLock()
+	checks := make(map[string]func(ctx context.Context) error, len(c.checks))
+	if active {
+		for name, check := range c.checks {
+			checks[name] = check
+		}
+	}
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus, len(c.checks)+len(c.components))}
+	for name, component := range c.components {
+		report.Components[name] = component.state()
+	}
+	c.mux.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	results := make(chan checkResult, len(checks))
+	for name, check := range checks {
+		// до получения результата проверка считается не уложившейся в срок
+		report.Components[name] = ComponentStatus{Status: StatusDown, Error: "timeout"}
+		go func(name string, check func(ctx context.Context) error) {
+			results <- checkResult{err: check(ctx), name: name}
+		}(name, check)
+	}
+collect:
+	for range checks {
+		select {
+		case <-ctx.Done():
+			break collect
+		case result := <-results:
+			state := ComponentStatus{Status: StatusOK}
+			if result.err != nil {
+				state = ComponentStatus{Status: StatusDown, Error: result.err.Error()}
+			}
+			report.Components[result.name] = state
+		}
+	}
+
+	for _, state := range report.Components {
+		if state.Status != StatusOK {
+			report.Status = StatusDown
+			break
+		}
+	}
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report
+}
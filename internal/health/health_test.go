@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	errStore := errors.New("connection refused")
+
+	tests := []struct {
+		prepare func(c *Checker)
+		want    Report
+		name    string
+	}{
+		{
+			name:    "no components",
+			prepare: func(c *Checker) {},
+			want:    Report{Status: StatusOK, Components: map[string]ComponentStatus{}},
+		},
+		{
+			name: "all ok",
+			prepare: func(c *Checker) {
+				c.AddCheck("store", func(ctx context.Context) error { return nil })
+				c.Component("grpc").SetReady()
+			},
+			want: Report{Status: StatusOK, Components: map[string]ComponentStatus{
+				"store": {Status: StatusOK},
+				"grpc":  {Status: StatusOK},
+			}},
+		},
+		{
+			name: "component is starting",
+			prepare: func(c *Checker) {
+				c.Component("grpc")
+			},
+			want: Report{Status: StatusDown, Components: map[string]ComponentStatus{
+				"grpc": {Status: StatusStarting},
+			}},
+		},
+		{
+			name: "failed check",
+			prepare: func(c *Checker) {
+				c.AddCheck("store", func(ctx context.Context) error { return errStore })
+				c.Component("grpc").SetReady()
+			},
+			want: Report{Status: StatusDown, Components: map[string]ComponentStatus{
+				"store": {Status: StatusDown, Error: "connection refused"},
+				"grpc":  {Status: StatusOK},
+			}},
+		},
+		{
+			name: "stopped component",
+			prepare: func(c *Checker) {
+				c.Component("linkcheck").SetReady()
+				c.Component("linkcheck").SetDown(nil)
+			},
+			want: Report{Status: StatusDown, Components: map[string]ComponentStatus{
+				"linkcheck": {Status: StatusDown},
+			}},
+		},
+		{
+			name: "shutting down",
+			prepare: func(c *Checker) {
+				c.Component("grpc").SetReady()
+				c.Shutdown()
+			},
+			want: Report{Status: StatusShuttingDown, Components: map[string]ComponentStatus{
+				"grpc": {Status: StatusOK},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			tt.prepare(c)
+
+			got := c.Check(context.Background())
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.Status == StatusOK, got.Ready())
+		})
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	c := NewChecker()
+	c.AddCheck("store", func(ctx context.Context) error {
+		// проверка не учитывает ctx, как зависший драйвер базы данных
+		<-hang
+		return nil
+	})
+	c.Component("grpc").SetReady()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	got := c.Check(ctx)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, Report{Status: StatusDown, Components: map[string]ComponentStatus{
+		"store": {Status: StatusDown, Error: "timeout"},
+		"grpc":  {Status: StatusOK},
+	}}, got)
+
+	// проверка живости не выполняет активные проверки
+	assert.Equal(t, Report{Status: StatusOK, Components: map[string]ComponentStatus{
+		"grpc": {Status: StatusOK},
+	}}, c.Live())
+}
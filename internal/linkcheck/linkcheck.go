@@ -0,0 +1,264 @@
+// Модуль фоновой проверки доступности адресов назначения коротких ссылок.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/netguard"
+	"go.uber.org/zap"
+)
+
+const (
+	userAgent        = "shortener-linkcheck/1.0"
+	defaultTimeout   = time.Second * 10
+	defaultBatchSize = 500
+	maxBodyRead      = 64 << 10
+)
+
+// Store хранилище ссылок, которые нужно проверять.
+type Store interface {
+	GetLinksToCheck(checkedBefore time.Time, limit int) ([]models.Link, error)
+	SetHealth(id string, health models.LinkHealth) error
+}
+
+// Config параметры проверки.
+type Config struct {
+	// Interval как часто перепроверять одну и ту же ссылку.
+	Interval time.Duration
+	// HostDelay минимальная пауза между запросами к одному хосту.
+	HostDelay time.Duration
+	// Timeout ограничение на время одного запроса.
+	Timeout time.Duration
+	// Workers количество одновременных проверок.
+	Workers int
+	// BatchSize сколько ссылок запрашивается из хранилища за раз.
+	BatchSize int
+	// AllowLoopback разрешает проверять адреса loopback, например в тестах.
+	AllowLoopback bool
+}
+
+type hostState struct {
+	mux  *sync.Mutex
+	last time.Time
+}
+
+// Checker периодически проверяет адреса назначения и сохраняет результат в хранилище.
+// К одному хосту одновременно выполняется не больше одного запроса.
+type Checker struct {
+	store  Store
+	client *http.Client
+	logger *zap.SugaredLogger
+	hosts  map[string]*hostState
+	mux    *sync.Mutex
+	config Config
+}
+
+// NewChecker создает проверку. Если client не задан, используется клиент netguard с таймаутом
+// из конфига: адреса во внутренней сети не проверяются, в том числе после перенаправления.
+func NewChecker(config Config, store Store, logger *zap.SugaredLogger, client *http.Client) *Checker {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if client == nil {
+		client = netguard.NewClient(config.Timeout, config.AllowLoopback, true)
+	}
+
+	return &Checker{
+		store:  store,
+		client: client,
+		logger: logger,
+		hosts:  make(map[string]*hostState),
+		mux:    &sync.Mutex{},
+		config: config,
+	}
+}
+
+// Run проверяет ссылки каждые Interval до отмены контекста.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckOnce(ctx); err != nil {
+			c.logger.Errorf("link check failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce проверяет партиями по BatchSize все ссылки, которые не проверялись дольше Interval,
+// пока хранилище не вернет неполную партию или не будет отменен контекст.
+func (c *Checker) CheckOnce(ctx context.Context) error {
+	// граница фиксируется на весь проход, иначе при малом Interval
+	// только что проверенные ссылки снова попадали бы в выборку
+	checkedBefore := time.Now().Add(-c.config.Interval)
+	for ctx.Err() == nil {
+		links, err := c.store.GetLinksToCheck(checkedBefore, c.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("error getting links to check: %w", err)
+		}
+
+		// если не сохранилось ни одного результата, следующая выборка вернет те же ссылки
+		if saved := c.checkBatch(ctx, links); saved == 0 || len(links) < c.config.BatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// checkBatch проверяет партию ссылок и возвращает количество сохраненных результатов.
+func (c *Checker) checkBatch(ctx context.Context, links []models.Link) int {
+	var saved int64
+	jobs := make(chan models.Link)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				health, err := c.check(ctx, link.OriginalURL)
+				if err != nil {
+					// проверка прервана остановкой сервиса, результат не сохраняется
+					continue
+				}
+				if err := c.store.SetHealth(link.ShortURL, health); err != nil {
+					c.logger.Errorf("error saving health of %s: %v", link.ShortURL, err)
+					continue
+				}
+				atomic.AddInt64(&saved, 1)
+			}
+		}()
+	}
+
+loop:
+	for _, link := range links {
+		select {
+		case <-ctx.Done():
+			break loop
+		case jobs <- link:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return int(saved)
+}
+
+// check проверяет один адрес. Ошибка возвращается только при отмене контекста.
+func (c *Checker) check(ctx context.Context, rawURL string) (models.LinkHealth, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return models.LinkHealth{
+			CheckedAt: time.Now(),
+			Error:     "unsupported url",
+			Broken:    true,
+		}, nil
+	}
+
+	host := c.host(u.Host)
+	host.mux.Lock()
+	defer host.mux.Unlock()
+
+	if wait := time.Until(host.last.Add(c.config.HostDelay)); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return models.LinkHealth{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	defer func() {
+		host.last = time.Now()
+	}()
+
+	start := time.Now()
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	// не все серверы поддерживают HEAD, в этом случае адрес проверяется через GET
+	if err != nil || status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented {
+		status, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	if ctx.Err() != nil {
+		return models.LinkHealth{}, ctx.Err()
+	}
+
+	health := models.LinkHealth{
+		CheckedAt: time.Now(),
+		Status:    status,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Broken:    IsBroken(status, err),
+	}
+	if err != nil {
+		health.Error = err.Error()
+	}
+
+	return health, nil
+}
+
+func (c *Checker) request(ctx context.Context, method string, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, http.NoBody)
+	if err != nil {
+		return 0, fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			c.logger.Errorf("error closing response body: %v", err)
+		}
+	}()
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead)); err != nil &&
+		!errors.Is(err, context.Canceled) {
+		c.logger.Debugf("error reading response body: %v", err)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (c *Checker) host(name string) *hostState {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	h, ok := c.hosts[name]
+	if !ok {
+		h = &hostState{mux: &sync.Mutex{}}
+		c.hosts[name] = h
+	}
+
+	return h
+}
+
+// IsBroken считает адрес недоступным при сетевой ошибке или ответе 4xx/5xx.
+// 429 означает ограничение частоты запросов и не считается поломкой.
+func IsBroken(status int, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return status >= http.StatusBadRequest && status != http.StatusTooManyRequests
+}
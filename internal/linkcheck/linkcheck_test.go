@@ -0,0 +1,171 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/netguard"
+	"github.com/rawen554/shortener/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const userID = "user"
+
+func TestChecker_CheckOnce(t *testing.T) {
+	var inFlight, maxInFlight int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	require.NoError(t, err)
+
+	links := map[string]string{
+		"ok1":     srv.URL + "/ok",
+		"ok2":     srv.URL + "/ok?b",
+		"ok3":     srv.URL + "/ok?c",
+		"missing": srv.URL + "/missing",
+		"nohead":  srv.URL + "/nohead",
+		"limited": srv.URL + "/limited",
+		"ftp":     "ftp://example.com/file",
+	}
+	for id, u := range links {
+		_, err := storage.Put(id, u, userID, models.LinkOptions{})
+		require.NoError(t, err)
+	}
+
+	// партия меньше числа ссылок: за один проход проверяются все партии
+	checker := NewChecker(Config{Workers: 4, BatchSize: 2, AllowLoopback: true}, storage, zap.NewNop().Sugar(), nil)
+	require.NoError(t, checker.CheckOnce(context.Background()))
+
+	records, err := storage.GetAllByUserID(userID)
+	require.NoError(t, err)
+	require.Len(t, records, len(links))
+
+	type result struct {
+		status int
+		broken bool
+	}
+	want := map[string]result{
+		"ok1":     {status: http.StatusOK},
+		"ok2":     {status: http.StatusOK},
+		"ok3":     {status: http.StatusOK},
+		"missing": {status: http.StatusNotFound, broken: true},
+		"nohead":  {status: http.StatusOK},
+		"limited": {status: http.StatusTooManyRequests},
+		"ftp":     {broken: true},
+	}
+	for _, record := range records {
+		require.NotNil(t, record.Health, record.ShortURL)
+		assert.Equal(t, want[record.ShortURL].status, record.Health.Status, record.ShortURL)
+		assert.Equal(t, want[record.ShortURL].broken, record.Health.Broken, record.ShortURL)
+		assert.False(t, record.Health.CheckedAt.IsZero())
+	}
+
+	// все ссылки ведут на один хост, поэтому запросы не должны идти параллельно
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+
+	// свежепроверенные ссылки повторно не проверяются
+	stale, err := storage.GetLinksToCheck(time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+}
+
+func TestChecker_ForbiddenTargets(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		allowLoopback bool
+		wantCalls     int32
+	}{
+		{name: "loopback", url: srv.URL},
+		{name: "private", url: "http://10.0.0.1/"},
+		{name: "metadata", url: "http://169.254.169.254/latest/meta-data/"},
+		{name: "redirect to metadata", url: srv.URL + "/redirect", allowLoopback: true, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+			require.NoError(t, err)
+			_, err = storage.Put("link", tt.url, userID, models.LinkOptions{})
+			require.NoError(t, err)
+
+			checker := NewChecker(Config{AllowLoopback: tt.allowLoopback}, storage, zap.NewNop().Sugar(), nil)
+			require.NoError(t, checker.CheckOnce(context.Background()))
+
+			records, err := storage.GetAllByUserID(userID)
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+			require.NotNil(t, records[0].Health)
+			assert.True(t, records[0].Health.Broken)
+			assert.Contains(t, records[0].Health.Error, netguard.ErrForbiddenAddress.Error())
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestIsBroken(t *testing.T) {
+	tests := []struct {
+		err    error
+		name   string
+		status int
+		want   bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "redirect", status: http.StatusFound},
+		{name: "not found", status: http.StatusNotFound, want: true},
+		{name: "server error", status: http.StatusBadGateway, want: true},
+		{name: "rate limited", status: http.StatusTooManyRequests},
+		{name: "network error", err: errors.New("connection refused"), want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsBroken(tt.status, tt.err))
+		})
+	}
+}
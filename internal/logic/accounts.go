@@ -0,0 +1,148 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rawen554/shortener/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minLoginLength    = 3
+	maxLoginLength    = 64
+	minPasswordLength = 8
+	// maxPasswordLength bcrypt учитывает только первые 72 байта пароля.
+	maxPasswordLength = 72
+)
+
+var (
+	ErrBadCredentials     = errors.New("invalid login or password format")
+	ErrInvalidCredentials = errors.New("wrong login or password")
+)
+
+// Register регистрирует аккаунт. Если текущий пользователь анонимный, аккаунт получает
+// его идентификатор, и все созданные ранее ссылки остаются за ним.
+func (cl *CoreLogic) Register(ctx context.Context, currentUserID string, creds models.Credentials) (*models.Account, error) {
+	if err := validateCredentials(creds); err != nil {
+		return nil, err
+	}
+
+	existing, err := cl.getUserByLogin(creds.Login)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrConflict
+	}
+
+	anonymous, err := cl.isAnonymous(currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	id := currentUserID
+	if !anonymous {
+		id = uuid.New().String()
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
+	if err != nil {
+		err = fmt.Errorf("error hashing password: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	if err := cl.store.CreateUser(models.User{
+		CreatedAt:    time.Now().UTC(),
+		ID:           id,
+		Login:        creds.Login,
+		PasswordHash: string(hash),
+	}); err != nil {
+		if errors.Is(err, models.ErrUserConflict) {
+			return nil, ErrConflict
+		}
+		err = fmt.Errorf("error saving user: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return &models.Account{UserID: id, Login: creds.Login}, nil
+}
+
+// Login проверяет логин и пароль. Ссылки текущего анонимного пользователя
+// переносятся в аккаунт.
+func (cl *CoreLogic) Login(ctx context.Context, currentUserID string, creds models.Credentials) (*models.Account, error) {
+	user, err := cl.getUserByLogin(creds.Login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	account := &models.Account{UserID: user.ID, Login: user.Login}
+	if currentUserID == user.ID {
+		return account, nil
+	}
+
+	// ссылки другого зарегистрированного пользователя не переносятся
+	anonymous, err := cl.isAnonymous(currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if anonymous {
+		account.Merged, err = cl.store.TransferLinks(currentUserID, user.ID)
+		if err != nil {
+			err = fmt.Errorf("error merging anonymous links: %w", err)
+			cl.logger.Error(err)
+			return nil, err
+		}
+	}
+
+	return account, nil
+}
+
+func (cl *CoreLogic) getUserByLogin(login string) (*models.User, error) {
+	user, err := cl.store.GetUserByLogin(login)
+	if err != nil {
+		err = fmt.Errorf("error getting user: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// isAnonymous проверяет, что пользователь не зарегистрирован.
+func (cl *CoreLogic) isAnonymous(userID string) (bool, error) {
+	if userID == "" {
+		return false, nil
+	}
+
+	user, err := cl.store.GetUserByID(userID)
+	if err != nil {
+		err = fmt.Errorf("error getting user: %w", err)
+		cl.logger.Error(err)
+		return false, err
+	}
+
+	return user == nil, nil
+}
+
+func validateCredentials(creds models.Credentials) error {
+	if n := utf8.RuneCountInString(creds.Login); n < minLoginLength || n > maxLoginLength {
+		return fmt.Errorf("%w: login must be %d-%d characters", ErrBadCredentials, minLoginLength, maxLoginLength)
+	}
+	if n := len(creds.Password); n < minPasswordLength || n > maxPasswordLength {
+		return fmt.Errorf("%w: password must be %d-%d bytes", ErrBadCredentials, minPasswordLength, maxPasswordLength)
+	}
+
+	return nil
+}
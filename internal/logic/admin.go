@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+var ErrBadAdminRequest = errors.New("invalid admin request")
+
+// SearchLinks ищет ссылки всех пользователей, начиная с новых.
+func (cl *CoreLogic) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: negative limit or offset", ErrBadAdminRequest)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+
+	links, err := cl.store.SearchLinks(filter)
+	if err != nil {
+		err = fmt.Errorf("error searching links: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	for idx, link := range links {
+		resultURL, err := url.JoinPath(cl.config.RedirectBaseURL, link.ShortURL)
+		if err != nil {
+			err = fmt.Errorf(ErrorJoinURL, err)
+			cl.logger.Error(err)
+			return nil, err
+		}
+		links[idx].ShortURL = resultURL
+	}
+
+	return links, nil
+}
+
+// SetLinkDisabled отключает ссылку или снова включает ее.
+// Отключенная ссылка не перенаправляет, но остается у владельца.
+func (cl *CoreLogic) SetLinkDisabled(ctx context.Context, shortURL string, disabled bool) error {
+	if _, err := cl.getLink(shortURL); err != nil {
+		return err
+	}
+
+	if err := cl.store.SetDisabled(shortURL, disabled); err != nil {
+		err = fmt.Errorf("error updating link: %w", err)
+		cl.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// TransferLinks передает все ссылки пользователя fromUserID пользователю toUserID.
+func (cl *CoreLogic) TransferLinks(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	if fromUserID == "" || toUserID == "" {
+		return 0, fmt.Errorf("%w: empty user id", ErrBadAdminRequest)
+	}
+	if fromUserID == toUserID {
+		return 0, fmt.Errorf("%w: same user", ErrBadAdminRequest)
+	}
+
+	count, err := cl.store.TransferLinks(fromUserID, toUserID)
+	if err != nil {
+		err = fmt.Errorf("error transferring links: %w", err)
+		cl.logger.Error(err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// PurgeUser безвозвратно удаляет ссылки, статистику, подписки, ключи API и аккаунт пользователя.
+func (cl *CoreLogic) PurgeUser(ctx context.Context, userID string) (int, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("%w: empty user id", ErrBadAdminRequest)
+	}
+
+	count, err := cl.store.PurgeUser(userID)
+	if err != nil {
+		err = fmt.Errorf("error purging user: %w", err)
+		cl.logger.Error(err)
+		return 0, err
+	}
+	cl.webhooks.Invalidate(userID)
+
+	return count, nil
+}
@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	// apiKeyPrefix отличает ключи API от других токенов.
+	apiKeyPrefix = "shk_"
+	apiKeyLength = 24
+	// apiKeyVisible сколько первых символов ключа показывается в списке.
+	apiKeyVisible = 12
+	// maxAPIKeys ограничение на количество ключей у одного пользователя.
+	maxAPIKeys = 20
+	maxKeyName = 255
+)
+
+var ErrBadAPIKey = errors.New("invalid api key request")
+
+// CreateAPIKey создает ключ API пользователя. Ключ возвращается только в ответе,
+// в хранилище сохраняется его хеш. По умолчанию ключ дает права на запись.
+func (cl *CoreLogic) CreateAPIKey(ctx context.Context, userID string, req models.APIKeyReq) (*models.APIKeyRes, error) {
+	scope := req.Scope
+	if scope == "" {
+		scope = models.ScopeWrite
+	}
+	if scope != models.ScopeRead && scope != models.ScopeWrite {
+		return nil, fmt.Errorf("%w: unknown scope %q", ErrBadAPIKey, req.Scope)
+	}
+	if len(req.Name) > maxKeyName {
+		return nil, fmt.Errorf("%w: name is too long", ErrBadAPIKey)
+	}
+
+	keys, err := cl.store.GetAPIKeys(userID)
+	if err != nil {
+		err = fmt.Errorf("error getting api keys: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+	if len(keys) >= maxAPIKeys {
+		return nil, fmt.Errorf("%w: more than %d keys", ErrBadAPIKey, maxAPIKeys)
+	}
+
+	b := make([]byte, apiKeyLength)
+	if _, err := rand.Read(b); err != nil {
+		err = fmt.Errorf("random string generator error: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(b)
+	id := hex.EncodeToString(b[:8])
+
+	key := models.APIKey{
+		CreatedAt: time.Now().UTC(),
+		ID:        id,
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    secret[:apiKeyVisible],
+		Hash:      hashAPIKey(secret),
+		Scope:     scope,
+	}
+	if err := cl.store.PutAPIKey(key); err != nil {
+		err = fmt.Errorf("error saving api key: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return &models.APIKeyRes{APIKey: key, Key: secret}, nil
+}
+
+// GetAPIKeys возвращает ключи API пользователя без самих ключей.
+func (cl *CoreLogic) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	keys, err := cl.store.GetAPIKeys(userID)
+	if err != nil {
+		err = fmt.Errorf("error getting api keys: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ API пользователя.
+func (cl *CoreLogic) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	keys, err := cl.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.ID != id {
+			continue
+		}
+		if err := cl.store.DeleteAPIKey(id); err != nil {
+			err = fmt.Errorf("error deleting api key: %w", err)
+			cl.logger.Error(err)
+			return err
+		}
+		return nil
+	}
+
+	return ErrNotFound
+}
+
+// VerifyAPIKey возвращает ключ API по его значению или nil, если ключ неизвестен.
+func (cl *CoreLogic) VerifyAPIKey(ctx context.Context, secret string) (*models.APIKey, error) {
+	key, err := cl.store.GetAPIKeyByHash(hashAPIKey(secret))
+	if err != nil {
+		err = fmt.Errorf("error getting api key: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// hashAPIKey хеширует ключ API. Ключ случайный и длинный, поэтому медленный хеш не нужен.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
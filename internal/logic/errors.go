@@ -0,0 +1,64 @@
+package logic
+
+import (
+	"errors"
+
+	"github.com/rawen554/shortener/internal/qr"
+)
+
+// ErrorCode — вид ошибки логики, по которому транспорты выбирают код ответа.
+type ErrorCode int
+
+const (
+	CodeInternal ErrorCode = iota
+	CodeInvalidArgument
+	CodeNotFound
+	// CodeGone — ссылка удалена владельцем или отключена администратором.
+	CodeGone
+	CodeConflict
+	CodeUnauthenticated
+)
+
+// ErrorDomain — домен причин ошибок в ответах API.
+const ErrorDomain = "shortener"
+
+// ErrorInfo описывает ошибку логики для клиентов API.
+type ErrorInfo struct {
+	// Reason — машиночитаемая причина ошибки, например LINK_DELETED.
+	Reason string
+	Code   ErrorCode
+}
+
+// errorTaxonomy сопоставляет ошибки логики их описаниям. Более частные ошибки
+// идут раньше общих: ErrIsDisabled оборачивает ErrIsDeleted.
+var errorTaxonomy = []struct {
+	err  error
+	info ErrorInfo
+}{
+	{ErrIsDisabled, ErrorInfo{Code: CodeGone, Reason: "LINK_DISABLED"}},
+	{ErrIsDeleted, ErrorInfo{Code: CodeGone, Reason: "LINK_DELETED"}},
+	{ErrNotFound, ErrorInfo{Code: CodeNotFound, Reason: "NOT_FOUND"}},
+	{ErrConflict, ErrorInfo{Code: CodeConflict, Reason: "ALREADY_EXISTS"}},
+	{ErrBadLinkOptions, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_LINK_OPTIONS"}},
+	{qr.ErrBadOptions, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_QR_OPTIONS"}},
+	{ErrBadRedirectQuery, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_QUERY"}},
+	{ErrBadWebhook, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_WEBHOOK"}},
+	{ErrBadAdminRequest, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_ADMIN_REQUEST"}},
+	{ErrBadImportRecord, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_IMPORT_RECORD"}},
+	{ErrBadListQuery, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_LIST_QUERY"}},
+	{ErrBadAPIKey, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_API_KEY_REQUEST"}},
+	{ErrBadCredentials, ErrorInfo{Code: CodeInvalidArgument, Reason: "INVALID_CREDENTIALS_FORMAT"}},
+	{ErrInvalidCredentials, ErrorInfo{Code: CodeUnauthenticated, Reason: "WRONG_CREDENTIALS"}},
+}
+
+// DescribeError возвращает описание ошибки логики.
+// Неизвестные ошибки считаются внутренними с причиной INTERNAL.
+func DescribeError(err error) ErrorInfo {
+	for _, item := range errorTaxonomy {
+		if errors.Is(err, item.err) {
+			return item.info
+		}
+	}
+
+	return ErrorInfo{Code: CodeInternal, Reason: "INTERNAL"}
+}
@@ -0,0 +1,144 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	// exportPageSize сколько ссылок читается из хранилища за один запрос при экспорте.
+	exportPageSize = 500
+	maxSlugLength  = 64
+)
+
+var ErrBadImportRecord = errors.New("invalid import record")
+
+// reservedSlugs первые сегменты путей служебных маршрутов и групп API. Ссылка с таким
+// идентификатором была бы недоступна: маршрут перекрывает перенаправление по /:id.
+var reservedSlugs = map[string]bool{
+	"api":     true,
+	"debug":   true,
+	"healthz": true,
+	"ping":    true,
+	"readyz":  true,
+}
+
+// IsReservedSlug проверяет, что идентификатор совпадает с маршрутом сервиса верхнего уровня.
+func IsReservedSlug(id string) bool {
+	return reservedSlugs[id]
+}
+
+// ExportUserLinks передает fn действующие ссылки пользователя в порядке создания.
+// Ссылки читаются из хранилища страницами, весь список в память не загружается.
+// Ошибка fn прекращает экспорт и возвращается вызывающему.
+func (cl *CoreLogic) ExportUserLinks(ctx context.Context, userID string, fn func(models.LinkExport) error) error {
+	query := models.UserLinksQuery{Sort: models.SortByCreatedAt, Limit: exportPageSize}
+	for {
+		links, err := cl.store.ListByUserID(ctx, userID, query)
+		if err != nil {
+			err = fmt.Errorf("error exporting user urls: %w", err)
+			cl.logger.Error(err)
+			return err
+		}
+
+		for _, link := range links {
+			shortURL, err := url.JoinPath(cl.config.RedirectBaseURL, link.ShortURL)
+			if err != nil {
+				return fmt.Errorf(ErrorJoinURL, err)
+			}
+			if err := fn(models.LinkExport{
+				CreatedAt:   link.CreatedAt,
+				LinkOptions: link.LinkOptions,
+				Slug:        link.ShortURL,
+				ShortURL:    shortURL,
+				OriginalURL: link.OriginalURL,
+				Clicks:      link.Clicks,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if len(links) < exportPageSize {
+			return nil
+		}
+		last := links[len(links)-1]
+		query.After = &models.LinkCursor{CreatedAt: last.CreatedAt, Slug: last.ShortURL}
+	}
+}
+
+// ImportLink создает ссылку пользователя из записи импорта. Идентификатор из записи сохраняется,
+// если он свободен и не совпадает с маршрутом сервиса, иначе ссылка получает новый. Возвращает полную короткую ссылку и признак
+// того, что идентификатор из записи сохранен.
+func (cl *CoreLogic) ImportLink(ctx context.Context, userID string, record models.LinkExport) (string, bool, error) {
+	if err := validateImportRecord(record); err != nil {
+		return "", false, err
+	}
+	if err := ValidateOptions(record.LinkOptions); err != nil {
+		return "", false, err
+	}
+
+	id, preserved := record.Slug, record.Slug != "" && !IsReservedSlug(record.Slug)
+	if preserved {
+		free, err := cl.isSlugFree(id)
+		if err != nil {
+			return "", false, err
+		}
+		preserved = free
+	}
+	if !preserved {
+		var err error
+		if id, err = cl.generateSlug(); err != nil {
+			return "", false, err
+		}
+	}
+
+	shortURL, err := cl.putLink(userID, id, record.OriginalURL, record.LinkOptions)
+	if err != nil {
+		return "", false, err
+	}
+
+	return shortURL, preserved, nil
+}
+
+// isSlugFree проверяет, что идентификатор не занят ни действующей, ни удаленной ссылкой.
+// Проверка и сохранение не атомарны: параллельно созданная ссылка с тем же идентификатором
+// в PostgreSQL приведет к конфликту, а в памяти будет заменена.
+func (cl *CoreLogic) isSlugFree(id string) (bool, error) {
+	link, err := cl.store.Get(id)
+	if errors.Is(err, models.ErrLinkDeleted) {
+		return false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error checking slug: %w", err)
+		cl.logger.Error(err)
+		return false, err
+	}
+
+	return link == nil, nil
+}
+
+func validateImportRecord(record models.LinkExport) error {
+	u, err := url.ParseRequestURI(record.OriginalURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: original_url must be an absolute http or https URL", ErrBadImportRecord)
+	}
+
+	if len(record.Slug) > maxSlugLength {
+		return fmt.Errorf("%w: slug is longer than %d characters", ErrBadImportRecord, maxSlugLength)
+	}
+	for _, r := range record.Slug {
+		if !isSlugRune(r) {
+			return fmt.Errorf("%w: slug may contain only letters, digits, '-' and '_'", ErrBadImportRecord)
+		}
+	}
+
+	return nil
+}
+
+func isSlugRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
@@ -0,0 +1,141 @@
+package logic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+// maxListLimit наибольший размер страницы списка ссылок пользователя.
+const maxListLimit = 1000
+
+var ErrBadListQuery = errors.New("invalid list query")
+
+// listCursor содержимое курсора страницы. Курсор действителен только для того порядка
+// сортировки, в котором он получен.
+type listCursor struct {
+	models.LinkCursor
+	Sort string `json:"o"`
+	Desc bool   `json:"d,omitempty"`
+}
+
+// ListUserLinks возвращает страницу ссылок пользователя и курсор следующей страницы.
+// Пустой курсор в ответе означает, что страница последняя. Нулевой query.Limit
+// возвращает все подходящие ссылки одной страницей.
+func (cl *CoreLogic) ListUserLinks(
+	ctx context.Context,
+	userID string,
+	query models.UserLinksQuery,
+	cursor string,
+) ([]models.UserLink, string, error) {
+	if err := normalizeListQuery(&query); err != nil {
+		return nil, "", err
+	}
+	if cursor != "" {
+		after, err := decodeListCursor(cursor, query)
+		if err != nil {
+			return nil, "", err
+		}
+		query.After = after
+	}
+
+	limit := query.Limit
+	if limit > 0 {
+		// лишняя ссылка показывает, есть ли следующая страница
+		query.Limit++
+	}
+
+	links, err := cl.store.ListByUserID(ctx, userID, query)
+	if err != nil {
+		err = fmt.Errorf("error listing user urls: %w", err)
+		cl.logger.Error(err)
+		return nil, "", err
+	}
+
+	next := ""
+	if limit > 0 && len(links) > limit {
+		links = links[:limit]
+		next, err = encodeListCursor(links[limit-1], query)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	for idx, link := range links {
+		resultURL, err := url.JoinPath(cl.config.RedirectBaseURL, link.ShortURL)
+		if err != nil {
+			err = fmt.Errorf(ErrorJoinURL, err)
+			cl.logger.Error(err)
+			return nil, "", err
+		}
+		links[idx].ShortURL = resultURL
+	}
+
+	return links, next, nil
+}
+
+func normalizeListQuery(query *models.UserLinksQuery) error {
+	if query.Limit < 0 {
+		return fmt.Errorf("%w: negative limit", ErrBadListQuery)
+	}
+	if query.Limit > maxListLimit {
+		query.Limit = maxListLimit
+	}
+
+	switch query.Sort {
+	case "":
+		query.Sort = models.SortByCreatedAt
+	case models.SortByCreatedAt, models.SortByClicks:
+	default:
+		return fmt.Errorf("%w: unsupported sort %q", ErrBadListQuery, query.Sort)
+	}
+
+	switch query.Deleted {
+	case models.DeletedExclude, models.DeletedOnly, models.DeletedInclude:
+	default:
+		return fmt.Errorf("%w: unsupported deleted filter %q", ErrBadListQuery, query.Deleted)
+	}
+
+	if !query.CreatedFrom.IsZero() && !query.CreatedTo.IsZero() && !query.CreatedFrom.Before(query.CreatedTo) {
+		return fmt.Errorf("%w: empty creation date range", ErrBadListQuery)
+	}
+
+	query.Domain = strings.Trim(strings.ToLower(query.Domain), ".")
+
+	return nil
+}
+
+func encodeListCursor(link models.UserLink, query models.UserLinksQuery) (string, error) {
+	data, err := json.Marshal(listCursor{
+		LinkCursor: models.LinkCursor{CreatedAt: link.CreatedAt, Slug: link.ShortURL, Clicks: link.Clicks},
+		Sort:       query.Sort,
+		Desc:       query.Desc,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeListCursor(cursor string, query models.UserLinksQuery) (*models.LinkCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrBadListQuery)
+	}
+
+	var result listCursor
+	if err := json.Unmarshal(data, &result); err != nil || result.Slug == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrBadListQuery)
+	}
+	if result.Sort != query.Sort || result.Desc != query.Desc {
+		return nil, fmt.Errorf("%w: cursor was issued for another sort order", ErrBadListQuery)
+	}
+
+	return &result.LinkCursor, nil
+}
@@ -6,16 +6,21 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/rawen554/shortener/internal/config"
 	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/qr"
 	"github.com/rawen554/shortener/internal/store/postgres"
+	"github.com/rawen554/shortener/internal/webhook"
 	"go.uber.org/zap"
 )
 
 const (
 	slugLength      = 4
+	qrCacheSize     = 1024
 	applicationJSON = "application/json"
 	textPlain       = "text/plain"
 	contentType     = "Content-Type"
@@ -31,40 +36,91 @@ var (
 	ErrNoContent = errors.New("no content")
 	ErrNotFound  = errors.New("not found")
 	ErrIsDeleted = errors.New("deleted")
-	ErrConflict  = errors.New("conflict")
+	// ErrIsDisabled ссылка отключена администратором, для клиентов она выглядит удаленной.
+	ErrIsDisabled = fmt.Errorf("%w: disabled", ErrIsDeleted)
+	ErrConflict   = errors.New("conflict")
+	// ErrBadRedirectQuery строка запроса перехода не разбирается.
+	ErrBadRedirectQuery = errors.New("invalid redirect query")
+
+	ErrBadLinkOptions  = errors.New("invalid link options")
+	ErrBadRedirectType = fmt.Errorf("%w: unsupported redirect type", ErrBadLinkOptions)
+	ErrBadQueryMode    = fmt.Errorf("%w: unsupported query mode", ErrBadLinkOptions)
+	ErrBadUTM          = fmt.Errorf("%w: unsupported utm key", ErrBadLinkOptions)
+	ErrBadRules        = fmt.Errorf("%w: invalid redirect rules", ErrBadLinkOptions)
+	ErrBadVariants     = fmt.Errorf("%w: invalid variants", ErrBadLinkOptions)
 )
 
 type Store interface {
-	Get(id string) (string, error)
+	Get(id string) (*models.Link, error)
 	GetStats() (*models.Stats, error)
 	GetAllByUserID(userID string) ([]models.URLRecord, error)
-	DeleteMany(ids models.DeleteUserURLsReq, userID string) error
-	Put(id string, shortURL string, userID string) (string, error)
+	IterateByUserID(ctx context.Context, userID string, after string, fn func(models.URLRecord) error) error
+	ListByUserID(ctx context.Context, userID string, query models.UserLinksQuery) ([]models.UserLink, error)
+	DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error)
+	Put(id string, shortURL string, userID string, opts models.LinkOptions) (string, error)
 	PutBatch(data []models.URLBatchReq, userID string) ([]models.URLBatchRes, error)
-	Ping() error
+	SetRules(id string, rules []models.RedirectRule) error
+	RecordClick(id string, variant string) error
+	GetClicks(id string) (*models.ClickStats, error)
+	PutWebhook(hook models.Webhook) error
+	GetWebhooks(userID string) ([]models.Webhook, error)
+	UpdateWebhook(hook models.Webhook) error
+	DeleteWebhook(id string) error
+	PutWebhookDelivery(delivery models.WebhookDelivery) error
+	GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error)
+	SearchLinks(filter models.LinkFilter) ([]models.Link, error)
+	SetDisabled(id string, disabled bool) error
+	TransferLinks(fromUserID string, toUserID string) (int, error)
+	PurgeUser(userID string) (int, error)
+	PutAPIKey(key models.APIKey) error
+	GetAPIKeys(userID string) ([]models.APIKey, error)
+	GetAPIKeyByHash(hash string) (*models.APIKey, error)
+	DeleteAPIKey(id string) error
+	CreateUser(user models.User) error
+	GetUserByLogin(login string) (*models.User, error)
+	GetUserByID(id string) (*models.User, error)
+	Ping(ctx context.Context) error
 }
 
 type CoreLogic struct {
-	config *config.ServerConfig
-	store  Store
-	logger *zap.SugaredLogger
+	config   *config.ServerConfig
+	store    Store
+	logger   *zap.SugaredLogger
+	qrCache  *qr.Cache
+	webhooks *webhook.Dispatcher
 }
 
 func NewCoreLogic(config *config.ServerConfig, store Store, logger *zap.SugaredLogger) *CoreLogic {
+	webhookConfig := webhook.DefaultConfig()
+	webhookConfig.AllowLoopback = config.AllowLoopback
+
 	return &CoreLogic{
-		config: config,
-		store:  store,
-		logger: logger,
+		config:   config,
+		store:    store,
+		logger:   logger,
+		qrCache:  qr.NewCache(qrCacheSize),
+		webhooks: webhook.NewDispatcher(webhookConfig, store, logger.Named("webhook"), nil),
 	}
 }
 
+// Close дожидается завершения фоновых доставок событий.
+func (cl *CoreLogic) Close() {
+	cl.webhooks.Close()
+}
+
 func (cl *CoreLogic) DeleteUserRecords(ctx context.Context, userID string, urls models.DeleteUserURLsReq) error {
-	if err := cl.store.DeleteMany(urls, userID); err != nil {
+	deleted, err := cl.store.DeleteMany(urls, userID)
+	if err != nil {
 		err = fmt.Errorf("error deleting: %w", err)
 		cl.logger.Error(err)
 		return err
 	}
 
+	// событие получают только действительно удаленные ссылки владельца
+	for _, id := range deleted {
+		cl.publish(userID, models.EventLinkDeleted, id, models.LinkEvent{})
+	}
+
 	return nil
 }
 
@@ -93,23 +149,274 @@ func (cl *CoreLogic) GetUserRecords(ctx context.Context, userID string) ([]model
 	return records, nil
 }
 
-func (cl *CoreLogic) GetOriginalURL(ctx context.Context, shortURL string) (string, error) {
-	originalURL, err := cl.store.Get(shortURL)
+// IterateUserRecords передает fn ссылки пользователя по одной в порядке возрастания идентификатора,
+// начиная со следующей после cursor. Вместе со ссылкой передается курсор для возобновления обхода.
+func (cl *CoreLogic) IterateUserRecords(
+	ctx context.Context,
+	userID string,
+	cursor string,
+	fn func(record models.URLRecord, cursor string) error,
+) error {
+	err := cl.store.IterateByUserID(ctx, userID, cursor, func(record models.URLRecord) error {
+		id := record.ShortURL
+		resultURL, err := url.JoinPath(cl.config.RedirectBaseURL, id)
+		if err != nil {
+			return fmt.Errorf(ErrorJoinURL, err)
+		}
+		record.ShortURL = resultURL
+
+		return fn(record, id)
+	})
+	if err != nil {
+		return fmt.Errorf("error iterating user urls: %w", err)
+	}
+
+	return nil
+}
+
+// GetOriginalURL разрешает короткую ссылку в адрес перенаправления.
+// Сначала проверяются правила по платформе клиента, затем выбирается вариант
+// A/B-теста, после чего параметры входящего запроса переносятся в адрес
+// согласно настройкам ссылки. Переход учитывается в статистике ссылки.
+func (cl *CoreLogic) GetOriginalURL(ctx context.Context, req *models.RedirectReq) (*models.Redirect, error) {
+	link, err := cl.getActiveLink(req.ShortURL)
 	if err != nil {
-		if errors.Is(err, postgres.ErrURLDeleted) {
-			return "", ErrIsDeleted
+		return nil, err
+	}
+
+	target := *link
+	var variantID string
+	if ruleURL, ok := matchRules(link.Rules, req.UserAgent); ok {
+		target.OriginalURL = ruleURL
+	} else if variant, ok := chooseVariant(link.Variants, req.Variant, randIntn); ok {
+		target.OriginalURL = variant.URL
+		variantID = variant.ID
+	}
+
+	dest, err := buildDestination(&target, req.Query, time.Now())
+	if err != nil {
+		err = fmt.Errorf("error building destination: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	if err := cl.store.RecordClick(link.ShortURL, variantID); err != nil {
+		cl.logger.Errorf("error recording click: %v", err)
+	}
+	cl.publish(link.UserID, models.EventLinkClicked, link.ShortURL, models.LinkEvent{
+		OriginalURL: dest,
+		Variant:     variantID,
+	})
+
+	result := &models.Redirect{
+		URL:          dest,
+		Variant:      variantID,
+		RedirectType: cl.redirectType(link.RedirectType),
+		Sticky:       link.Sticky && variantID != "",
+	}
+
+	if link.Interstitial {
+		result.Preview, err = cl.buildPreview(link)
+		if err != nil {
+			return nil, err
+		}
+		result.Preview.OriginalURL = dest
+	}
+
+	return result, nil
+}
+
+// GetPreview возвращает данные для страницы предпросмотра ссылки.
+// Переход при этом не учитывается в статистике.
+func (cl *CoreLogic) GetPreview(ctx context.Context, shortURL string) (*models.Preview, error) {
+	link, err := cl.getActiveLink(shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return cl.buildPreview(link)
+}
+
+// GetQRCode возвращает QR-код с полной короткой ссылкой.
+func (cl *CoreLogic) GetQRCode(ctx context.Context, shortURL string, opts qr.Options) (*qr.Image, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	link, err := cl.getActiveLink(shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resultURL, err := url.JoinPath(cl.config.RedirectBaseURL, link.ShortURL)
+	if err != nil {
+		err = fmt.Errorf(ErrorJoinURL, err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	img, err := cl.qrCache.Get(resultURL, opts)
+	if err != nil {
+		err = fmt.Errorf("error generating qr code: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return img, nil
+}
+
+func (cl *CoreLogic) buildPreview(link *models.Link) (*models.Preview, error) {
+	shortURL, err := url.JoinPath(cl.config.RedirectBaseURL, link.ShortURL)
+	if err != nil {
+		err = fmt.Errorf(ErrorJoinURL, err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return &models.Preview{
+		CreatedAt:   link.CreatedAt,
+		ShortURL:    shortURL,
+		OriginalURL: link.OriginalURL,
+		Title:       link.Title,
+	}, nil
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке пользователя с разбивкой по вариантам.
+func (cl *CoreLogic) GetLinkStats(ctx context.Context, userID string, shortURL string) (*models.LinkStats, error) {
+	link, err := cl.getUserLink(userID, shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	clicks, err := cl.store.GetClicks(shortURL)
+	if err != nil {
+		err = fmt.Errorf("error getting clicks: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	result := &models.LinkStats{
+		Clicks:   clicks.Clicks,
+		Variants: make([]models.VariantStats, 0, len(link.Variants)),
+	}
+	for _, v := range link.Variants {
+		result.Variants = append(result.Variants, models.VariantStats{Variant: v, Clicks: clicks.Variants[v.ID]})
+	}
+
+	return result, nil
+}
+
+func (cl *CoreLogic) getLink(shortURL string) (*models.Link, error) {
+	link, err := cl.store.Get(shortURL)
+	if err != nil {
+		if errors.Is(err, models.ErrLinkDeleted) {
+			return nil, ErrIsDeleted
 		}
 
 		err = fmt.Errorf("error getting original URL: %w", err)
 		cl.logger.Error(err)
-		return "", err
+		return nil, err
+	}
+
+	if link == nil || link.OriginalURL == "" {
+		return nil, ErrNotFound
+	}
+
+	return link, nil
+}
+
+// getActiveLink возвращает ссылку, если она не отключена администратором.
+func (cl *CoreLogic) getActiveLink(shortURL string) (*models.Link, error) {
+	link, err := cl.getLink(shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.Disabled {
+		return nil, ErrIsDisabled
+	}
+
+	return link, nil
+}
+
+// getUserLink возвращает ссылку, только если она принадлежит пользователю.
+func (cl *CoreLogic) getUserLink(userID string, shortURL string) (*models.Link, error) {
+	link, err := cl.getLink(shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	return link, nil
+}
+
+// GetRules возвращает правила перенаправления ссылки пользователя.
+func (cl *CoreLogic) GetRules(ctx context.Context, userID string, shortURL string) ([]models.RedirectRule, error) {
+	link, err := cl.getUserLink(userID, shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.Rules == nil {
+		return []models.RedirectRule{}, nil
+	}
+
+	return link.Rules, nil
+}
+
+// SetRules заменяет правила перенаправления ссылки пользователя.
+func (cl *CoreLogic) SetRules(
+	ctx context.Context,
+	userID string,
+	shortURL string,
+	rules []models.RedirectRule,
+) error {
+	if err := validateRules(rules); err != nil {
+		return err
+	}
+
+	if _, err := cl.getUserLink(userID, shortURL); err != nil {
+		return err
+	}
+
+	if err := cl.store.SetRules(shortURL, rules); err != nil {
+		err = fmt.Errorf("error saving rules: %w", err)
+		cl.logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// redirectType выбирает код перенаправления: заданный у ссылки или по умолчанию для сервиса.
+func (cl *CoreLogic) redirectType(linkType int) int {
+	if linkType != 0 {
+		return linkType
+	}
+	if cl.config.RedirectType != 0 {
+		return cl.config.RedirectType
+	}
+	return http.StatusTemporaryRedirect
+}
+
+// ValidateOptions проверяет параметры ссылки до ее сохранения.
+func ValidateOptions(opts models.LinkOptions) error {
+	if opts.RedirectType != 0 && !models.IsValidRedirectType(opts.RedirectType) {
+		return fmt.Errorf("%w: %d", ErrBadRedirectType, opts.RedirectType)
+	}
+
+	if err := validateRules(opts.Rules); err != nil {
+		return err
 	}
 
-	if originalURL == "" {
-		return "", ErrNotFound
+	if err := validateVariants(opts.Variants); err != nil {
+		return err
 	}
 
-	return originalURL, nil
+	return validateQueryOptions(opts)
 }
 
 func (cl *CoreLogic) ShortenBatch(
@@ -117,6 +424,12 @@ func (cl *CoreLogic) ShortenBatch(
 	userID string,
 	batchURLsReq []models.URLBatchReq,
 ) ([]models.URLBatchRes, error) {
+	for _, item := range batchURLsReq {
+		if err := ValidateOptions(item.LinkOptions); err != nil {
+			return nil, err
+		}
+	}
+
 	result, err := cl.store.PutBatch(batchURLsReq, userID)
 	if err != nil {
 		err := fmt.Errorf("cant put batch: %w", err)
@@ -124,6 +437,10 @@ func (cl *CoreLogic) ShortenBatch(
 		return nil, err
 	}
 
+	for _, item := range batchURLsReq {
+		cl.publish(userID, models.EventLinkCreated, item.CorrelationID, models.LinkEvent{OriginalURL: item.OriginalURL})
+	}
+
 	for idx, urlObj := range result {
 		resultURL, err := url.JoinPath(cl.config.RedirectBaseURL, urlObj.CorrelationID)
 		if err != nil {
@@ -137,7 +454,26 @@ func (cl *CoreLogic) ShortenBatch(
 	return result, nil
 }
 
-func (cl *CoreLogic) ShortenURL(ctx context.Context, userID string, originalURL string) (string, error) {
+func (cl *CoreLogic) ShortenURL(
+	ctx context.Context,
+	userID string,
+	originalURL string,
+	opts models.LinkOptions,
+) (string, error) {
+	if err := ValidateOptions(opts); err != nil {
+		return "", err
+	}
+
+	id, err := cl.generateSlug()
+	if err != nil {
+		return "", err
+	}
+
+	return cl.putLink(userID, id, originalURL, opts)
+}
+
+// generateSlug возвращает случайный идентификатор новой ссылки.
+func (cl *CoreLogic) generateSlug() (string, error) {
 	b := make([]byte, slugLength)
 	_, err := rand.Read(b)
 	if err != nil {
@@ -145,9 +481,13 @@ func (cl *CoreLogic) ShortenURL(ctx context.Context, userID string, originalURL
 		cl.logger.Error(err)
 		return "", err
 	}
-	id := hex.EncodeToString(b)
+	return hex.EncodeToString(b), nil
+}
 
-	id, err = cl.store.Put(id, originalURL, userID)
+// putLink сохраняет ссылку с идентификатором id, сообщает подписчикам о ее создании
+// и возвращает полную короткую ссылку.
+func (cl *CoreLogic) putLink(userID string, id string, originalURL string, opts models.LinkOptions) (string, error) {
+	id, err := cl.store.Put(id, originalURL, userID, opts)
 	if err != nil {
 		if errors.Is(err, postgres.ErrDBInsertConflict) {
 			return "", ErrConflict
@@ -157,6 +497,7 @@ func (cl *CoreLogic) ShortenURL(ctx context.Context, userID string, originalURL
 			return "", err
 		}
 	}
+	cl.publish(userID, models.EventLinkCreated, id, models.LinkEvent{OriginalURL: originalURL})
 
 	resultURL, err := url.JoinPath(cl.config.RedirectBaseURL, id)
 	if err != nil {
@@ -169,7 +510,7 @@ func (cl *CoreLogic) ShortenURL(ctx context.Context, userID string, originalURL
 }
 
 func (cl *CoreLogic) Ping(ctx context.Context) error {
-	if err := cl.store.Ping(); err != nil {
+	if err := cl.store.Ping(ctx); err != nil {
 		err := fmt.Errorf("error opening connection to DB: %w", err)
 		cl.logger.Error(err)
 		return err
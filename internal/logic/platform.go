@@ -0,0 +1,81 @@
+package logic
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+// maxRules ограничение на количество правил перенаправления у одной ссылки.
+const maxRules = 32
+
+// DetectPlatform определяет платформу клиента по заголовку User-Agent.
+func DetectPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return models.PlatformIOS
+	case strings.Contains(ua, "android"):
+		return models.PlatformAndroid
+	case strings.Contains(ua, "windows"):
+		return models.PlatformWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return models.PlatformMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return models.PlatformLinux
+	default:
+		return models.PlatformOther
+	}
+}
+
+func matchPlatform(rule string, platform string) bool {
+	switch rule {
+	case models.PlatformMobile:
+		return platform == models.PlatformIOS || platform == models.PlatformAndroid
+	case models.PlatformDesktop:
+		return platform == models.PlatformWindows || platform == models.PlatformMacOS || platform == models.PlatformLinux
+	default:
+		return rule == platform
+	}
+}
+
+// matchRules возвращает адрес первого правила, подходящего под User-Agent клиента.
+func matchRules(rules []models.RedirectRule, userAgent string) (string, bool) {
+	if len(rules) == 0 {
+		return "", false
+	}
+
+	platform := DetectPlatform(userAgent)
+	for _, rule := range rules {
+		if matchPlatform(rule.Platform, platform) {
+			return rule.URL, true
+		}
+	}
+
+	return "", false
+}
+
+func validateRules(rules []models.RedirectRule) error {
+	if len(rules) > maxRules {
+		return fmt.Errorf("%w: more than %d rules", ErrBadRules, maxRules)
+	}
+
+	for _, rule := range rules {
+		switch rule.Platform {
+		case models.PlatformIOS, models.PlatformAndroid, models.PlatformWindows, models.PlatformMacOS,
+			models.PlatformLinux, models.PlatformOther, models.PlatformMobile, models.PlatformDesktop:
+		default:
+			return fmt.Errorf("%w: unknown platform %q", ErrBadRules, rule.Platform)
+		}
+
+		u, err := url.Parse(rule.URL)
+		if err != nil || u.Scheme == "" {
+			return fmt.Errorf("%w: bad url %q", ErrBadRules, rule.URL)
+		}
+	}
+
+	return nil
+}
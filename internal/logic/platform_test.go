@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 Chrome/116.0 Mobile Safari/537.36"
+	uaWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/116.0 Safari/537.36"
+	uaMac     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 Version/16.5 Safari/605.1.15"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{ua: uaIPhone, want: models.PlatformIOS},
+		{ua: uaAndroid, want: models.PlatformAndroid},
+		{ua: uaWindows, want: models.PlatformWindows},
+		{ua: uaMac, want: models.PlatformMacOS},
+		{ua: "curl/8.1.2", want: models.PlatformOther},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectPlatform(tt.ua))
+		})
+	}
+}
+
+func Test_matchRules(t *testing.T) {
+	rules := []models.RedirectRule{
+		{Platform: models.PlatformIOS, URL: "https://apps.apple.com/app"},
+		{Platform: models.PlatformMobile, URL: "https://play.google.com/app"},
+	}
+
+	got, ok := matchRules(rules, uaIPhone)
+	assert.True(t, ok)
+	assert.Equal(t, "https://apps.apple.com/app", got)
+
+	got, ok = matchRules(rules, uaAndroid)
+	assert.True(t, ok)
+	assert.Equal(t, "https://play.google.com/app", got)
+
+	_, ok = matchRules(rules, uaWindows)
+	assert.False(t, ok)
+}
+
+func Test_validateRules(t *testing.T) {
+	assert.NoError(t, validateRules([]models.RedirectRule{{Platform: models.PlatformDesktop, URL: "https://ya.ru"}}))
+	assert.ErrorIs(t, validateRules([]models.RedirectRule{{Platform: "tv", URL: "https://ya.ru"}}), ErrBadRules)
+	assert.ErrorIs(t, validateRules([]models.RedirectRule{{Platform: models.PlatformIOS, URL: "ya.ru"}}), ErrBadRules)
+}
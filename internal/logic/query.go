@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+const (
+	utmPrefix   = "utm_"
+	utmSlug     = "{slug}"
+	utmDate     = "{date}"
+	utmDateForm = "2006-01-02"
+)
+
+// utmKeys допустимые ключи UTM-шаблонов.
+var utmKeys = map[string]struct{}{
+	"source":   {},
+	"medium":   {},
+	"campaign": {},
+	"term":     {},
+	"content":  {},
+	"id":       {},
+}
+
+func validateQueryOptions(opts models.LinkOptions) error {
+	switch opts.QueryMode {
+	case models.QueryModeDrop, models.QueryModePreserve, models.QueryModeOverride, models.QueryModeAppend:
+	default:
+		return fmt.Errorf("%w: %q", ErrBadQueryMode, opts.QueryMode)
+	}
+
+	for key := range opts.UTM {
+		if _, ok := utmKeys[key]; !ok {
+			return fmt.Errorf("%w: %q", ErrBadUTM, key)
+		}
+	}
+
+	return nil
+}
+
+// buildDestination собирает итоговый адрес перенаправления: оригинальный URL,
+// параметры входящего запроса согласно QueryMode и UTM-метки ссылки.
+// UTM-метки выставляются, только если параметр еще не задан.
+func buildDestination(link *models.Link, incoming url.Values, now time.Time) (string, error) {
+	if len(link.UTM) == 0 && (link.QueryMode == models.QueryModeDrop || len(incoming) == 0) {
+		return link.OriginalURL, nil
+	}
+
+	dest, err := url.Parse(link.OriginalURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing original URL: %w", err)
+	}
+
+	query := dest.Query()
+	mergeQuery(query, incoming, link.QueryMode)
+
+	replacer := strings.NewReplacer(utmSlug, link.ShortURL, utmDate, now.Format(utmDateForm))
+	for key, tmpl := range link.UTM {
+		param := utmPrefix + key
+		if query.Has(param) {
+			continue
+		}
+		query.Set(param, replacer.Replace(tmpl))
+	}
+
+	dest.RawQuery = query.Encode()
+
+	return dest.String(), nil
+}
+
+func mergeQuery(dst url.Values, incoming url.Values, mode string) {
+	for key, values := range incoming {
+		switch mode {
+		case models.QueryModePreserve:
+			if !dst.Has(key) {
+				dst[key] = values
+			}
+		case models.QueryModeOverride:
+			dst[key] = values
+		case models.QueryModeAppend:
+			dst[key] = append(dst[key], values...)
+		}
+	}
+}
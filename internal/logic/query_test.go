@@ -0,0 +1,94 @@
+package logic
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_buildDestination(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		link     models.Link
+		incoming url.Values
+		want     string
+	}{
+		{
+			name:     "drop incoming query",
+			link:     models.Link{OriginalURL: "https://ya.ru/path?a=1"},
+			incoming: url.Values{"b": {"2"}},
+			want:     "https://ya.ru/path?a=1",
+		},
+		{
+			name: "preserve destination on conflict",
+			link: models.Link{
+				OriginalURL: "https://ya.ru/?a=1",
+				LinkOptions: models.LinkOptions{QueryMode: models.QueryModePreserve},
+			},
+			incoming: url.Values{"a": {"2"}, "b": {"3"}},
+			want:     "https://ya.ru/?a=1&b=3",
+		},
+		{
+			name: "override destination on conflict",
+			link: models.Link{
+				OriginalURL: "https://ya.ru/?a=1",
+				LinkOptions: models.LinkOptions{QueryMode: models.QueryModeOverride},
+			},
+			incoming: url.Values{"a": {"2"}},
+			want:     "https://ya.ru/?a=2",
+		},
+		{
+			name: "append on conflict",
+			link: models.Link{
+				OriginalURL: "https://ya.ru/?a=1",
+				LinkOptions: models.LinkOptions{QueryMode: models.QueryModeAppend},
+			},
+			incoming: url.Values{"a": {"2"}},
+			want:     "https://ya.ru/?a=1&a=2",
+		},
+		{
+			name: "utm templates",
+			link: models.Link{
+				ShortURL:    "abcd",
+				OriginalURL: "https://ya.ru/",
+				LinkOptions: models.LinkOptions{
+					UTM: map[string]string{"source": "short-{slug}", "campaign": "c-{date}"},
+				},
+			},
+			want: "https://ya.ru/?utm_campaign=c-2023-10-01&utm_source=short-abcd",
+		},
+		{
+			name: "incoming utm wins over template",
+			link: models.Link{
+				ShortURL:    "abcd",
+				OriginalURL: "https://ya.ru/",
+				LinkOptions: models.LinkOptions{
+					QueryMode: models.QueryModeOverride,
+					UTM:       map[string]string{"source": "short"},
+				},
+			},
+			incoming: url.Values{"utm_source": {"newsletter"}},
+			want:     "https://ya.ru/?utm_source=newsletter",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildDestination(&tt.link, tt.incoming, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_validateQueryOptions(t *testing.T) {
+	assert.NoError(t, validateQueryOptions(models.LinkOptions{QueryMode: models.QueryModeAppend}))
+	assert.ErrorIs(t, validateQueryOptions(models.LinkOptions{QueryMode: "merge"}), ErrBadLinkOptions)
+	assert.ErrorIs(t, validateQueryOptions(models.LinkOptions{UTM: map[string]string{"foo": "bar"}}), ErrBadUTM)
+}
@@ -0,0 +1,72 @@
+package logic
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+// maxVariants ограничение на количество вариантов у одной ссылки.
+const maxVariants = 16
+
+// chooseVariant выбирает вариант ссылки. Закрепленный за посетителем вариант
+// используется, если он все еще есть у ссылки, иначе вариант выбирается случайно
+// пропорционально весам.
+func chooseVariant(variants []models.Variant, preferred string, rnd func(n int) int) (models.Variant, bool) {
+	if len(variants) == 0 {
+		return models.Variant{}, false
+	}
+
+	total := 0
+	for _, v := range variants {
+		if preferred != "" && v.ID == preferred {
+			return v, true
+		}
+		total += v.Weight
+	}
+
+	n := rnd(total)
+	for _, v := range variants {
+		if n < v.Weight {
+			return v, true
+		}
+		n -= v.Weight
+	}
+
+	return variants[len(variants)-1], true
+}
+
+func randIntn(n int) int {
+	//nolint:gosec // выбор варианта не требует криптостойкости
+	return rand.Intn(n)
+}
+
+func validateVariants(variants []models.Variant) error {
+	if len(variants) > maxVariants {
+		return fmt.Errorf("%w: more than %d variants", ErrBadVariants, maxVariants)
+	}
+
+	ids := make(map[string]struct{}, len(variants))
+	for _, v := range variants {
+		if v.ID == "" {
+			return fmt.Errorf("%w: empty variant id", ErrBadVariants)
+		}
+		if _, ok := ids[v.ID]; ok {
+			return fmt.Errorf("%w: duplicate variant id %q", ErrBadVariants, v.ID)
+		}
+		ids[v.ID] = struct{}{}
+
+		if v.Weight <= 0 {
+			return fmt.Errorf("%w: variant %q weight must be positive", ErrBadVariants, v.ID)
+		}
+
+		u, err := url.Parse(v.URL)
+		if err != nil || u.Scheme == "" {
+			return fmt.Errorf("%w: bad url %q", ErrBadVariants, v.URL)
+		}
+	}
+
+	return nil
+}
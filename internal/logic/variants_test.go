@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_chooseVariant(t *testing.T) {
+	variants := []models.Variant{
+		{ID: "a", URL: "https://a.ru", Weight: 1},
+		{ID: "b", URL: "https://b.ru", Weight: 3},
+	}
+
+	tests := []struct {
+		name      string
+		preferred string
+		roll      int
+		want      string
+	}{
+		{name: "first bucket", roll: 0, want: "a"},
+		{name: "second bucket", roll: 1, want: "b"},
+		{name: "last in second bucket", roll: 3, want: "b"},
+		{name: "sticky variant", preferred: "a", roll: 3, want: "a"},
+		{name: "unknown sticky variant", preferred: "c", roll: 0, want: "a"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := chooseVariant(variants, tt.preferred, func(n int) int {
+				assert.Equal(t, 4, n)
+				return tt.roll
+			})
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+
+	_, ok := chooseVariant(nil, "", randIntn)
+	assert.False(t, ok)
+}
+
+func Test_validateVariants(t *testing.T) {
+	assert.NoError(t, validateVariants([]models.Variant{{ID: "a", URL: "https://a.ru", Weight: 1}}))
+	assert.ErrorIs(t, validateVariants([]models.Variant{{ID: "a", URL: "https://a.ru", Weight: 0}}), ErrBadVariants)
+	assert.ErrorIs(t, validateVariants([]models.Variant{
+		{ID: "a", URL: "https://a.ru", Weight: 1},
+		{ID: "a", URL: "https://b.ru", Weight: 1},
+	}), ErrBadVariants)
+}
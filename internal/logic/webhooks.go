@@ -0,0 +1,211 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/netguard"
+	"github.com/rawen554/shortener/internal/webhook"
+)
+
+const (
+	// maxWebhooks ограничение на количество подписок у одного пользователя.
+	maxWebhooks = 10
+	// deliveriesLimit сколько последних доставок возвращается в журнале.
+	deliveriesLimit = 50
+)
+
+var ErrBadWebhook = errors.New("invalid webhook")
+
+// CreateWebhook создает подписку пользователя на события ссылок.
+// Если список событий пуст, подписка получает все события.
+// Ключ подписи возвращается только в ответе на создание.
+func (cl *CoreLogic) CreateWebhook(ctx context.Context, userID string, req models.WebhookReq) (*models.Webhook, error) {
+	if err := validateWebhook(req); err != nil {
+		return nil, err
+	}
+	// доставки все равно проверяют адрес при соединении, здесь ошибка видна сразу при создании
+	u, _ := url.Parse(req.URL)
+	if err := netguard.CheckHost(ctx, u.Hostname(), cl.config.AllowLoopback); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadWebhook, err)
+	}
+
+	hooks, err := cl.getWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) >= maxWebhooks {
+		return nil, fmt.Errorf("%w: more than %d webhooks", ErrBadWebhook, maxWebhooks)
+	}
+
+	id, err := webhook.NewID()
+	if err != nil {
+		cl.logger.Error(err)
+		return nil, err
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	events := req.Events
+	if len(events) == 0 {
+		events = models.Events
+	}
+
+	hook := models.Webhook{
+		CreatedAt: time.Now().UTC(),
+		ID:        id,
+		UserID:    userID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+	}
+	if err := cl.store.PutWebhook(hook); err != nil {
+		err = fmt.Errorf("error saving webhook: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+	cl.webhooks.Invalidate(userID)
+
+	return &hook, nil
+}
+
+// GetWebhooks возвращает подписки пользователя без ключей подписи.
+func (cl *CoreLogic) GetWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	hooks, err := cl.getWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	return hooks, nil
+}
+
+// DeleteWebhook удаляет подписку пользователя вместе с журналом доставок.
+func (cl *CoreLogic) DeleteWebhook(ctx context.Context, userID string, id string) error {
+	if _, err := cl.getUserWebhook(userID, id); err != nil {
+		return err
+	}
+
+	if err := cl.store.DeleteWebhook(id); err != nil {
+		err = fmt.Errorf("error deleting webhook: %w", err)
+		cl.logger.Error(err)
+		return err
+	}
+	cl.webhooks.Invalidate(userID)
+
+	return nil
+}
+
+// EnableWebhook снова включает подписку, отключенную после неудачных доставок.
+func (cl *CoreLogic) EnableWebhook(ctx context.Context, userID string, id string) error {
+	hook, err := cl.getUserWebhook(userID, id)
+	if err != nil {
+		return err
+	}
+
+	hook.Disabled = false
+	hook.Failures = 0
+	hook.LastError = ""
+	if err := cl.store.UpdateWebhook(*hook); err != nil {
+		err = fmt.Errorf("error updating webhook: %w", err)
+		cl.logger.Error(err)
+		return err
+	}
+	cl.webhooks.Invalidate(userID)
+
+	return nil
+}
+
+// GetWebhookDeliveries возвращает последние доставки подписки пользователя, начиная с новых.
+func (cl *CoreLogic) GetWebhookDeliveries(
+	ctx context.Context,
+	userID string,
+	id string,
+) ([]models.WebhookDelivery, error) {
+	if _, err := cl.getUserWebhook(userID, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := cl.store.GetWebhookDeliveries(id, deliveriesLimit)
+	if err != nil {
+		err = fmt.Errorf("error getting webhook deliveries: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (cl *CoreLogic) getWebhooks(userID string) ([]models.Webhook, error) {
+	hooks, err := cl.store.GetWebhooks(userID)
+	if err != nil {
+		err = fmt.Errorf("error getting webhooks: %w", err)
+		cl.logger.Error(err)
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (cl *CoreLogic) getUserWebhook(userID string, id string) (*models.Webhook, error) {
+	hooks, err := cl.getWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hooks {
+		if hooks[i].ID == id {
+			return &hooks[i], nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// publish отправляет событие ссылки подпискам ее владельца.
+func (cl *CoreLogic) publish(userID string, eventType string, id string, data models.LinkEvent) {
+	if userID == "" {
+		return
+	}
+
+	shortURL, err := url.JoinPath(cl.config.RedirectBaseURL, id)
+	if err != nil {
+		cl.logger.Errorf(ErrorJoinURL, err)
+		return
+	}
+	data.ShortURL = shortURL
+
+	cl.webhooks.Publish(userID, eventType, data)
+}
+
+func validateWebhook(req models.WebhookReq) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: bad url %q", ErrBadWebhook, req.URL)
+	}
+
+	for _, event := range req.Events {
+		known := false
+		for _, e := range models.Events {
+			if e == event {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: unknown event %q", ErrBadWebhook, event)
+		}
+	}
+
+	return nil
+}
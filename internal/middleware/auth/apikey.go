@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rawen554/shortener/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// ScopeKey ключ контекста gin с правами ключа API.
+	ScopeKey     = "scope"
+	bearerPrefix = "Bearer "
+)
+
+// APIKeyVerifier проверяет ключ API. Для неизвестного ключа возвращает nil без ошибки.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
+type userIDCtxKey struct{}
+
+// WithUserID сохраняет идентификатор пользователя в контексте.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDCtxKey{}, userID)
+}
+
+// UserIDFromContext возвращает идентификатор пользователя, сохраненный WithUserID.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDCtxKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// BearerToken извлекает токен из значения заголовка "Authorization: Bearer <token>".
+func BearerToken(header string) (string, bool) {
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	return token, token != ""
+}
+
+// isSafeMethod методы HTTP, доступные ключам только для чтения.
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// authenticateAPIKey аутентифицирует запрос по ключу API из заголовка Authorization.
+func authenticateAPIKey(c *gin.Context, header string, apiKeys APIKeyVerifier, logger *zap.SugaredLogger) {
+	token, ok := BearerToken(header)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	key, err := apiKeys.VerifyAPIKey(c, token)
+	if err != nil {
+		logger.Errorf("error verifying api key: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if key.Scope == models.ScopeRead && !isSafeMethod(c.Request.Method) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	c.Set(UserIDKey, key.UserID)
+	c.Set(ScopeKey, key.Scope)
+	c.Next()
+}
@@ -19,25 +19,50 @@ type Claims struct {
 }
 
 const (
-	tokenExp   = time.Hour * 3
-	maxAge     = 3600 * 24 * 30
 	CookieName = "jwt-token"
 	UserIDKey  = "userID"
 )
 
+// TokenTTL задает время жизни токенов пользователя.
+type TokenTTL struct {
+	// Access — срок действия токена, после которого клиент получает новый.
+	Access time.Duration
+	// Refresh — срок с момента выдачи, в течение которого истекший токен
+	// обменивается на новый с тем же пользователем. Задает и время жизни cookie.
+	Refresh time.Duration
+}
+
+// DefaultTokenTTL используется для незаданных значений TokenTTL.
+var DefaultTokenTTL = TokenTTL{
+	Access:  time.Hour * 3,
+	Refresh: time.Hour * 24 * 30,
+}
+
+// withDefaults подставляет значения по умолчанию вместо нулевых.
+func (ttl TokenTTL) withDefaults() TokenTTL {
+	if ttl.Access <= 0 {
+		ttl.Access = DefaultTokenTTL.Access
+	}
+	if ttl.Refresh <= 0 {
+		ttl.Refresh = DefaultTokenTTL.Refresh
+	}
+	return ttl
+}
+
 var ErrTokenNotValid = errors.New("token is not valid")
+var ErrTokenExpired = errors.New("token is expired")
 var ErrNoUserInToken = errors.New("no user data in token")
 var ErrBuildJWTString = errors.New("error building JWT string")
 
-func BuildJWTString(secret string, userID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+func BuildJWTString(keys *Keyring, userID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	tokenString, err := keys.Sign(Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExp)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 		UserID: userID,
 	})
-
-	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
 		return "", fmt.Errorf("error creating signed JWT: %w", err)
 	}
@@ -46,72 +71,120 @@ func BuildJWTString(secret string, userID string) (string, error) {
 	return tokenString, nil
 }
 
-func GetUserID(tokenString string, secret string) (string, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims,
-		func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+// SetTokenCookie выдает клиенту cookie с токеном пользователя userID.
+func SetTokenCookie(c *gin.Context, keys *Keyring, ttl TokenTTL, userID string) error {
+	ttl = ttl.withDefaults()
+	token, err := BuildJWTString(keys, userID, ttl.Access)
 	if err != nil {
-		if !token.Valid {
-			return "", ErrTokenNotValid
-		} else {
-			return "", errors.New("parsing error")
+		return err
+	}
+
+	c.SetCookie(CookieName, token, int(ttl.Refresh.Seconds()), "", "", false, true)
+	return nil
+}
+
+// ParseToken проверяет подпись токена и возвращает его данные.
+// Для истекшего токена с верной подписью вместе с данными возвращается ErrTokenExpired,
+// для поддельного или поврежденного — ErrTokenNotValid.
+func ParseToken(tokenString string, keys *Keyring) (*Claims, error) {
+	claims := &Claims{}
+	if err := keys.Parse(tokenString, claims); err != nil {
+		var validationErr *jwt.ValidationError
+		// истекший токен с верной подписью дает только ошибку срока действия
+		if errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired {
+			return claims, ErrTokenExpired
 		}
+		return nil, ErrTokenNotValid
 	}
 
 	if claims.UserID == "" {
-		return "", ErrNoUserInToken
+		return nil, ErrNoUserInToken
+	}
+
+	return claims, nil
+}
+
+func GetUserID(tokenString string, keys *Keyring) (string, error) {
+	claims, err := ParseToken(tokenString, keys)
+	if err != nil {
+		return "", err
 	}
 
 	return claims.UserID, nil
 }
 
-func NewAuthMiddleware(secret string, logger *zap.SugaredLogger) (gin.HandlerFunc, error) {
-	if secret == "" {
-		return nil, fmt.Errorf("empty secret for signing token")
+// refreshable сообщает, можно ли обменять истекший токен на новый с тем же пользователем.
+// Окно продления отсчитывается от момента выдачи, а для токенов без него — от момента истечения.
+func (c *Claims) refreshable(refresh time.Duration, now time.Time) bool {
+	if c.UserID == "" {
+		return false
+	}
+
+	from := c.ExpiresAt
+	if c.IssuedAt != nil {
+		from = c.IssuedAt
+	}
+	if from == nil {
+		return false
+	}
+
+	return now.Before(from.Add(refresh))
+}
+
+// NewAuthMiddleware аутентифицирует запрос по ключу API из заголовка Authorization,
+// а при его отсутствии — по cookie с JWT, выдавая новому клиенту анонимный идентификатор.
+// Истекший токен в пределах ttl.Refresh продлевается с тем же пользователем,
+// новый идентификатор получают только клиенты с поддельным или слишком старым токеном.
+// Если apiKeys не задан, ключи API не принимаются.
+func NewAuthMiddleware(
+	keys *Keyring,
+	ttl TokenTTL,
+	apiKeys APIKeyVerifier,
+	logger *zap.SugaredLogger,
+) (gin.HandlerFunc, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("empty keyring for signing token")
 	}
+	ttl = ttl.withDefaults()
 
 	return func(c *gin.Context) {
+		if header := c.GetHeader("Authorization"); header != "" && apiKeys != nil {
+			authenticateAPIKey(c, header, apiKeys, logger)
+			return
+		}
+
+		var userID string
 		cookie, err := c.Cookie(CookieName)
-		if err != nil {
-			if errors.Is(err, http.ErrNoCookie) {
-				token, err := BuildJWTString(secret, uuid.New().String())
-				if err != nil {
-					logger.Error(ErrBuildJWTString, err)
-					c.AbortWithStatus(http.StatusInternalServerError)
-					return
-				}
-				c.SetCookie(CookieName, token, maxAge, "", "", false, true)
-				cookie = token
-			} else {
-				logger.Error("Error reading cookie[%v]: %v", CookieName, err)
-				c.AbortWithStatus(http.StatusInternalServerError)
-				return
-			}
+		if err != nil && !errors.Is(err, http.ErrNoCookie) {
+			logger.Errorf("Error reading cookie[%v]: %v", CookieName, err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
 		}
 
-		userID, err := GetUserID(cookie, secret)
-		if err != nil {
-			if errors.Is(err, ErrNoUserInToken) {
+		if err == nil {
+			claims, err := ParseToken(cookie, keys)
+			switch {
+			case err == nil:
+				c.Set(UserIDKey, claims.UserID)
+				c.Next()
+				return
+			case errors.Is(err, ErrNoUserInToken):
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
+			case errors.Is(err, ErrTokenExpired) && claims.refreshable(ttl.Refresh, time.Now()):
+				userID = claims.UserID
+			default:
+				logger.Infof("Issuing new identity instead of rejected token: %v", err)
 			}
-			if errors.Is(err, ErrTokenNotValid) {
-				token, err := BuildJWTString(secret, uuid.New().String())
-				if err != nil {
-					logger.Error(ErrBuildJWTString, err)
-					c.AbortWithStatus(http.StatusInternalServerError)
-					return
-				}
-				userID, err = GetUserID(token, secret)
-				if err != nil {
-					logger.Error("Revalidate error user id from renewed token: %v", err)
-					c.AbortWithStatus(http.StatusInternalServerError)
-					return
-				}
-				c.SetCookie(CookieName, token, maxAge, "", "", false, true)
-			}
+		}
+
+		if userID == "" {
+			userID = uuid.New().String()
+		}
+		if err := SetTokenCookie(c, keys, ttl, userID); err != nil {
+			logger.Error(ErrBuildJWTString, err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
 		}
 
 		c.Set(UserIDKey, userID)
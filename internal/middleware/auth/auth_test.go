@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testSecret = "b4952c3809196592c026529df00774e46bfb5be0"
+
+func newSecretKeyring(t *testing.T, secret string) *Keyring {
+	t.Helper()
+	keys, err := NewSecretKeyring(secret)
+	require.NoError(t, err)
+	return keys
+}
+
+func signClaims(t *testing.T, keys *Keyring, userID string, issuedAt time.Time, ttl time.Duration) string {
+	t.Helper()
+	token, err := keys.Sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
+		},
+		UserID: userID,
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestNewAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const userID = "user"
+	keys := newSecretKeyring(t, testSecret)
+	forged := newSecretKeyring(t, "another secret")
+	ttl := TokenTTL{Access: time.Hour, Refresh: 24 * time.Hour}
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		cookie      string
+		wantCode    int
+		wantUser    bool
+		wantRenewed bool
+	}{
+		{
+			name:        "no cookie",
+			wantCode:    http.StatusOK,
+			wantRenewed: true,
+		},
+		{
+			name:     "valid token",
+			cookie:   signClaims(t, keys, userID, now, ttl.Access),
+			wantCode: http.StatusOK,
+			wantUser: true,
+		},
+		{
+			name:        "expired token within refresh period",
+			cookie:      signClaims(t, keys, userID, now.Add(-2*time.Hour), ttl.Access),
+			wantCode:    http.StatusOK,
+			wantUser:    true,
+			wantRenewed: true,
+		},
+		{
+			name:        "expired token after refresh period",
+			cookie:      signClaims(t, keys, userID, now.Add(-48*time.Hour), ttl.Access),
+			wantCode:    http.StatusOK,
+			wantRenewed: true,
+		},
+		{
+			name:        "forged token",
+			cookie:      signClaims(t, forged, userID, now, ttl.Access),
+			wantCode:    http.StatusOK,
+			wantRenewed: true,
+		},
+		{
+			name:        "forged expired token",
+			cookie:      signClaims(t, forged, userID, now.Add(-2*time.Hour), ttl.Access),
+			wantCode:    http.StatusOK,
+			wantRenewed: true,
+		},
+		{
+			name:        "malformed token",
+			cookie:      "not a token",
+			wantCode:    http.StatusOK,
+			wantRenewed: true,
+		},
+		{
+			name:     "token without user",
+			cookie:   signClaims(t, keys, "", now, ttl.Access),
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	middleware, err := NewAuthMiddleware(keys, ttl, nil, zap.L().Sugar())
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			r := gin.New()
+			r.GET("/", middleware, func(c *gin.Context) {
+				gotUserID = c.GetString(UserIDKey)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.cookie})
+			}
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			require.NotEmpty(t, gotUserID)
+			if tt.wantUser {
+				assert.Equal(t, userID, gotUserID)
+			} else {
+				assert.NotEqual(t, userID, gotUserID)
+			}
+
+			var renewed *http.Cookie
+			for _, c := range res.Cookies() {
+				if c.Name == CookieName {
+					renewed = c
+				}
+			}
+			if !tt.wantRenewed {
+				assert.Nil(t, renewed)
+				return
+			}
+
+			require.NotNil(t, renewed)
+			assert.Equal(t, int(ttl.Refresh.Seconds()), renewed.MaxAge)
+			renewedUserID, err := GetUserID(renewed.Value, keys)
+			require.NoError(t, err)
+			assert.Equal(t, gotUserID, renewedUserID)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeyConfig описывает ключ подписи в файле ключей.
+type KeyConfig struct {
+	// ID передается в заголовке kid выпущенных токенов.
+	ID string `json:"kid"`
+	// Alg — алгоритм подписи: HS256, RS256 или EdDSA.
+	Alg string `json:"alg"`
+	// Secret — секрет для HS256.
+	Secret string `json:"secret,omitempty"`
+	// KeyFile — PEM-файл с закрытым ключом для RS256 и EdDSA. Ключ, для которого указан
+	// только открытый ключ, принимается лишь для проверки. Относительный путь отсчитывается
+	// от каталога файла ключей.
+	KeyFile string `json:"key_file,omitempty"`
+}
+
+// KeyringConfig — содержимое файла ключей.
+type KeyringConfig struct {
+	// Active — идентификатор ключа, которым подписываются новые токены.
+	Active string      `json:"active"`
+	Keys   []KeyConfig `json:"keys"`
+}
+
+// JWK — открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS — набор открытых ключей, публикуемый для проверки токенов сторонними сервисами.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type signingKey struct {
+	method jwt.SigningMethod
+	// signKey пуст у ключей, принимаемых только для проверки.
+	signKey   interface{}
+	verifyKey interface{}
+	id        string
+}
+
+type keySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	// order сохраняет порядок ключей из конфигурации для JWKS и проверки токенов без kid.
+	order []*signingKey
+}
+
+// Keyring хранит ключи подписи токенов: активный ключ подписывает новые токены,
+// остальные принимаются при проверке, что позволяет менять ключи без выхода пользователей.
+type Keyring struct {
+	set  *keySet
+	path string
+	mu   sync.RWMutex
+}
+
+// NewSecretKeyring создает набор из единственного ключа HS256 без идентификатора.
+// Токены такого набора совместимы с токенами, выпущенными до появления ключей.
+func NewSecretKeyring(secret string) (*Keyring, error) {
+	set, err := newKeySet(KeyringConfig{Keys: []KeyConfig{{Alg: AlgHS256, Secret: secret}}}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keyring{set: set}, nil
+}
+
+// LoadKeyring читает набор ключей из файла path, а если путь не задан — создает его из secret.
+func LoadKeyring(path string, secret string) (*Keyring, error) {
+	if path == "" {
+		return NewSecretKeyring(secret)
+	}
+
+	k := &Keyring{path: path}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Reload перечитывает файл ключей. При ошибке продолжает действовать прежний набор.
+// Для набора, созданного из секрета, ничего не делает.
+func (k *Keyring) Reload() error {
+	if k.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("error reading keyring file: %w", err)
+	}
+
+	var config KeyringConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("error parsing keyring file: %w", err)
+	}
+
+	set, err := newKeySet(config, filepath.Dir(k.path))
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.set = set
+	return nil
+}
+
+func (k *Keyring) keys() *keySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.set
+}
+
+// Sign подписывает claims активным ключом и указывает его идентификатор в заголовке kid.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	active := k.keys().active
+
+	token := jwt.NewWithClaims(active.method, claims)
+	if active.id != "" {
+		token.Header["kid"] = active.id
+	}
+
+	tokenString, err := token.SignedString(active.signKey)
+	if err != nil {
+		return "", fmt.Errorf("error signing token with key %q: %w", active.id, err)
+	}
+
+	return tokenString, nil
+}
+
+// Parse проверяет подпись токена ключом из заголовка kid и заполняет claims.
+// Токены без kid проверяются всеми ключами набора по порядку.
+func (k *Keyring) Parse(tokenString string, claims jwt.Claims) error {
+	set := k.keys()
+
+	keyfunc := func(key *signingKey) jwt.Keyfunc {
+		return func(t *jwt.Token) (interface{}, error) {
+			if key == nil {
+				kid, _ := t.Header["kid"].(string)
+				if key = set.keys[kid]; key == nil {
+					return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+				}
+			}
+			if t.Method.Alg() != key.method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			return key.verifyKey, nil
+		}
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	if err != nil {
+		return fmt.Errorf("error parsing token: %w", err)
+	}
+	if _, ok := token.Header["kid"]; ok {
+		_, err = jwt.ParseWithClaims(tokenString, claims, keyfunc(nil))
+		return err //nolint:wrapcheck // вызывающий код разбирает ошибку валидации
+	}
+
+	for _, key := range set.order {
+		_, err = jwt.ParseWithClaims(tokenString, claims, keyfunc(key))
+		var validationErr *jwt.ValidationError
+		if !errors.As(err, &validationErr) ||
+			validationErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) == 0 {
+			break
+		}
+	}
+	return err //nolint:wrapcheck // вызывающий код разбирает ошибку валидации
+}
+
+// JWKS возвращает открытые ключи RS256 и EdDSA. Ключи HS256 не публикуются.
+func (k *Keyring) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0)}
+	for _, key := range k.keys().order {
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func newKeySet(config KeyringConfig, dir string) (*keySet, error) {
+	set := &keySet{keys: make(map[string]*signingKey, len(config.Keys))}
+	for _, kc := range config.Keys {
+		if _, ok := set.keys[kc.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", kc.ID)
+		}
+
+		key, err := newSigningKey(kc, dir)
+		if err != nil {
+			return nil, fmt.Errorf("error loading key %q: %w", kc.ID, err)
+		}
+		set.keys[kc.ID] = key
+		set.order = append(set.order, key)
+	}
+
+	active, ok := set.keys[config.Active]
+	if !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, config.Active)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", config.Active)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+func newSigningKey(config KeyConfig, dir string) (*signingKey, error) {
+	key := &signingKey{id: config.ID}
+
+	if config.Alg == AlgHS256 {
+		if config.Secret == "" {
+			return nil, errors.New("empty secret")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(config.Secret)
+		key.verifyKey = key.signKey
+		return key, nil
+	}
+
+	path := config.KeyFile
+	if path == "" {
+		return nil, errors.New("empty key file")
+	}
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+
+	switch config.Alg {
+	case AlgRS256:
+		key.method = jwt.SigningMethodRS256
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.signKey, key.verifyKey = private, &private.PublicKey
+			return key, nil
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing RSA key: %w", err)
+		}
+		key.verifyKey = public
+	case AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			signer, ok := private.(crypto.Signer)
+			if !ok {
+				return nil, errors.New("unsupported EdDSA private key")
+			}
+			key.signKey, key.verifyKey = signer, signer.Public()
+			return key, nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing EdDSA key: %w", err)
+		}
+		key.verifyKey = public
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", config.Alg)
+	}
+
+	return key, nil
+}
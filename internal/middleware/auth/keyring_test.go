@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyring(t *testing.T, path string, config KeyringConfig) {
+	t.Helper()
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+func signUser(t *testing.T, keys *Keyring, userID string) string {
+	t.Helper()
+	return signClaims(t, keys, userID, time.Now(), time.Hour)
+}
+
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	require.NoError(t, err)
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	k1 := KeyConfig{ID: "k1", Alg: AlgHS256, Secret: "first secret"}
+	k2 := KeyConfig{ID: "k2", Alg: AlgHS256, Secret: "second secret"}
+
+	writeKeyring(t, path, KeyringConfig{Active: "k1", Keys: []KeyConfig{k1}})
+	keys, err := LoadKeyring(path, "")
+	require.NoError(t, err)
+
+	old := signUser(t, keys, "old")
+	assert.Equal(t, "k1", kidOf(t, old))
+
+	writeKeyring(t, path, KeyringConfig{Active: "k2", Keys: []KeyConfig{k2, k1}})
+	require.NoError(t, keys.Reload())
+
+	fresh := signUser(t, keys, "fresh")
+	assert.Equal(t, "k2", kidOf(t, fresh))
+	for token, want := range map[string]string{old: "old", fresh: "fresh"} {
+		userID, err := GetUserID(token, keys)
+		require.NoError(t, err)
+		assert.Equal(t, want, userID)
+	}
+
+	writeKeyring(t, path, KeyringConfig{Active: "k2", Keys: []KeyConfig{k2}})
+	require.NoError(t, keys.Reload())
+
+	_, err = GetUserID(old, keys)
+	assert.ErrorIs(t, err, ErrTokenNotValid)
+
+	// неверный файл не заменяет действующие ключи
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	assert.Error(t, keys.Reload())
+	_, err = GetUserID(fresh, keys)
+	assert.NoError(t, err)
+}
+
+func TestKeyring_LegacyTokens(t *testing.T) {
+	legacy := newSecretKeyring(t, testSecret)
+	token := signUser(t, legacy, "user")
+	assert.Empty(t, kidOf(t, token))
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeyring(t, path, KeyringConfig{Active: "new", Keys: []KeyConfig{
+		{ID: "new", Alg: AlgHS256, Secret: "new secret"},
+		{ID: "legacy", Alg: AlgHS256, Secret: testSecret},
+	}})
+	keys, err := LoadKeyring(path, "")
+	require.NoError(t, err)
+
+	userID, err := GetUserID(token, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "user", userID)
+
+	_, err = GetUserID(signUser(t, newSecretKeyring(t, "forged"), "user"), keys)
+	assert.ErrorIs(t, err, ErrTokenNotValid)
+}
+
+func TestKeyring_Asymmetric(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "rsa.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ed.pem"), "PRIVATE KEY", edDER)
+	edPublicDER, err := x509.MarshalPKIXPublicKey(edPublic)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ed.pub.pem"), "PUBLIC KEY", edPublicDER)
+
+	keyConfigs := []KeyConfig{
+		{ID: "rsa", Alg: AlgRS256, KeyFile: "rsa.pem"},
+		{ID: "ed", Alg: AlgEdDSA, KeyFile: "ed.pem"},
+		{ID: "hmac", Alg: AlgHS256, Secret: testSecret},
+	}
+
+	path := filepath.Join(dir, "keys.json")
+	for _, active := range []string{"rsa", "ed"} {
+		t.Run(active, func(t *testing.T) {
+			writeKeyring(t, path, KeyringConfig{Active: active, Keys: keyConfigs})
+			keys, err := LoadKeyring(path, "")
+			require.NoError(t, err)
+
+			token := signUser(t, keys, "user")
+			assert.Equal(t, active, kidOf(t, token))
+			userID, err := GetUserID(token, keys)
+			require.NoError(t, err)
+			assert.Equal(t, "user", userID)
+		})
+	}
+
+	t.Run("public key verifies only", func(t *testing.T) {
+		writeKeyring(t, path, KeyringConfig{Active: "ed", Keys: keyConfigs})
+		signer, err := LoadKeyring(path, "")
+		require.NoError(t, err)
+		token := signUser(t, signer, "user")
+
+		verifyOnly := []KeyConfig{keyConfigs[2], {ID: "ed", Alg: AlgEdDSA, KeyFile: "ed.pub.pem"}}
+		writeKeyring(t, path, KeyringConfig{Active: "hmac", Keys: verifyOnly})
+		keys, err := LoadKeyring(path, "")
+		require.NoError(t, err)
+		_, err = GetUserID(token, keys)
+		assert.NoError(t, err)
+
+		writeKeyring(t, path, KeyringConfig{Active: "ed", Keys: verifyOnly})
+		_, err = LoadKeyring(path, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("jwks", func(t *testing.T) {
+		writeKeyring(t, path, KeyringConfig{Active: "rsa", Keys: keyConfigs})
+		keys, err := LoadKeyring(path, "")
+		require.NoError(t, err)
+
+		jwks := keys.JWKS()
+		require.Len(t, jwks.Keys, 2)
+		assert.Equal(t, JWK{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: AlgRS256, N: jwks.Keys[0].N, E: "AQAB"}, jwks.Keys[0])
+		assert.NotEmpty(t, jwks.Keys[0].N)
+		assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+		assert.Equal(t, "Ed25519", jwks.Keys[1].Crv)
+		assert.Equal(t, "ed", jwks.Keys[1].Kid)
+	})
+}
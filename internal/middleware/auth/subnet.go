@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 
@@ -8,35 +10,52 @@ import (
 	"go.uber.org/zap"
 )
 
-func NewSubnetChecker(trustedSubnet string, logger *zap.SugaredLogger) gin.HandlerFunc {
+var (
+	ErrNoTrustedSubnet = errors.New("trusted subnet is not defined")
+	ErrNoRealIP        = errors.New("empty X-Real-IP")
+	ErrBadRealIP       = errors.New("error parsing X-Real-IP")
+	ErrUntrustedIP     = errors.New("unauthorized request denied")
+)
+
+// ParseTrustedSubnet разбирает доверенную подсеть. При ошибке внутренние методы недоступны.
+func ParseTrustedSubnet(trustedSubnet string, logger *zap.SugaredLogger) *net.IPNet {
 	_, netMask, err := net.ParseCIDR(trustedSubnet)
 	if err != nil {
-		logger.Warnf("cannot parse trusted subnet, internal service unavaliable: %w", err)
+		logger.Warnf("cannot parse trusted subnet, internal service unavaliable: %v", err)
+		return nil
 	}
 
-	return func(c *gin.Context) {
-		if netMask == nil {
-			logger.Error("trusted subnet is not defined")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
+	return netMask
+}
 
-		realIP := c.GetHeader("X-Real-IP")
-		if realIP == "" {
-			logger.Error("internal request: empty X-Real-IP")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
+// CheckRealIP проверяет, что адрес клиента входит в доверенную подсеть.
+func CheckRealIP(netMask *net.IPNet, realIP string) error {
+	if netMask == nil {
+		return ErrNoTrustedSubnet
+	}
 
-		ipAddr := net.ParseIP(realIP)
-		if ipAddr == nil {
-			logger.Error("internal request: error parsing X-Real-IP")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
+	if realIP == "" {
+		return ErrNoRealIP
+	}
+
+	ipAddr := net.ParseIP(realIP)
+	if ipAddr == nil {
+		return ErrBadRealIP
+	}
+
+	if !netMask.Contains(ipAddr) {
+		return fmt.Errorf("%w: ip %s", ErrUntrustedIP, ipAddr)
+	}
+
+	return nil
+}
 
-		if !netMask.Contains(ipAddr) {
-			logger.Errorf("internal request: unauthorized request denied: ip %s", ipAddr)
+func NewSubnetChecker(trustedSubnet string, logger *zap.SugaredLogger) gin.HandlerFunc {
+	netMask := ParseTrustedSubnet(trustedSubnet, logger)
+
+	return func(c *gin.Context) {
+		if err := CheckRealIP(netMask, c.GetHeader("X-Real-IP")); err != nil {
+			logger.Errorf("internal request: %v", err)
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
@@ -44,6 +44,20 @@ func (c *compressWriter) WriteHeader(statusCode int) {
 	c.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Flush отправляет клиенту данные, накопленные в gzip.Writer, чтобы потоковые ответы
+// не задерживались до завершения запроса.
+func (c *compressWriter) Flush() {
+	if err := c.zw.Flush(); err != nil {
+		return
+	}
+	c.ResponseWriter.Flush()
+}
+
+// Unwrap возвращает исходный http.ResponseWriter.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
 // Close закрывает gzip.Writer и досылает все данные из буфера.
 func (c *compressWriter) Close() error {
 	if err := c.zw.Close(); err != nil {
@@ -0,0 +1,54 @@
+// Модуль присваивает запросам идентификаторы для сопоставления ответов и журналов.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Header заголовок с идентификатором запроса.
+	Header = "X-Request-ID"
+	// Key ключ идентификатора запроса в контексте gin.
+	Key = "request_id"
+
+	maxLength = 64
+	idBytes   = 16
+)
+
+// RequestID Получение middleware функции, которая присваивает запросу идентификатор.
+// Идентификатор клиента из заголовка X-Request-ID сохраняется, если он не длиннее 64 печатных
+// символов ASCII, иначе генерируется новый. Идентификатор возвращается в заголовке ответа.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !isValid(id) {
+			id = generate()
+		}
+
+		c.Set(Key, id)
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+func isValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func generate() string {
+	b := make([]byte, idBytes)
+	// crypto/rand не возвращает ошибок на поддерживаемых платформах
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,51 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "generated", header: "", keep: false},
+		{name: "client id kept", header: "req-42", keep: true},
+		{name: "too long", header: strings.Repeat("a", maxLength+1), keep: false},
+		{name: "non printable", header: "req 42", keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(RequestID())
+			var seen string
+			r.GET("/", func(c *gin.Context) {
+				seen = c.GetString(Key)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, seen, w.Header().Get(Header))
+			if tt.keep {
+				assert.Equal(t, tt.header, seen)
+			} else {
+				assert.Len(t, seen, 2*idBytes)
+			}
+		})
+	}
+}
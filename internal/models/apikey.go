@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Права ключа API.
+const (
+	// ScopeRead только чтение: ключ принимается для безопасных методов.
+	ScopeRead = "read"
+	// ScopeWrite чтение и изменение данных.
+	ScopeWrite = "write"
+)
+
+// APIKey ключ API пользователя для программных клиентов.
+// Сам ключ не хранится, сохраняется только его хеш.
+type APIKey struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Name      string    `json:"name"`
+	// Prefix начало ключа, по которому его можно узнать в списке.
+	Prefix string `json:"prefix"`
+	Hash   string `json:"-"`
+	Scope  string `json:"scope"`
+}
+
+// APIKeyReq структура запроса на создание ключа API.
+type APIKeyReq struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// APIKeyRes структура ответа на создание ключа API, ключ возвращается только здесь.
+type APIKeyRes struct {
+	APIKey
+	Key string `json:"key"`
+}
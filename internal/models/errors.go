@@ -0,0 +1,22 @@
+package models
+
+// ErrorRes тело ответа API v2 с ошибкой.
+type ErrorRes struct {
+	Error APIError `json:"error"`
+}
+
+// APIError описание ошибки API v2.
+type APIError struct {
+	// Code машиночитаемая причина ошибки, например NOT_FOUND или INVALID_JSON.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// RequestID идентификатор запроса, совпадает с заголовком X-Request-ID ответа.
+	RequestID string        `json:"request_id"`
+	Details   []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail уточнение ошибки, например поле запроса с некорректным значением.
+type ErrorDetail struct {
+	Field       string `json:"field,omitempty"`
+	Description string `json:"description"`
+}
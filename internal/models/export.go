@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Форматы экспорта и импорта ссылок.
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// LinkExport ссылка пользователя в формате экспорта и импорта.
+// При импорте CreatedAt, ShortURL и Clicks не используются.
+type LinkExport struct {
+	CreatedAt time.Time `json:"created_at"`
+	LinkOptions
+	Slug        string `json:"slug"`
+	ShortURL    string `json:"short_url,omitempty"`
+	OriginalURL string `json:"original_url"`
+	Clicks      int64  `json:"clicks"`
+}
+
+// Результаты импорта записи.
+const (
+	ImportCreated = "created"
+	ImportFailed  = "failed"
+)
+
+// ImportRow результат импорта одной записи.
+type ImportRow struct {
+	// Slug идентификатор из записи, ShortURL — созданная ссылка.
+	Slug     string `json:"slug,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	Status   string `json:"status"`
+	// Reason машиночитаемая причина ошибки, Error — ее описание.
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Row номер записи, начиная с 1; строка заголовка CSV не учитывается.
+	Row int `json:"row"`
+	// SlugPreserved ссылка создана с идентификатором из записи.
+	SlugPreserved bool `json:"slug_preserved"`
+}
+
+// ImportRes отчет об импорте ссылок.
+type ImportRes struct {
+	Rows    []ImportRow `json:"rows"`
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+}
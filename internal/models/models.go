@@ -1,27 +1,255 @@
 // Модуль декларирует модели объектов.
 package models
 
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
 // URLRecordFS структура URL записей при работе с файловой системой.
 type URLRecordFS struct {
+	CreatedAt time.Time `json:"created_at"`
 	URLRecord
-	UUID   string `json:"uuid"`
-	UserID string `json:"user_id"`
+	LinkOptions
+	UUID     string `json:"uuid"`
+	UserID   string `json:"user_id"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Deleted ссылка удалена владельцем, при чтении файла запись остается с этой отметкой.
+	Deleted bool `json:"deleted,omitempty"`
+	// Purged отметка об удалении данных пользователя, при чтении файла запись удаляется.
+	Purged bool `json:"purged,omitempty"`
 }
 
 // URLRecordMemory структура URL записей при работе с памятью.
 type URLRecordMemory struct {
+	CreatedAt time.Time
+	LinkOptions
 	OriginalURL string
 	UserID      string
+	Disabled    bool
+	// Deleted ссылка удалена владельцем и хранится до удаления данных пользователя.
+	Deleted bool
 }
 
-// URLRecord ожидаемое тело запроса на сохранение записи URL.
-type URLRecord struct {
+// LinkOptions параметры поведения короткой ссылки, задаваемые при создании.
+type LinkOptions struct {
+	// RedirectType код ответа при перенаправлении (301, 302, 307, 308).
+	// Нулевое значение означает код по умолчанию из конфигурации сервиса.
+	RedirectType int `json:"redirect_type,omitempty"`
+	// QueryMode правило переноса параметров запроса в оригинальный URL.
+	QueryMode string `json:"query_mode,omitempty"`
+	// UTM шаблоны UTM-меток: ключ без префикса utm_ (source, medium, ...) и значение,
+	// в котором допускаются подстановки {slug} и {date}.
+	UTM map[string]string `json:"utm,omitempty"`
+	// Rules упорядоченный список правил перенаправления по платформе клиента.
+	// Применяется первое подходящее правило, иначе используется оригинальный URL.
+	Rules []RedirectRule `json:"rules,omitempty"`
+	// Variants варианты адреса для A/B-тестирования, выбираются пропорционально весу.
+	Variants []Variant `json:"variants,omitempty"`
+	// Sticky закрепляет выбранный вариант за посетителем через cookie.
+	Sticky bool `json:"sticky,omitempty"`
+	// Title название ссылки для владельца, показывается на странице предпросмотра.
+	Title string `json:"title,omitempty"`
+	// Interstitial всегда показывать промежуточную страницу с обратным отсчетом.
+	Interstitial bool `json:"interstitial,omitempty"`
+}
+
+// Variant вариант адреса перенаправления с весом.
+type Variant struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// ClickStats счетчики переходов по ссылке: общий и по вариантам.
+type ClickStats struct {
+	Variants map[string]int64
+	Clicks   int64
+}
+
+// VariantStats вариант ссылки с количеством переходов.
+type VariantStats struct {
+	Variant
+	Clicks int64 `json:"clicks"`
+}
+
+// LinkStats структура ответа со статистикой переходов по ссылке.
+type LinkStats struct {
+	Variants []VariantStats `json:"variants"`
+	Clicks   int64          `json:"clicks"`
+}
+
+// RedirectRule правило перенаправления для определенной платформы клиента.
+type RedirectRule struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// Платформы клиента, распознаваемые по User-Agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformOther   = "other"
+
+	// PlatformMobile объединяет ios и android.
+	PlatformMobile = "mobile"
+	// PlatformDesktop объединяет windows, macos и linux.
+	PlatformDesktop = "desktop"
+)
+
+// Режимы переноса параметров запроса при перенаправлении.
+const (
+	// QueryModeDrop параметры входящего запроса отбрасываются.
+	QueryModeDrop = ""
+	// QueryModePreserve параметры добавляются, при конфликте остается значение оригинального URL.
+	QueryModePreserve = "preserve"
+	// QueryModeOverride параметры добавляются, при конфликте побеждает входящий запрос.
+	QueryModeOverride = "override"
+	// QueryModeAppend параметры добавляются, при конфликте сохраняются оба значения.
+	QueryModeAppend = "append"
+)
+
+// ErrLinkDeleted ссылка удалена владельцем. Ее возвращает Get всех хранилищ.
+var ErrLinkDeleted = errors.New("url is deleted")
+
+// Link полная запись короткой ссылки в хранилище.
+type Link struct {
+	CreatedAt time.Time `json:"created_at"`
+	LinkOptions
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	// Disabled ссылка отключена администратором.
+	Disabled bool `json:"disabled"`
+}
+
+// LinkFilter параметры поиска ссылок администратором.
+// Slug и URL ищутся по подстроке без учета регистра, UserID — по точному совпадению.
+type LinkFilter struct {
+	Slug   string
+	URL    string
+	UserID string
+	Limit  int
+	Offset int
+}
+
+// TransferReq структура запроса на передачу ссылок другому пользователю.
+type TransferReq struct {
+	UserID string `json:"user_id"`
+}
+
+// TransferRes структура ответа на передачу ссылок.
+type TransferRes struct {
+	Transferred int `json:"transferred"`
+}
+
+// PurgeRes структура ответа на удаление данных пользователя.
+type PurgeRes struct {
+	Deleted int `json:"deleted"`
+}
+
+// Preview данные страницы предпросмотра короткой ссылки.
+type Preview struct {
+	CreatedAt   time.Time
+	ShortURL    string
+	OriginalURL string
+	Title       string
+}
+
+// RedirectReq запрос на разрешение короткой ссылки.
+type RedirectReq struct {
+	Query     url.Values
+	ShortURL  string
+	UserAgent string
+	// Variant вариант, ранее закрепленный за посетителем.
+	Variant string
+}
+
+// Redirect результат разрешения короткой ссылки.
+type Redirect struct {
+	// Preview заполняется, если перед перенаправлением нужно показать промежуточную страницу.
+	Preview      *Preview
+	URL          string
+	Variant      string
+	RedirectType int
+	Sticky       bool
+}
+
+// URLRecord ожидаемое тело запроса на сохранение записи URL.
+type URLRecord struct {
+	// Health результат последней проверки доступности оригинального URL.
+	Health      *LinkHealth `json:"health,omitempty"`
+	ShortURL    string      `json:"short_url"`
+	OriginalURL string      `json:"original_url"`
+}
+
+// UserLink ссылка пользователя в выдаче списка ссылок.
+type UserLink struct {
+	CreatedAt time.Time `json:"created_at"`
+	URLRecord
+	LinkOptions
+	Clicks int64 `json:"clicks"`
+	// Deleted ссылка удалена владельцем, такие ссылки выдаются только по запросу.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Порядок сортировки ссылок пользователя.
+const (
+	SortByCreatedAt = "created_at"
+	SortByClicks    = "clicks"
+)
+
+// Выбор ссылок по признаку удаления.
+const (
+	// DeletedExclude только действующие ссылки.
+	DeletedExclude = ""
+	// DeletedOnly только удаленные ссылки.
+	DeletedOnly = "only"
+	// DeletedInclude действующие и удаленные ссылки.
+	DeletedInclude = "include"
+)
+
+// UserLinksQuery параметры выборки ссылок пользователя.
+// URL ищется по подстроке без учета регистра, Domain совпадает с хостом адреса назначения
+// или его родительским доменом. Нулевые границы даты создания не ограничивают выборку.
+type UserLinksQuery struct {
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	After       *LinkCursor
+	URL         string
+	Domain      string
+	Deleted     string
+	Sort        string
+	Desc        bool
+	// Limit максимальное число ссылок, ноль снимает ограничение.
+	Limit int
+}
+
+// LinkCursor позиция в списке ссылок пользователя: выдача продолжается после ссылки
+// с указанными значениями ключа сортировки и slug.
+type LinkCursor struct {
+	CreatedAt time.Time `json:"t"`
+	Slug      string    `json:"s"`
+	Clicks    int64     `json:"c"`
+}
+
+// LinkHealth результат проверки доступности оригинального URL.
+type LinkHealth struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+	Status    int       `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	Broken    bool      `json:"broken"`
 }
 
 // URLBatchReq структура запроса на сохранение батча.
 type URLBatchReq struct {
+	LinkOptions
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
 }
@@ -32,11 +260,21 @@ type URLBatchRes struct {
 	ShortURL      string `json:"short_url"`
 }
 
+// URLBatchStreamRes строка ответа потокового сокращения: результат строки запроса с номером Line.
+// Для несохраненной ссылки вместо ShortURL заполняется Error.
+type URLBatchStreamRes struct {
+	Error         *APIError `json:"error,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	ShortURL      string    `json:"short_url,omitempty"`
+	Line          int       `json:"line"`
+}
+
 // DeleteUserURLsReq структура запроса на удаление записей батчем.
 type DeleteUserURLsReq []string
 
 // ShortenReq структура запроса на сохранение одного URL.
 type ShortenReq struct {
+	LinkOptions
 	URL string `json:"url"`
 }
 
@@ -45,6 +283,21 @@ type ShortenRes struct {
 	Result string `json:"result"`
 }
 
+// IsValidRedirectType проверяет, что код ответа подходит для перенаправления.
+func IsValidRedirectType(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPermanentRedirect проверяет, что код ответа означает постоянное перенаправление.
+func IsPermanentRedirect(code int) bool {
+	return code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect
+}
+
 type Stats struct {
 	URLs  int `json:"urls"`
 	Users int `json:"users"`
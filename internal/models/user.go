@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUserConflict аккаунт с таким логином или идентификатором уже существует.
+// Его возвращает CreateUser всех хранилищ.
+var ErrUserConflict = errors.New("user already exists")
+
+// User зарегистрированный пользователь.
+type User struct {
+	CreatedAt    time.Time
+	ID           string
+	Login        string
+	PasswordHash string
+}
+
+// Credentials структура запроса на регистрацию и вход.
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// Account структура ответа на регистрацию и вход.
+type Account struct {
+	UserID string `json:"user_id"`
+	Login  string `json:"login"`
+	// Merged количество анонимных ссылок, перенесенных в аккаунт.
+	Merged int `json:"merged"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Типы событий жизненного цикла ссылки.
+const (
+	EventLinkCreated = "link.created"
+	EventLinkDeleted = "link.deleted"
+	EventLinkClicked = "link.clicked"
+)
+
+// Events все поддерживаемые типы событий.
+var Events = []string{EventLinkCreated, EventLinkDeleted, EventLinkClicked}
+
+// Webhook подписка пользователя на события ссылок.
+type Webhook struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	URL       string    `json:"url"`
+	// Secret ключ подписи, возвращается только при создании подписки.
+	Secret    string   `json:"secret,omitempty"`
+	LastError string   `json:"last_error,omitempty"`
+	Events    []string `json:"events"`
+	// Failures количество подряд неудачных доставок.
+	Failures int  `json:"failures"`
+	Disabled bool `json:"disabled"`
+}
+
+// Subscribed проверяет, что подписка получает события типа event.
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookReq структура запроса на создание подписки.
+type WebhookReq struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// LinkEvent данные события ссылки.
+type LinkEvent struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url,omitempty"`
+	Variant     string `json:"variant,omitempty"`
+}
+
+// WebhookEvent тело запроса, отправляемого подписчику.
+type WebhookEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Data      LinkEvent `json:"data"`
+}
+
+// WebhookDelivery запись журнала доставки события.
+type WebhookDelivery struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	WebhookID string    `json:"webhook_id"`
+	EventID   string    `json:"event_id"`
+	Event     string    `json:"event"`
+	Error     string    `json:"error,omitempty"`
+	Status    int       `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Success   bool      `json:"success"`
+}
@@ -0,0 +1,128 @@
+// Модуль защиты исходящих запросов по адресам, которые задают пользователи.
+//
+// Адрес проверяется при установке соединения, после разрешения имени, поэтому
+// запрос не попадет во внутреннюю сеть ни через IP в адресе, ни через DNS-имя,
+// ни через перенаправление.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const dialTimeout = time.Second * 10
+
+// ErrForbiddenAddress адрес во внутренней сети, запросы к нему запрещены.
+var ErrForbiddenAddress = errors.New("address is not allowed")
+
+// reservedNets диапазоны, не покрытые методами net.IP: текущая сеть, CGNAT,
+// служебные, тестовые, зарезервированные и NAT64.
+var reservedNets = parseNets(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+func parseNets(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Allowed сообщает, можно ли отправлять запросы на ip. Запрещены частные, локальные,
+// многоадресные и зарезервированные адреса; адреса loopback — если allowLoopback ложно.
+func Allowed(ip net.IP, allowLoopback bool) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return allowLoopback
+	}
+	if ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost разрешает имя хоста и возвращает ErrForbiddenAddress, если хотя бы один
+// из его адресов запрещен. Подходит для проверки адреса при его сохранении;
+// запросы все равно нужно отправлять через NewClient.
+func CheckHost(ctx context.Context, host string, allowLoopback bool) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !Allowed(ip, allowLoopback) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !Allowed(addr.IP, allowLoopback) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// control проверяет адрес, к которому устанавливается соединение.
+func control(allowLoopback bool) func(network string, address string, c syscall.RawConn) error {
+	return func(network string, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("error parsing address %s: %w", address, err)
+		}
+		if !Allowed(net.ParseIP(host), allowLoopback) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+		return nil
+	}
+}
+
+// NewClient создает http.Client, который не соединяется с запрещенными адресами
+// и не использует прокси из окружения: через прокси проверка адреса назначения невозможна.
+// Перенаправления выполняются, только если followRedirects истинно; каждый переход
+// проверяется так же, как исходный адрес.
+func NewClient(timeout time.Duration, allowLoopback bool, followRedirects bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: control(allowLoopback),
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+	if !followRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client
+}
@@ -0,0 +1,72 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		ip            string
+		allowLoopback bool
+		want          bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "127.0.0.1", allowLoopback: true, want: true},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "169.254.169.254", allowLoopback: true, want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "::ffff:10.0.0.1", want: false},
+		{ip: "64:ff9b::a00:1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, Allowed(net.ParseIP(tt.ip), tt.allowLoopback))
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	assert.ErrorIs(t, CheckHost(ctx, "169.254.169.254", true), ErrForbiddenAddress)
+	assert.ErrorIs(t, CheckHost(ctx, "localhost", false), ErrForbiddenAddress)
+	assert.NoError(t, CheckHost(ctx, "127.0.0.1", true))
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second, false, false).Get(srv.URL)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	resp, err := NewClient(time.Second, true, false).Get(srv.URL + "/redirect")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp, err = NewClient(time.Second, true, true).Get(srv.URL + "/redirect")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,83 @@
+package qr
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// Image сгенерированное изображение QR-кода.
+type Image struct {
+	ContentType string
+	// ETag хеш содержимого для условных запросов.
+	ETag string
+	Data []byte
+}
+
+type cacheEntry struct {
+	image *Image
+	key   string
+}
+
+// Cache LRU-кеш сгенерированных QR-кодов ограниченного размера.
+type Cache struct {
+	mux     *sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+}
+
+// NewCache создает кеш, хранящий не более size изображений.
+func NewCache(size int) *Cache {
+	return &Cache{
+		mux:     &sync.Mutex{},
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		size:    size,
+	}
+}
+
+// Get возвращает изображение из кеша или генерирует и сохраняет новое.
+func (c *Cache) Get(content string, opts Options) (*Image, error) {
+	key := content + "|" + opts.key()
+
+	c.mux.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		c.mux.Unlock()
+		return el.Value.(*cacheEntry).image, nil
+	}
+	c.mux.Unlock()
+
+	data, err := Render(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	img := &Image{
+		ContentType: opts.ContentType(),
+		ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		Data:        data,
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.size <= 0 {
+		return img, nil
+	}
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*cacheEntry).image, nil
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, image: img})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	return img, nil
+}
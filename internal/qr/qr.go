@@ -0,0 +1,208 @@
+// Модуль генерации QR-кодов для коротких ссылок в форматах PNG и SVG.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Форматы изображения QR-кода.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Значения параметров по умолчанию и ограничения.
+const (
+	DefaultSize   = 256
+	DefaultMargin = 4
+	DefaultLevel  = "M"
+
+	MinSize   = 64
+	MaxSize   = 2048
+	MaxMargin = 16
+
+	ContentTypePNG = "image/png"
+	ContentTypeSVG = "image/svg+xml"
+)
+
+// ErrBadOptions некорректные параметры генерации QR-кода.
+var ErrBadOptions = errors.New("invalid qr options")
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options параметры генерации QR-кода.
+type Options struct {
+	Format     string
+	Level      string
+	Foreground string
+	Background string
+	Size       int
+	Margin     int
+}
+
+// DefaultOptions параметры по умолчанию: PNG 256x256, уровень коррекции M, отступ 4 модуля,
+// черный код на белом фоне.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Level:      DefaultLevel,
+		Foreground: "000000",
+		Background: "ffffff",
+		Size:       DefaultSize,
+		Margin:     DefaultMargin,
+	}
+}
+
+// ContentType возвращает MIME-тип изображения для формата.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return ContentTypeSVG
+	}
+	return ContentTypePNG
+}
+
+// key строковое представление параметров для кеширования.
+func (o Options) key() string {
+	return strings.Join([]string{
+		o.Format, o.Level, o.Foreground, o.Background, strconv.Itoa(o.Size), strconv.Itoa(o.Margin),
+	}, "|")
+}
+
+// Validate проверяет параметры генерации.
+func (o Options) Validate() error {
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return fmt.Errorf("%w: unsupported format %q", ErrBadOptions, o.Format)
+	}
+	if _, ok := levels[o.Level]; !ok {
+		return fmt.Errorf("%w: unsupported error correction level %q", ErrBadOptions, o.Level)
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("%w: size must be in [%d, %d]", ErrBadOptions, MinSize, MaxSize)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("%w: margin must be in [0, %d]", ErrBadOptions, MaxMargin)
+	}
+	if _, err := parseColor(o.Foreground); err != nil {
+		return err
+	}
+	if _, err := parseColor(o.Background); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Render генерирует изображение QR-кода с содержимым content.
+func Render(content string, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(content, levels[opts.Level])
+	if err != nil {
+		return nil, fmt.Errorf("error encoding qr code: %w", err)
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	fg, _ := parseColor(opts.Foreground)
+	bg, _ := parseColor(opts.Background)
+
+	if opts.Format == FormatSVG {
+		return renderSVG(modules, opts), nil
+	}
+
+	return renderPNG(modules, opts, fg, bg)
+}
+
+func renderPNG(modules [][]bool, opts Options, fg color.RGBA, bg color.RGBA) ([]byte, error) {
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		scale = 1
+	}
+	// остаток распределяется по краям, чтобы изображение было ровно Size пикселей
+	side := opts.Size
+	if scale*total > side {
+		side = scale * total
+	}
+	offset := (side - scale*total) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{bg, fg})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0 := offset + (x+opts.Margin)*scale
+			y0 := offset + (y+opts.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error encoding png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func renderSVG(modules [][]bool, opts Options) []byte {
+	total := len(modules) + 2*opts.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#%s"/>`, total, total, strings.ToLower(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="#%s" d="`, strings.ToLower(opts.Foreground))
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// соседние темные модули в строке объединяются в один прямоугольник
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
+
+// parseColor разбирает цвет в формате RRGGBB.
+func parseColor(hex string) (color.RGBA, error) {
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("%w: color must be RRGGBB, got %q", ErrBadOptions, hex)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%w: color must be RRGGBB, got %q", ErrBadOptions, hex)
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
@@ -0,0 +1,82 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	const content = "http://localhost:8080/abcd"
+
+	tests := []struct {
+		name    string
+		opts    func(o *Options)
+		wantErr bool
+	}{
+		{name: "default png", opts: func(o *Options) {}},
+		{name: "svg with colors", opts: func(o *Options) {
+			o.Format = FormatSVG
+			o.Foreground = "1A2B3C"
+			o.Background = "FFEEDD"
+		}},
+		{name: "high level no margin", opts: func(o *Options) {
+			o.Level = "H"
+			o.Margin = 0
+			o.Size = 100
+		}},
+		{name: "bad format", opts: func(o *Options) { o.Format = "gif" }, wantErr: true},
+		{name: "bad level", opts: func(o *Options) { o.Level = "X" }, wantErr: true},
+		{name: "too small", opts: func(o *Options) { o.Size = 10 }, wantErr: true},
+		{name: "bad color", opts: func(o *Options) { o.Foreground = "red" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.opts(&opts)
+
+			data, err := Render(content, opts)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrBadOptions)
+				return
+			}
+			require.NoError(t, err)
+
+			if opts.Format == FormatSVG {
+				svg := string(data)
+				assert.True(t, strings.HasPrefix(svg, "<svg"))
+				assert.Contains(t, svg, `fill="#1a2b3c"`)
+				assert.Contains(t, svg, `fill="#ffeedd"`)
+				return
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, opts.Size, img.Bounds().Dx())
+			assert.Equal(t, opts.Size, img.Bounds().Dy())
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(1)
+	opts := DefaultOptions()
+
+	first, err := cache.Get("a", opts)
+	require.NoError(t, err)
+	second, err := cache.Get("a", opts)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	_, err = cache.Get("b", opts)
+	require.NoError(t, err)
+	third, err := cache.Get("a", opts)
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assert.Equal(t, first.ETag, third.ETag)
+}
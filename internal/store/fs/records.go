@@ -0,0 +1,256 @@
+package fs
+
+import (
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+)
+
+// Виды строк файла. Строки без поля kind — записи ссылок models.URLRecordFS.
+const (
+	kindUser            = "user"
+	kindAPIKey          = "api_key"
+	kindWebhook         = "webhook"
+	kindWebhookState    = "webhook_state"
+	kindWebhookDelivery = "webhook_delivery"
+	kindClick           = "click"
+)
+
+// recordHeader общая часть строк файла, по которой определяется их вид.
+type recordHeader struct {
+	Kind string `json:"kind"`
+}
+
+// Records состояние хранилища, восстановленное из файла.
+type Records struct {
+	URLs     map[string]models.URLRecordMemory
+	Users    map[string]models.User
+	APIKeys  map[string]models.APIKey
+	Webhooks map[string]models.Webhook
+	// Deliveries журнал доставок подписок в порядке записи.
+	Deliveries map[string][]models.WebhookDelivery
+	Clicks     map[string]*models.ClickStats
+}
+
+func newRecords() *Records {
+	return &Records{
+		URLs:       make(map[string]models.URLRecordMemory),
+		Users:      make(map[string]models.User),
+		APIKeys:    make(map[string]models.APIKey),
+		Webhooks:   make(map[string]models.Webhook),
+		Deliveries: make(map[string][]models.WebhookDelivery),
+		Clicks:     make(map[string]*models.ClickStats),
+	}
+}
+
+// userRecord строка файла с аккаунтом пользователя.
+type userRecord struct {
+	CreatedAt    time.Time `json:"created_at"`
+	Kind         string    `json:"kind"`
+	ID           string    `json:"id"`
+	Login        string    `json:"login,omitempty"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	// Deleted отметка об удалении аккаунта, при чтении файла аккаунт удаляется.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func newUserRecord(user models.User) *userRecord {
+	return &userRecord{
+		Kind:         kindUser,
+		CreatedAt:    user.CreatedAt,
+		ID:           user.ID,
+		Login:        user.Login,
+		PasswordHash: user.PasswordHash,
+	}
+}
+
+func (r *userRecord) apply(records *Records) {
+	if r.Deleted {
+		delete(records.Users, r.ID)
+		return
+	}
+	records.Users[r.ID] = models.User{
+		CreatedAt:    r.CreatedAt,
+		ID:           r.ID,
+		Login:        r.Login,
+		PasswordHash: r.PasswordHash,
+	}
+}
+
+// apiKeyRecord строка файла с ключом API. Сам ключ не сохраняется, только его хеш.
+type apiKeyRecord struct {
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Prefix    string    `json:"prefix,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+	Scope     string    `json:"scope,omitempty"`
+	// Deleted отметка об отзыве ключа, при чтении файла ключ удаляется.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func newAPIKeyRecord(key models.APIKey) *apiKeyRecord {
+	return &apiKeyRecord{
+		Kind:      kindAPIKey,
+		CreatedAt: key.CreatedAt,
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Scope:     key.Scope,
+	}
+}
+
+func (r *apiKeyRecord) apply(records *Records) {
+	if r.Deleted {
+		delete(records.APIKeys, r.ID)
+		return
+	}
+	records.APIKeys[r.ID] = models.APIKey{
+		CreatedAt: r.CreatedAt,
+		ID:        r.ID,
+		UserID:    r.UserID,
+		Name:      r.Name,
+		Prefix:    r.Prefix,
+		Hash:      r.Hash,
+		Scope:     r.Scope,
+	}
+}
+
+// webhookRecord строка файла с подпиской на события.
+type webhookRecord struct {
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Secret    string    `json:"secret,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Events    []string  `json:"events,omitempty"`
+	Failures  int       `json:"failures,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	// Deleted отметка об удалении подписки, при чтении файла удаляется и журнал ее доставок.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func newWebhookRecord(hook models.Webhook) *webhookRecord {
+	return &webhookRecord{
+		Kind:      kindWebhook,
+		CreatedAt: hook.CreatedAt,
+		ID:        hook.ID,
+		UserID:    hook.UserID,
+		URL:       hook.URL,
+		Secret:    hook.Secret,
+		LastError: hook.LastError,
+		Events:    hook.Events,
+		Failures:  hook.Failures,
+		Disabled:  hook.Disabled,
+	}
+}
+
+func (r *webhookRecord) apply(records *Records) {
+	if r.Deleted {
+		delete(records.Webhooks, r.ID)
+		delete(records.Deliveries, r.ID)
+		return
+	}
+	records.Webhooks[r.ID] = models.Webhook{
+		CreatedAt: r.CreatedAt,
+		ID:        r.ID,
+		UserID:    r.UserID,
+		URL:       r.URL,
+		Secret:    r.Secret,
+		LastError: r.LastError,
+		Events:    r.Events,
+		Failures:  r.Failures,
+		Disabled:  r.Disabled,
+	}
+}
+
+// webhookStateRecord строка файла с состоянием доставки подписки,
+// меняет те же поля, что и UpdateWebhook.
+type webhookStateRecord struct {
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	LastError string `json:"last_error,omitempty"`
+	Failures  int    `json:"failures,omitempty"`
+	Disabled  bool   `json:"disabled,omitempty"`
+}
+
+func newWebhookStateRecord(hook models.Webhook) *webhookStateRecord {
+	return &webhookStateRecord{
+		Kind:      kindWebhookState,
+		ID:        hook.ID,
+		LastError: hook.LastError,
+		Failures:  hook.Failures,
+		Disabled:  hook.Disabled,
+	}
+}
+
+func (r *webhookStateRecord) apply(records *Records) {
+	hook, ok := records.Webhooks[r.ID]
+	if !ok {
+		return
+	}
+	hook.LastError = r.LastError
+	hook.Failures = r.Failures
+	hook.Disabled = r.Disabled
+	records.Webhooks[r.ID] = hook
+}
+
+// webhookDeliveryRecord строка файла с попыткой доставки события.
+type webhookDeliveryRecord struct {
+	Kind string `json:"kind"`
+	models.WebhookDelivery
+}
+
+func (r *webhookDeliveryRecord) apply(records *Records) {
+	if _, ok := records.Webhooks[r.WebhookID]; !ok {
+		return
+	}
+	records.Deliveries[r.WebhookID] = append(records.Deliveries[r.WebhookID], r.WebhookDelivery)
+}
+
+// clickRecord строка файла со счетчиками переходов по ссылке. Более поздняя строка
+// заменяет счетчики целиком. Строки без clicks записаны прежними версиями по одной
+// на переход и увеличивают счетчики на единицу.
+type clickRecord struct {
+	Kind     string           `json:"kind"`
+	ShortURL string           `json:"short_url"`
+	Variant  string           `json:"variant,omitempty"`
+	Variants map[string]int64 `json:"variants,omitempty"`
+	Clicks   int64            `json:"clicks,omitempty"`
+}
+
+func newClickRecord(id string, stats *models.ClickStats) *clickRecord {
+	return &clickRecord{
+		Kind:     kindClick,
+		ShortURL: id,
+		Clicks:   stats.Clicks,
+		Variants: stats.Variants,
+	}
+}
+
+func (r *clickRecord) apply(records *Records) {
+	if r.Clicks > 0 {
+		stats := &models.ClickStats{Clicks: r.Clicks, Variants: make(map[string]int64)}
+		for variant, clicks := range r.Variants {
+			stats.Variants[variant] = clicks
+		}
+		records.Clicks[r.ShortURL] = stats
+		return
+	}
+
+	stats, ok := records.Clicks[r.ShortURL]
+	if !ok {
+		stats = &models.ClickStats{Variants: make(map[string]int64)}
+		records.Clicks[r.ShortURL] = stats
+	}
+	stats.Clicks++
+	if r.Variant != "" {
+		stats.Variants[r.Variant]++
+	}
+}
@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/rawen554/shortener/internal/models"
 	"github.com/rawen554/shortener/internal/store/memory"
@@ -15,11 +18,24 @@ import (
 
 const FileStorageFilePerm = 0600
 
+// clicksFlushInterval как часто счетчики переходов сбрасываются в файл.
+const clicksFlushInterval = 10 * time.Second
+
+// FSStorage хранилище с сохранением записей в файл.
+// Результаты проверки доступности хранятся только в памяти и в файл не записываются.
+// Счетчики переходов копятся в памяти и дописываются в файл периодически и при закрытии,
+// поэтому при аварийной остановке теряются переходы за последний интервал.
 type FSStorage struct {
 	*memory.MemoryStorage
 	sr   *StorageReader
 	sw   *StorageWriter
 	path string
+	// clicked ссылки, счетчики которых изменились после последнего сброса в файл.
+	clicked   map[string]struct{}
+	clicksMux sync.Mutex
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
 }
 
 func NewFileStorage(filename string) (*FSStorage, error) {
@@ -33,29 +49,60 @@ func NewFileStorage(filename string) (*FSStorage, error) {
 		return nil, err
 	}
 
-	storage, err := memory.NewMemoryStorage(records)
+	storage, err := memory.NewMemoryStorage(records.URLs)
 	if err != nil {
 		return nil, fmt.Errorf("error initialising memory storage with records: %w", err)
 	}
+	for _, user := range records.Users {
+		if err := storage.CreateUser(user); err != nil {
+			return nil, fmt.Errorf("error restoring user: %w", err)
+		}
+	}
+	for _, key := range records.APIKeys {
+		if err := storage.PutAPIKey(key); err != nil {
+			return nil, fmt.Errorf("error restoring api key: %w", err)
+		}
+	}
+	for id, stats := range records.Clicks {
+		storage.SetClicks(id, stats)
+	}
+	for _, hook := range records.Webhooks {
+		if err := storage.PutWebhook(hook); err != nil {
+			return nil, fmt.Errorf("error restoring webhook: %w", err)
+		}
+	}
+	for _, deliveries := range records.Deliveries {
+		for _, delivery := range deliveries {
+			if err := storage.PutWebhookDelivery(delivery); err != nil {
+				return nil, fmt.Errorf("error restoring webhook delivery: %w", err)
+			}
+		}
+	}
 
 	sw, err := NewStorageWriter(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	return &FSStorage{
+	s := &FSStorage{
 		path:          filename,
 		MemoryStorage: storage,
 		sr:            sr,
 		sw:            sw,
-	}, nil
+		clicked:       make(map[string]struct{}),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go s.flushClicksLoop(clicksFlushInterval)
+
+	return s, nil
 }
 
 func (s *FSStorage) PutBatch(urls []models.URLBatchReq, userID string) ([]models.URLBatchRes, error) {
 	result := make([]models.URLBatchRes, 0)
 
 	for _, url := range urls {
-		id, err := s.Put(url.CorrelationID, url.OriginalURL, userID)
+		id, err := s.Put(url.CorrelationID, url.OriginalURL, userID, url.LinkOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -68,14 +115,23 @@ func (s *FSStorage) PutBatch(urls []models.URLBatchReq, userID string) ([]models
 	return result, nil
 }
 
-func (s *FSStorage) Ping() error {
+func (s *FSStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
+// Close сбрасывает в файл накопленные счетчики переходов и закрывает файл.
 func (s *FSStorage) Close() {
-	if err := s.sw.file.Close(); err != nil {
-		log.Printf("error closing file: %v", err)
-	}
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+
+		if err := s.flushClicks(); err != nil {
+			log.Printf("error flushing clicks: %v", err)
+		}
+		if err := s.sw.file.Close(); err != nil {
+			log.Printf("error closing file: %v", err)
+		}
+	})
 }
 
 func (s *FSStorage) DeleteStorageFile() error {
@@ -102,33 +158,107 @@ func NewStorageReader(filename string) (*StorageReader, error) {
 	}, nil
 }
 
-func (sr *StorageReader) ReadFromFile() (map[string]models.URLRecordMemory, error) {
-	records := make(map[string]models.URLRecordMemory)
+func (sr *StorageReader) ReadFromFile() (*Records, error) {
+	records := newRecords()
 	for {
-		r, err := sr.ReadLine()
+		line, err := sr.ReadLine()
 		if errors.Is(err, io.EOF) {
 			break
 		} else if err != nil {
 			return nil, err
 		}
-		records[r.ShortURL] = models.URLRecordMemory{OriginalURL: r.OriginalURL, UserID: r.UserID}
+		if err := applyLine(records, line); err != nil {
+			return nil, err
+		}
 	}
 
 	return records, nil
 }
 
-func (sr *StorageReader) ReadLine() (*models.URLRecordFS, error) {
-	r := models.URLRecordFS{}
-	if err := sr.decoder.Decode(&r); err != nil {
+// ReadLine читает очередную строку файла без разбора ее содержимого.
+func (sr *StorageReader) ReadLine() (json.RawMessage, error) {
+	var line json.RawMessage
+	if err := sr.decoder.Decode(&line); err != nil {
 		return nil, fmt.Errorf("error decode records: %w", err)
 	}
 
-	return &r, nil
+	return line, nil
+}
+
+// applyLine применяет строку файла к восстанавливаемому состоянию.
+func applyLine(records *Records, line json.RawMessage) error {
+	var header recordHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return fmt.Errorf("error decode record kind: %w", err)
+	}
+
+	switch header.Kind {
+	case "":
+		r := models.URLRecordFS{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode url record: %w", err)
+		}
+		// отметки удаления без адреса назначения записывались при удалении данных пользователя
+		if r.Purged || (r.Deleted && r.OriginalURL == "") {
+			delete(records.URLs, r.ShortURL)
+			delete(records.Clicks, r.ShortURL)
+			return nil
+		}
+		records.URLs[r.ShortURL] = models.URLRecordMemory{
+			CreatedAt:   r.CreatedAt,
+			LinkOptions: r.LinkOptions,
+			OriginalURL: r.OriginalURL,
+			UserID:      r.UserID,
+			Disabled:    r.Disabled,
+			Deleted:     r.Deleted,
+		}
+	case kindUser:
+		r := userRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode user record: %w", err)
+		}
+		r.apply(records)
+	case kindAPIKey:
+		r := apiKeyRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode api key record: %w", err)
+		}
+		r.apply(records)
+	case kindWebhook:
+		r := webhookRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode webhook record: %w", err)
+		}
+		r.apply(records)
+	case kindWebhookState:
+		r := webhookStateRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode webhook state record: %w", err)
+		}
+		r.apply(records)
+	case kindWebhookDelivery:
+		r := webhookDeliveryRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode webhook delivery record: %w", err)
+		}
+		r.apply(records)
+	case kindClick:
+		r := clickRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("error decode click record: %w", err)
+		}
+		r.apply(records)
+	default:
+		return fmt.Errorf("unknown record kind %q", header.Kind)
+	}
+
+	return nil
 }
 
 type StorageWriter struct {
 	file    *os.File
 	encoder *json.Encoder
+	mux     sync.Mutex
 }
 
 func NewStorageWriter(filename string) (*StorageWriter, error) {
@@ -143,22 +273,153 @@ func NewStorageWriter(filename string) (*StorageWriter, error) {
 	}, nil
 }
 
-func (sw *StorageWriter) AppendToFile(r *models.URLRecordFS) error {
-	if err := sw.encoder.Encode(&r); err != nil {
+// AppendToFile дописывает в файл строку: запись ссылки models.URLRecordFS
+// или запись другого вида с полем kind.
+func (sw *StorageWriter) AppendToFile(r any) error {
+	sw.mux.Lock()
+	defer sw.mux.Unlock()
+
+	if err := sw.encoder.Encode(r); err != nil {
 		return fmt.Errorf("error encode records: %w", err)
 	}
 	return nil
 }
 
-func (s *FSStorage) Put(id string, url string, userID string) (string, error) {
-	id, err := s.MemoryStorage.Put(id, url, userID)
+func (s *FSStorage) Put(id string, url string, userID string, opts models.LinkOptions) (string, error) {
+	id, err := s.MemoryStorage.Put(id, url, userID, opts)
 	if err != nil {
 		return "", fmt.Errorf("error put file: %w", err)
 	}
-	return id,
-		s.sw.AppendToFile(&models.URLRecordFS{UUID: strconv.Itoa(s.UrlsCount), UserID: userID, URLRecord: models.URLRecord{
-			OriginalURL: url, ShortURL: id,
-		}})
+	return id, s.appendLink(id)
+}
+
+func (s *FSStorage) SetRules(id string, rules []models.RedirectRule) error {
+	if err := s.MemoryStorage.SetRules(id, rules); err != nil {
+		return fmt.Errorf("error set rules: %w", err)
+	}
+
+	return s.appendLink(id)
+}
+
+// appendLink дописывает актуальное состояние записи в файл.
+// При чтении файла более поздняя запись с тем же slug заменяет предыдущую.
+func (s *FSStorage) appendLink(id string) error {
+	record, ok := s.MemoryStorage.Record(id)
+	if !ok {
+		return fmt.Errorf("url %s not found", id)
+	}
+
+	return s.sw.AppendToFile(&models.URLRecordFS{
+		CreatedAt:   record.CreatedAt,
+		UUID:        strconv.Itoa(s.UrlsCount),
+		UserID:      record.UserID,
+		Disabled:    record.Disabled,
+		Deleted:     record.Deleted,
+		LinkOptions: record.LinkOptions,
+		URLRecord: models.URLRecord{
+			OriginalURL: record.OriginalURL, ShortURL: id,
+		},
+	})
+}
+
+// DeleteMany отмечает ссылки удаленными и дописывает их состояние в файл.
+func (s *FSStorage) DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error) {
+	deleted, err := s.MemoryStorage.DeleteMany(ids, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error delete links: %w", err)
+	}
+
+	for _, id := range deleted {
+		if err := s.appendLink(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *FSStorage) SetDisabled(id string, disabled bool) error {
+	if err := s.MemoryStorage.SetDisabled(id, disabled); err != nil {
+		return fmt.Errorf("error set disabled: %w", err)
+	}
+
+	return s.appendLink(id)
+}
+
+func (s *FSStorage) TransferLinks(fromUserID string, toUserID string) (int, error) {
+	links, err := s.MemoryStorage.SearchLinks(models.LinkFilter{UserID: fromUserID})
+	if err != nil {
+		return 0, fmt.Errorf("error search links: %w", err)
+	}
+
+	count, err := s.MemoryStorage.TransferLinks(fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("error transfer links: %w", err)
+	}
+
+	for _, link := range links {
+		if err := s.appendLink(link.ShortURL); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+func (s *FSStorage) PurgeUser(userID string) (int, error) {
+	links, err := s.MemoryStorage.ListByUserID(
+		context.Background(), userID, models.UserLinksQuery{Deleted: models.DeletedInclude},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error list links: %w", err)
+	}
+	keys, err := s.MemoryStorage.GetAPIKeys(userID)
+	if err != nil {
+		return 0, fmt.Errorf("error get api keys: %w", err)
+	}
+	hooks, err := s.MemoryStorage.GetWebhooks(userID)
+	if err != nil {
+		return 0, fmt.Errorf("error get webhooks: %w", err)
+	}
+
+	count, err := s.MemoryStorage.PurgeUser(userID)
+	if err != nil {
+		return 0, fmt.Errorf("error purge user: %w", err)
+	}
+
+	for _, link := range links {
+		if err := s.sw.AppendToFile(&models.URLRecordFS{
+			URLRecord: models.URLRecord{ShortURL: link.ShortURL},
+			Purged:    true,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, key := range keys {
+		if err := s.sw.AppendToFile(&apiKeyRecord{Kind: kindAPIKey, ID: key.ID, Deleted: true}); err != nil {
+			return 0, err
+		}
+	}
+	for _, hook := range hooks {
+		if err := s.sw.AppendToFile(&webhookRecord{Kind: kindWebhook, ID: hook.ID, Deleted: true}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.sw.AppendToFile(&userRecord{Kind: kindUser, ID: userID, Deleted: true}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *FSStorage) CreateUser(user models.User) error {
+	if err := s.MemoryStorage.CreateUser(user); err != nil {
+		return fmt.Errorf("error create user: %w", err)
+	}
+
+	return s.sw.AppendToFile(newUserRecord(user))
 }
 
 func (s *FSStorage) GetStats() (stats *models.Stats, err error) {
@@ -169,3 +430,121 @@ func (s *FSStorage) GetStats() (stats *models.Stats, err error) {
 
 	return stats, nil
 }
+
+func (s *FSStorage) PutAPIKey(key models.APIKey) error {
+	if err := s.MemoryStorage.PutAPIKey(key); err != nil {
+		return fmt.Errorf("error put api key: %w", err)
+	}
+
+	return s.sw.AppendToFile(newAPIKeyRecord(key))
+}
+
+func (s *FSStorage) DeleteAPIKey(id string) error {
+	if err := s.MemoryStorage.DeleteAPIKey(id); err != nil {
+		return fmt.Errorf("error delete api key: %w", err)
+	}
+
+	return s.sw.AppendToFile(&apiKeyRecord{Kind: kindAPIKey, ID: id, Deleted: true})
+}
+
+func (s *FSStorage) PutWebhook(hook models.Webhook) error {
+	if err := s.MemoryStorage.PutWebhook(hook); err != nil {
+		return fmt.Errorf("error put webhook: %w", err)
+	}
+
+	return s.sw.AppendToFile(newWebhookRecord(hook))
+}
+
+func (s *FSStorage) UpdateWebhook(hook models.Webhook) error {
+	if err := s.MemoryStorage.UpdateWebhook(hook); err != nil {
+		return fmt.Errorf("error update webhook: %w", err)
+	}
+
+	return s.sw.AppendToFile(newWebhookStateRecord(hook))
+}
+
+func (s *FSStorage) DeleteWebhook(id string) error {
+	if err := s.MemoryStorage.DeleteWebhook(id); err != nil {
+		return fmt.Errorf("error delete webhook: %w", err)
+	}
+
+	return s.sw.AppendToFile(&webhookRecord{Kind: kindWebhook, ID: id, Deleted: true})
+}
+
+// PutWebhookDelivery дописывает доставку в файл. Журнал в файле не ограничен,
+// при чтении в памяти остаются последние доставки, как и при работе без файла.
+func (s *FSStorage) PutWebhookDelivery(delivery models.WebhookDelivery) error {
+	if err := s.MemoryStorage.PutWebhookDelivery(delivery); err != nil {
+		return fmt.Errorf("error put webhook delivery: %w", err)
+	}
+
+	return s.sw.AppendToFile(&webhookDeliveryRecord{Kind: kindWebhookDelivery, WebhookDelivery: delivery})
+}
+
+// RecordClick учитывает переход в памяти, в файл счетчики дописывает flushClicks.
+func (s *FSStorage) RecordClick(id string, variant string) error {
+	if err := s.MemoryStorage.RecordClick(id, variant); err != nil {
+		return fmt.Errorf("error record click: %w", err)
+	}
+
+	s.clicksMux.Lock()
+	s.clicked[id] = struct{}{}
+	s.clicksMux.Unlock()
+
+	return nil
+}
+
+func (s *FSStorage) flushClicksLoop(interval time.Duration) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.flushClicks(); err != nil {
+				log.Printf("error flushing clicks: %v", err)
+			}
+		}
+	}
+}
+
+// flushClicks дописывает в файл по одной строке с текущими счетчиками
+// для каждой ссылки, по которой были переходы после прошлого сброса.
+// При ошибке ссылки остаются отмеченными до следующего сброса.
+func (s *FSStorage) flushClicks() (err error) {
+	s.clicksMux.Lock()
+	clicked := s.clicked
+	s.clicked = make(map[string]struct{})
+	s.clicksMux.Unlock()
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		s.clicksMux.Lock()
+		for id := range clicked {
+			s.clicked[id] = struct{}{}
+		}
+		s.clicksMux.Unlock()
+	}()
+
+	for id := range clicked {
+		// счетчики ссылок, удаленных вместе с данными пользователя, не сохраняются
+		if _, ok := s.MemoryStorage.Record(id); !ok {
+			continue
+		}
+		var stats *models.ClickStats
+		if stats, err = s.MemoryStorage.GetClicks(id); err != nil {
+			return fmt.Errorf("error get clicks: %w", err)
+		}
+		if err = s.sw.AppendToFile(newClickRecord(id, stats)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
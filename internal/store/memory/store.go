@@ -1,82 +1,387 @@
 package memory
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/rawen554/shortener/internal/models"
 )
 
 type MemoryStorage struct {
-	mux       *sync.Mutex
-	urls      map[string]models.URLRecordMemory
+	mux        *sync.Mutex
+	urls       map[string]models.URLRecordMemory
+	clicks     map[string]*models.ClickStats
+	health     map[string]models.LinkHealth
+	webhooks   map[string]models.Webhook
+	deliveries map[string][]models.WebhookDelivery
+	apiKeys    map[string]models.APIKey
+	users      map[string]models.User
+	// byUser индекс ссылок пользователей: slug в порядке возрастания даты создания.
+	byUser    map[string][]string
 	UrlsCount int
 }
 
+// maxDeliveries сколько последних доставок хранится для каждой подписки.
+const maxDeliveries = 100
+
 func NewMemoryStorage(records map[string]models.URLRecordMemory) (*MemoryStorage, error) {
-	return &MemoryStorage{
-		mux:       &sync.Mutex{},
-		urls:      records,
-		UrlsCount: len(records),
-	}, nil
+	s := &MemoryStorage{
+		mux:        &sync.Mutex{},
+		urls:       records,
+		clicks:     make(map[string]*models.ClickStats),
+		health:     make(map[string]models.LinkHealth),
+		webhooks:   make(map[string]models.Webhook),
+		deliveries: make(map[string][]models.WebhookDelivery),
+		apiKeys:    make(map[string]models.APIKey),
+		users:      make(map[string]models.User),
+		byUser:     make(map[string][]string),
+	}
+	for id, record := range records {
+		s.byUser[record.UserID] = append(s.byUser[record.UserID], id)
+		if !record.Deleted {
+			s.UrlsCount++
+		}
+	}
+	for userID := range s.byUser {
+		s.sortIndex(userID)
+	}
+
+	return s, nil
 }
 
-func (s *MemoryStorage) Put(id string, url string, userID string) (string, error) {
+func (s *MemoryStorage) Put(id string, url string, userID string, opts models.LinkOptions) (string, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
+	if current, ok := s.urls[id]; ok {
+		s.removeFromIndex(current.UserID, id)
+	}
 	s.urls[id] = models.URLRecordMemory{
+		CreatedAt:   time.Now(),
+		LinkOptions: opts,
 		OriginalURL: url,
 		UserID:      userID,
 	}
+	s.addToIndex(userID, id)
 	s.UrlsCount++
 	return id, nil
 }
 
-func (s *MemoryStorage) Get(id string) (string, error) {
+func (s *MemoryStorage) Get(id string) (*models.Link, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	originalURL := s.urls[id]
+	record, ok := s.urls[id]
+	if !ok {
+		return nil, nil
+	}
+	if record.Deleted {
+		return nil, models.ErrLinkDeleted
+	}
+
+	return &models.Link{
+		CreatedAt:   record.CreatedAt,
+		LinkOptions: record.LinkOptions,
+		ShortURL:    id,
+		OriginalURL: record.OriginalURL,
+		UserID:      record.UserID,
+		Disabled:    record.Disabled,
+	}, nil
+}
+
+// Record возвращает запись ссылки, в том числе удаленной владельцем.
+func (s *MemoryStorage) Record(id string) (models.URLRecordMemory, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	return originalURL.OriginalURL, nil
+	record, ok := s.urls[id]
+	return record, ok
 }
 
 func (s *MemoryStorage) GetAllByUserID(userID string) ([]models.URLRecord, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	result := make([]models.URLRecord, 0)
 
 	for id, url := range s.urls {
-		if url.UserID == userID {
-			s.mux.Lock()
-			defer s.mux.Unlock()
-
-			result = append(result, models.URLRecord{
+		if url.UserID == userID && !url.Deleted {
+			record := models.URLRecord{
 				ShortURL:    id,
 				OriginalURL: url.OriginalURL,
-			})
+			}
+			if health, ok := s.health[id]; ok {
+				record.Health = &health
+			}
+			result = append(result, record)
+		}
+	}
+
+	return result, nil
+}
+
+// IterateByUserID вызывает fn для ссылок пользователя в порядке возрастания идентификатора,
+// начиная со следующей после after. Ошибка fn прекращает обход и возвращается вызывающему.
+func (s *MemoryStorage) IterateByUserID(
+	ctx context.Context,
+	userID string,
+	after string,
+	fn func(models.URLRecord) error,
+) error {
+	s.mux.Lock()
+	records := make([]models.URLRecord, 0)
+	for id, url := range s.urls {
+		if url.UserID != userID || url.Deleted || id <= after {
+			continue
+		}
+		record := models.URLRecord{ShortURL: id, OriginalURL: url.OriginalURL}
+		if health, ok := s.health[id]; ok {
+			record.Health = &health
+		}
+		records = append(records, record)
+	}
+	s.mux.Unlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ShortURL < records[j].ShortURL })
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListByUserID возвращает ссылки пользователя, отобранные и упорядоченные по query.
+// При сортировке по дате создания ссылки читаются из индекса пользователя начиная с позиции
+// курсора и обход заканчивается, как только набрано query.Limit ссылок. При сортировке по числу
+// переходов, которое меняется при каждом переходе, ссылки пользователя сортируются при запросе.
+func (s *MemoryStorage) ListByUserID(
+	ctx context.Context,
+	userID string,
+	query models.UserLinksQuery,
+) ([]models.UserLink, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	result := make([]models.UserLink, 0)
+	ids := s.byUser[userID]
+	full := func() bool { return query.Limit > 0 && len(result) >= query.Limit }
+
+	if query.Sort == models.SortByClicks {
+		for _, id := range ids {
+			if s.matches(id, query) {
+				result = append(result, s.userLink(id))
+			}
+		}
+		sort.Slice(result, func(i, j int) bool {
+			a, b := linkKey(result[i]), linkKey(result[j])
+			if query.Desc {
+				a, b = b, a
+			}
+			return compareKeys(models.SortByClicks, a, b) < 0
+		})
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page := make([]models.UserLink, 0)
+		for _, link := range result {
+			if query.Limit > 0 && len(page) >= query.Limit {
+				break
+			}
+			if isAfter(models.SortByClicks, query, linkKey(link)) {
+				page = append(page, link)
+			}
+		}
+		return page, nil
+	}
+
+	// позиция первой ссылки после курсора в порядке возрастания
+	start := 0
+	if query.After != nil {
+		start = sort.Search(len(ids), func(i int) bool {
+			return compareKeys(models.SortByCreatedAt, s.indexKey(ids[i]), *query.After) > 0
+		})
+	}
+
+	step := 1
+	if query.Desc {
+		step = -1
+		start = len(ids) - 1
+		if query.After != nil {
+			start = sort.Search(len(ids), func(i int) bool {
+				return compareKeys(models.SortByCreatedAt, s.indexKey(ids[i]), *query.After) >= 0
+			}) - 1
+		}
+	}
+
+	for i := start; i >= 0 && i < len(ids) && !full(); i += step {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if s.matches(ids[i], query) {
+			result = append(result, s.userLink(ids[i]))
 		}
 	}
 
 	return result, nil
 }
 
-func (s *MemoryStorage) DeleteMany(ids models.DeleteUserURLsReq, userID string) error {
+// matches проверяет ссылку на соответствие фильтрам query.
+func (s *MemoryStorage) matches(id string, query models.UserLinksQuery) bool {
+	record := s.urls[id]
+	switch query.Deleted {
+	case models.DeletedExclude:
+		if record.Deleted {
+			return false
+		}
+	case models.DeletedOnly:
+		if !record.Deleted {
+			return false
+		}
+	}
+	if !query.CreatedFrom.IsZero() && record.CreatedAt.Before(query.CreatedFrom) {
+		return false
+	}
+	if !query.CreatedTo.IsZero() && !record.CreatedAt.Before(query.CreatedTo) {
+		return false
+	}
+	if query.URL != "" && !strings.Contains(strings.ToLower(record.OriginalURL), strings.ToLower(query.URL)) {
+		return false
+	}
+	if query.Domain != "" {
+		u, err := url.Parse(record.OriginalURL)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		if host != query.Domain && !strings.HasSuffix(host, "."+query.Domain) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *MemoryStorage) userLink(id string) models.UserLink {
+	record := s.urls[id]
+	link := models.UserLink{
+		CreatedAt:   record.CreatedAt,
+		URLRecord:   models.URLRecord{ShortURL: id, OriginalURL: record.OriginalURL},
+		LinkOptions: record.LinkOptions,
+		Deleted:     record.Deleted,
+	}
+	if health, ok := s.health[id]; ok {
+		link.Health = &health
+	}
+	if stats, ok := s.clicks[id]; ok {
+		link.Clicks = stats.Clicks
+	}
+	return link
+}
+
+func (s *MemoryStorage) indexKey(id string) models.LinkCursor {
+	return models.LinkCursor{CreatedAt: s.urls[id].CreatedAt, Slug: id}
+}
+
+func linkKey(link models.UserLink) models.LinkCursor {
+	return models.LinkCursor{CreatedAt: link.CreatedAt, Slug: link.ShortURL, Clicks: link.Clicks}
+}
+
+// compareKeys сравнивает позиции ссылок по ключу сортировки sortBy, при равенстве — по slug.
+func compareKeys(sortBy string, a models.LinkCursor, b models.LinkCursor) int {
+	switch {
+	case sortBy == models.SortByClicks && a.Clicks != b.Clicks:
+		if a.Clicks < b.Clicks {
+			return -1
+		}
+		return 1
+	case sortBy != models.SortByClicks && !a.CreatedAt.Equal(b.CreatedAt):
+		if a.CreatedAt.Before(b.CreatedAt) {
+			return -1
+		}
+		return 1
+	default:
+		return strings.Compare(a.Slug, b.Slug)
+	}
+}
+
+// isAfter проверяет, что позиция key идет после курсора query в порядке выдачи.
+func isAfter(sortBy string, query models.UserLinksQuery, key models.LinkCursor) bool {
+	if query.After == nil {
+		return true
+	}
+	if query.Desc {
+		return compareKeys(sortBy, key, *query.After) < 0
+	}
+	return compareKeys(sortBy, key, *query.After) > 0
+}
+
+// addToIndex добавляет ссылку в индекс пользователя. Новые ссылки обычно создаются позже
+// существующих, поэтому в большинстве случаев ссылка дописывается в конец.
+func (s *MemoryStorage) addToIndex(userID string, id string) {
+	ids := s.byUser[userID]
+	key := s.indexKey(id)
+	pos := sort.Search(len(ids), func(i int) bool {
+		return compareKeys(models.SortByCreatedAt, s.indexKey(ids[i]), key) > 0
+	})
+	ids = append(ids, "")
+	copy(ids[pos+1:], ids[pos:])
+	ids[pos] = id
+	s.byUser[userID] = ids
+}
+
+// removeFromIndex удаляет ссылку из индекса пользователя, запись ссылки еще должна быть в urls.
+func (s *MemoryStorage) removeFromIndex(userID string, id string) {
+	ids := s.byUser[userID]
+	key := s.indexKey(id)
+	pos := sort.Search(len(ids), func(i int) bool {
+		return compareKeys(models.SortByCreatedAt, s.indexKey(ids[i]), key) >= 0
+	})
+	if pos < len(ids) && ids[pos] == id {
+		s.byUser[userID] = append(ids[:pos], ids[pos+1:]...)
+	}
+}
+
+func (s *MemoryStorage) sortIndex(userID string) {
+	ids := s.byUser[userID]
+	sort.Slice(ids, func(i, j int) bool {
+		return compareKeys(models.SortByCreatedAt, s.indexKey(ids[i]), s.indexKey(ids[j])) < 0
+	})
+}
+
+// DeleteMany отмечает ссылки пользователя удаленными и возвращает их идентификаторы.
+// Чужие, несуществующие и уже удаленные идентификаторы пропускаются. Удаленные ссылки
+// остаются в индексе пользователя и выдаются ListByUserID по запросу.
+func (s *MemoryStorage) DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
+	deleted := make(models.DeleteUserURLsReq, 0, len(ids))
 	for _, id := range ids {
-		if url, ok := s.urls[id]; ok && url.UserID == userID {
-			delete(s.urls, id)
+		if url, ok := s.urls[id]; ok && url.UserID == userID && !url.Deleted {
+			url.Deleted = true
+			s.urls[id] = url
 			s.UrlsCount--
+			deleted = append(deleted, id)
 		}
 	}
 
-	return nil
+	return deleted, nil
 }
 
 func (s *MemoryStorage) PutBatch(urls []models.URLBatchReq, userID string) ([]models.URLBatchRes, error) {
 	result := make([]models.URLBatchRes, 0)
 
 	for _, url := range urls {
-		id, err := s.Put(url.CorrelationID, url.OriginalURL, userID)
+		id, err := s.Put(url.CorrelationID, url.OriginalURL, userID, url.LinkOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -89,7 +394,416 @@ func (s *MemoryStorage) PutBatch(urls []models.URLBatchReq, userID string) ([]mo
 	return result, nil
 }
 
-func (s *MemoryStorage) Ping() error {
+func (s *MemoryStorage) SetRules(id string, rules []models.RedirectRule) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	record, ok := s.urls[id]
+	if !ok || record.Deleted {
+		return fmt.Errorf("url %s not found", id)
+	}
+	record.Rules = rules
+	s.urls[id] = record
+
+	return nil
+}
+
+func (s *MemoryStorage) RecordClick(id string, variant string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	stats, ok := s.clicks[id]
+	if !ok {
+		stats = &models.ClickStats{Variants: make(map[string]int64)}
+		s.clicks[id] = stats
+	}
+	stats.Clicks++
+	if variant != "" {
+		stats.Variants[variant]++
+	}
+
+	return nil
+}
+
+// SetClicks заменяет счетчики переходов по ссылке, например при восстановлении из файла.
+func (s *MemoryStorage) SetClicks(id string, stats *models.ClickStats) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.clicks[id] = stats
+}
+
+func (s *MemoryStorage) GetClicks(id string) (*models.ClickStats, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	result := &models.ClickStats{Variants: make(map[string]int64)}
+	if stats, ok := s.clicks[id]; ok {
+		result.Clicks = stats.Clicks
+		for variant, clicks := range stats.Variants {
+			result.Variants[variant] = clicks
+		}
+	}
+
+	return result, nil
+}
+
+func (s *MemoryStorage) GetLinksToCheck(checkedBefore time.Time, limit int) ([]models.Link, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	result := make([]models.Link, 0)
+	for id, record := range s.urls {
+		if record.Deleted {
+			continue
+		}
+		if health, ok := s.health[id]; ok && !health.CheckedAt.Before(checkedBefore) {
+			continue
+		}
+		result = append(result, models.Link{
+			CreatedAt:   record.CreatedAt,
+			LinkOptions: record.LinkOptions,
+			ShortURL:    id,
+			OriginalURL: record.OriginalURL,
+			UserID:      record.UserID,
+		})
+	}
+
+	// в первую очередь проверяются ссылки, которые дольше всего не проверялись
+	sort.Slice(result, func(i, j int) bool {
+		return s.health[result[i].ShortURL].CheckedAt.Before(s.health[result[j].ShortURL].CheckedAt)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (s *MemoryStorage) SetHealth(id string, health models.LinkHealth) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.urls[id]; !ok {
+		return fmt.Errorf("url %s not found", id)
+	}
+	s.health[id] = health
+
+	return nil
+}
+
+func (s *MemoryStorage) PutWebhook(hook models.Webhook) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.webhooks[hook.ID] = hook
+
+	return nil
+}
+
+func (s *MemoryStorage) GetWebhooks(userID string) ([]models.Webhook, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	result := make([]models.Webhook, 0)
+	for _, hook := range s.webhooks {
+		if hook.UserID == userID {
+			result = append(result, hook)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+func (s *MemoryStorage) UpdateWebhook(hook models.Webhook) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	current, ok := s.webhooks[hook.ID]
+	if !ok {
+		return fmt.Errorf("webhook %s not found", hook.ID)
+	}
+	current.Failures = hook.Failures
+	current.LastError = hook.LastError
+	current.Disabled = hook.Disabled
+	s.webhooks[hook.ID] = current
+
+	return nil
+}
+
+func (s *MemoryStorage) DeleteWebhook(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+
+	return nil
+}
+
+func (s *MemoryStorage) PutWebhookDelivery(delivery models.WebhookDelivery) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.webhooks[delivery.WebhookID]; !ok {
+		return nil
+	}
+
+	deliveries := append(s.deliveries[delivery.WebhookID], delivery)
+	if len(deliveries) > maxDeliveries {
+		deliveries = deliveries[len(deliveries)-maxDeliveries:]
+	}
+	s.deliveries[delivery.WebhookID] = deliveries
+
+	return nil
+}
+
+func (s *MemoryStorage) GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	deliveries := s.deliveries[webhookID]
+	result := make([]models.WebhookDelivery, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, deliveries[i])
+	}
+
+	return result, nil
+}
+
+func (s *MemoryStorage) SearchLinks(filter models.LinkFilter) ([]models.Link, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	slug := strings.ToLower(filter.Slug)
+	url := strings.ToLower(filter.URL)
+
+	result := make([]models.Link, 0)
+	for id, record := range s.urls {
+		if record.Deleted {
+			continue
+		}
+		if filter.UserID != "" && record.UserID != filter.UserID {
+			continue
+		}
+		if slug != "" && !strings.Contains(strings.ToLower(id), slug) {
+			continue
+		}
+		if url != "" && !strings.Contains(strings.ToLower(record.OriginalURL), url) {
+			continue
+		}
+		result = append(result, models.Link{
+			CreatedAt:   record.CreatedAt,
+			LinkOptions: record.LinkOptions,
+			ShortURL:    id,
+			OriginalURL: record.OriginalURL,
+			UserID:      record.UserID,
+			Disabled:    record.Disabled,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ShortURL < result[j].ShortURL
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	if filter.Offset >= len(result) {
+		return []models.Link{}, nil
+	}
+	result = result[filter.Offset:]
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+
+	return result, nil
+}
+
+func (s *MemoryStorage) SetDisabled(id string, disabled bool) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	record, ok := s.urls[id]
+	if !ok {
+		return fmt.Errorf("url %s not found", id)
+	}
+	record.Disabled = disabled
+	s.urls[id] = record
+
+	return nil
+}
+
+func (s *MemoryStorage) TransferLinks(fromUserID string, toUserID string) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if fromUserID == toUserID {
+		count := 0
+		for _, id := range s.byUser[fromUserID] {
+			if !s.urls[id].Deleted {
+				count++
+			}
+		}
+		return count, nil
+	}
+
+	// удаленные ссылки остаются у прежнего владельца
+	kept := make([]string, 0)
+	for _, id := range s.byUser[fromUserID] {
+		record := s.urls[id]
+		if record.Deleted {
+			kept = append(kept, id)
+			continue
+		}
+		record.UserID = toUserID
+		s.urls[id] = record
+		s.byUser[toUserID] = append(s.byUser[toUserID], id)
+	}
+	count := len(s.byUser[fromUserID]) - len(kept)
+
+	if len(kept) > 0 {
+		s.byUser[fromUserID] = kept
+	} else {
+		delete(s.byUser, fromUserID)
+	}
+	if count > 0 {
+		s.sortIndex(toUserID)
+	}
+
+	return count, nil
+}
+
+func (s *MemoryStorage) PurgeUser(userID string) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	count := 0
+	for id, record := range s.urls {
+		if record.UserID == userID {
+			delete(s.urls, id)
+			delete(s.clicks, id)
+			delete(s.health, id)
+			if !record.Deleted {
+				s.UrlsCount--
+			}
+			count++
+		}
+	}
+
+	for id, hook := range s.webhooks {
+		if hook.UserID == userID {
+			delete(s.webhooks, id)
+			delete(s.deliveries, id)
+		}
+	}
+
+	for id, key := range s.apiKeys {
+		if key.UserID == userID {
+			delete(s.apiKeys, id)
+		}
+	}
+
+	delete(s.users, userID)
+	delete(s.byUser, userID)
+
+	return count, nil
+}
+
+func (s *MemoryStorage) PutAPIKey(key models.APIKey) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.apiKeys[key.ID] = key
+
+	return nil
+}
+
+func (s *MemoryStorage) GetAPIKeys(userID string) ([]models.APIKey, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	result := make([]models.APIKey, 0)
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			result = append(result, key)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+func (s *MemoryStorage) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, key := range s.apiKeys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *MemoryStorage) DeleteAPIKey(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.apiKeys, id)
+
+	return nil
+}
+
+func (s *MemoryStorage) CreateUser(user models.User) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.users[user.ID]; ok {
+		return fmt.Errorf("user id %s is taken: %w", user.ID, models.ErrUserConflict)
+	}
+	for _, u := range s.users {
+		if u.Login == user.Login {
+			return fmt.Errorf("login %s is taken: %w", user.Login, models.ErrUserConflict)
+		}
+	}
+	s.users[user.ID] = user
+
+	return nil
+}
+
+func (s *MemoryStorage) GetUserByLogin(login string) (*models.User, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, u := range s.users {
+		if u.Login == login {
+			return &u, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *MemoryStorage) GetUserByID(id string) (*models.User, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &u, nil
+}
+
+func (s *MemoryStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/rawen554/shortener/internal/models"
@@ -46,25 +48,68 @@ func (mr *MockStoreMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStore)(nil).Close))
 }
 
-// DeleteMany mocks base method.
-func (m *MockStore) DeleteMany(ids models.DeleteUserURLsReq, userID string) error {
+// CreateUser mocks base method.
+func (m *MockStore) CreateUser(user models.User) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMany", ids, userID)
+	ret := m.ctrl.Call(m, "CreateUser", user)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockStoreMockRecorder) CreateUser(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), user)
+}
+
+// DeleteAPIKey mocks base method.
+func (m *MockStore) DeleteAPIKey(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey.
+func (mr *MockStoreMockRecorder) DeleteAPIKey(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockStore)(nil).DeleteAPIKey), id)
+}
+
+// DeleteMany mocks base method.
+func (m *MockStore) DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMany", ids, userID)
+	ret0, _ := ret[0].(models.DeleteUserURLsReq)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // DeleteMany indicates an expected call of DeleteMany.
 func (mr *MockStoreMockRecorder) DeleteMany(ids, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMany", reflect.TypeOf((*MockStore)(nil).DeleteMany), ids, userID)
 }
 
+// DeleteWebhook mocks base method.
+func (m *MockStore) DeleteWebhook(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockStoreMockRecorder) DeleteWebhook(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockStore)(nil).DeleteWebhook), id)
+}
+
 // Get mocks base method.
-func (m *MockStore) Get(id string) (string, error) {
+func (m *MockStore) Get(id string) (*models.Link, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", id)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(*models.Link)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -75,6 +120,36 @@ func (mr *MockStoreMockRecorder) Get(id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStore)(nil).Get), id)
 }
 
+// GetAPIKeyByHash mocks base method.
+func (m *MockStore) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", hash)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockStoreMockRecorder) GetAPIKeyByHash(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockStore)(nil).GetAPIKeyByHash), hash)
+}
+
+// GetAPIKeys mocks base method.
+func (m *MockStore) GetAPIKeys(userID string) ([]models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys", userID)
+	ret0, _ := ret[0].([]models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys.
+func (mr *MockStoreMockRecorder) GetAPIKeys(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockStore)(nil).GetAPIKeys), userID)
+}
+
 // GetAllByUserID mocks base method.
 func (m *MockStore) GetAllByUserID(userID string) ([]models.URLRecord, error) {
 	m.ctrl.T.Helper()
@@ -90,6 +165,36 @@ func (mr *MockStoreMockRecorder) GetAllByUserID(userID interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByUserID", reflect.TypeOf((*MockStore)(nil).GetAllByUserID), userID)
 }
 
+// GetClicks mocks base method.
+func (m *MockStore) GetClicks(id string) (*models.ClickStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClicks", id)
+	ret0, _ := ret[0].(*models.ClickStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClicks indicates an expected call of GetClicks.
+func (mr *MockStoreMockRecorder) GetClicks(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClicks", reflect.TypeOf((*MockStore)(nil).GetClicks), id)
+}
+
+// GetLinksToCheck mocks base method.
+func (m *MockStore) GetLinksToCheck(checkedBefore time.Time, limit int) ([]models.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinksToCheck", checkedBefore, limit)
+	ret0, _ := ret[0].([]models.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinksToCheck indicates an expected call of GetLinksToCheck.
+func (mr *MockStoreMockRecorder) GetLinksToCheck(checkedBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinksToCheck", reflect.TypeOf((*MockStore)(nil).GetLinksToCheck), checkedBefore, limit)
+}
+
 // GetStats mocks base method.
 func (m *MockStore) GetStats() (*models.Stats, error) {
 	m.ctrl.T.Helper()
@@ -105,33 +210,151 @@ func (mr *MockStoreMockRecorder) GetStats() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockStore)(nil).GetStats))
 }
 
+// GetUserByID mocks base method.
+func (m *MockStore) GetUserByID(id string) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", id)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockStoreMockRecorder) GetUserByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockStore)(nil).GetUserByID), id)
+}
+
+// GetUserByLogin mocks base method.
+func (m *MockStore) GetUserByLogin(login string) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByLogin", login)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByLogin indicates an expected call of GetUserByLogin.
+func (mr *MockStoreMockRecorder) GetUserByLogin(login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockStore)(nil).GetUserByLogin), login)
+}
+
+// GetWebhookDeliveries mocks base method.
+func (m *MockStore) GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDeliveries", webhookID, limit)
+	ret0, _ := ret[0].([]models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDeliveries indicates an expected call of GetWebhookDeliveries.
+func (mr *MockStoreMockRecorder) GetWebhookDeliveries(webhookID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).GetWebhookDeliveries), webhookID, limit)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockStore) GetWebhooks(userID string) ([]models.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", userID)
+	ret0, _ := ret[0].([]models.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockStoreMockRecorder) GetWebhooks(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockStore)(nil).GetWebhooks), userID)
+}
+
+// IterateByUserID mocks base method.
+func (m *MockStore) IterateByUserID(ctx context.Context, userID, after string, fn func(models.URLRecord) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IterateByUserID", ctx, userID, after, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IterateByUserID indicates an expected call of IterateByUserID.
+func (mr *MockStoreMockRecorder) IterateByUserID(ctx, userID, after, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IterateByUserID", reflect.TypeOf((*MockStore)(nil).IterateByUserID), ctx, userID, after, fn)
+}
+
+// ListByUserID mocks base method.
+func (m *MockStore) ListByUserID(ctx context.Context, userID string, query models.UserLinksQuery) ([]models.UserLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUserID", ctx, userID, query)
+	ret0, _ := ret[0].([]models.UserLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUserID indicates an expected call of ListByUserID.
+func (mr *MockStoreMockRecorder) ListByUserID(ctx, userID, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUserID", reflect.TypeOf((*MockStore)(nil).ListByUserID), ctx, userID, query)
+}
+
 // Ping mocks base method.
-func (m *MockStore) Ping() error {
+func (m *MockStore) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockStoreMockRecorder) Ping() *gomock.Call {
+func (mr *MockStoreMockRecorder) Ping(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
+// PurgeUser mocks base method.
+func (m *MockStore) PurgeUser(userID string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUser", userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeUser indicates an expected call of PurgeUser.
+func (mr *MockStoreMockRecorder) PurgeUser(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUser", reflect.TypeOf((*MockStore)(nil).PurgeUser), userID)
 }
 
 // Put mocks base method.
-func (m *MockStore) Put(id, shortURL, userID string) (string, error) {
+func (m *MockStore) Put(id, shortURL, userID string, opts models.LinkOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Put", id, shortURL, userID)
+	ret := m.ctrl.Call(m, "Put", id, shortURL, userID, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Put indicates an expected call of Put.
-func (mr *MockStoreMockRecorder) Put(id, shortURL, userID interface{}) *gomock.Call {
+func (mr *MockStoreMockRecorder) Put(id, shortURL, userID, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockStore)(nil).Put), id, shortURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockStore)(nil).Put), id, shortURL, userID, opts)
+}
+
+// PutAPIKey mocks base method.
+func (m *MockStore) PutAPIKey(key models.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutAPIKey", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutAPIKey indicates an expected call of PutAPIKey.
+func (mr *MockStoreMockRecorder) PutAPIKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutAPIKey", reflect.TypeOf((*MockStore)(nil).PutAPIKey), key)
 }
 
 // PutBatch mocks base method.
@@ -148,3 +371,131 @@ func (mr *MockStoreMockRecorder) PutBatch(data, userID interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutBatch", reflect.TypeOf((*MockStore)(nil).PutBatch), data, userID)
 }
+
+// PutWebhook mocks base method.
+func (m *MockStore) PutWebhook(hook models.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutWebhook", hook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutWebhook indicates an expected call of PutWebhook.
+func (mr *MockStoreMockRecorder) PutWebhook(hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWebhook", reflect.TypeOf((*MockStore)(nil).PutWebhook), hook)
+}
+
+// PutWebhookDelivery mocks base method.
+func (m *MockStore) PutWebhookDelivery(delivery models.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutWebhookDelivery", delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutWebhookDelivery indicates an expected call of PutWebhookDelivery.
+func (mr *MockStoreMockRecorder) PutWebhookDelivery(delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutWebhookDelivery", reflect.TypeOf((*MockStore)(nil).PutWebhookDelivery), delivery)
+}
+
+// RecordClick mocks base method.
+func (m *MockStore) RecordClick(id, variant string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordClick", id, variant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordClick indicates an expected call of RecordClick.
+func (mr *MockStoreMockRecorder) RecordClick(id, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClick", reflect.TypeOf((*MockStore)(nil).RecordClick), id, variant)
+}
+
+// SearchLinks mocks base method.
+func (m *MockStore) SearchLinks(filter models.LinkFilter) ([]models.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchLinks", filter)
+	ret0, _ := ret[0].([]models.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchLinks indicates an expected call of SearchLinks.
+func (mr *MockStoreMockRecorder) SearchLinks(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchLinks", reflect.TypeOf((*MockStore)(nil).SearchLinks), filter)
+}
+
+// SetDisabled mocks base method.
+func (m *MockStore) SetDisabled(id string, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDisabled", id, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDisabled indicates an expected call of SetDisabled.
+func (mr *MockStoreMockRecorder) SetDisabled(id, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDisabled", reflect.TypeOf((*MockStore)(nil).SetDisabled), id, disabled)
+}
+
+// SetHealth mocks base method.
+func (m *MockStore) SetHealth(id string, health models.LinkHealth) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHealth", id, health)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHealth indicates an expected call of SetHealth.
+func (mr *MockStoreMockRecorder) SetHealth(id, health interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHealth", reflect.TypeOf((*MockStore)(nil).SetHealth), id, health)
+}
+
+// SetRules mocks base method.
+func (m *MockStore) SetRules(id string, rules []models.RedirectRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRules", id, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRules indicates an expected call of SetRules.
+func (mr *MockStoreMockRecorder) SetRules(id, rules interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRules", reflect.TypeOf((*MockStore)(nil).SetRules), id, rules)
+}
+
+// TransferLinks mocks base method.
+func (m *MockStore) TransferLinks(fromUserID, toUserID string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferLinks", fromUserID, toUserID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferLinks indicates an expected call of TransferLinks.
+func (mr *MockStoreMockRecorder) TransferLinks(fromUserID, toUserID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferLinks", reflect.TypeOf((*MockStore)(nil).TransferLinks), fromUserID, toUserID)
+}
+
+// UpdateWebhook mocks base method.
+func (m *MockStore) UpdateWebhook(hook models.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhook", hook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebhook indicates an expected call of UpdateWebhook.
+func (mr *MockStoreMockRecorder) UpdateWebhook(hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhook", reflect.TypeOf((*MockStore)(nil).UpdateWebhook), hook)
+}
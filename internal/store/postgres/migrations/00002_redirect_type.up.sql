@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN redirect_type SMALLINT NOT NULL DEFAULT 0;
+
+COMMIT;
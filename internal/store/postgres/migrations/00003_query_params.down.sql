@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener DROP COLUMN utm;
+ALTER TABLE shortener DROP COLUMN query_mode;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN query_mode VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE shortener ADD COLUMN utm JSONB;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener DROP COLUMN rules;
+
+COMMIT;
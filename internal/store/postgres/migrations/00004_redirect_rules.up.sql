@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN rules JSONB;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN TRANSACTION;
+
+DROP TABLE variant_clicks;
+
+ALTER TABLE shortener DROP COLUMN clicks;
+ALTER TABLE shortener DROP COLUMN sticky;
+ALTER TABLE shortener DROP COLUMN variants;
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN variants JSONB;
+ALTER TABLE shortener ADD COLUMN sticky BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE shortener ADD COLUMN clicks BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE variant_clicks(
+    slug VARCHAR(255),
+    variant VARCHAR(255),
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY(slug, variant)
+);
+
+COMMIT;
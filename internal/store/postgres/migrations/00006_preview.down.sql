@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener DROP COLUMN created_at;
+ALTER TABLE shortener DROP COLUMN interstitial;
+ALTER TABLE shortener DROP COLUMN title;
+
+COMMIT;
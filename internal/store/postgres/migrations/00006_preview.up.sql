@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE shortener ADD COLUMN interstitial BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE shortener ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+COMMIT;
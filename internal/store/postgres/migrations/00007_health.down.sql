@@ -0,0 +1,11 @@
+BEGIN TRANSACTION;
+
+DROP INDEX shortener_checked_at_idx;
+
+ALTER TABLE shortener DROP COLUMN broken;
+ALTER TABLE shortener DROP COLUMN health_error;
+ALTER TABLE shortener DROP COLUMN health_latency_ms;
+ALTER TABLE shortener DROP COLUMN health_status;
+ALTER TABLE shortener DROP COLUMN checked_at;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN checked_at TIMESTAMPTZ;
+ALTER TABLE shortener ADD COLUMN health_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE shortener ADD COLUMN health_latency_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE shortener ADD COLUMN health_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE shortener ADD COLUMN broken BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX shortener_checked_at_idx ON shortener (checked_at NULLS FIRST) WHERE deleted_flag = FALSE;
+
+COMMIT;
@@ -0,0 +1,31 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE webhooks(
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events JSONB NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE webhook_deliveries(
+    id VARCHAR(255) PRIMARY KEY,
+    webhook_id VARCHAR(255) NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id VARCHAR(255) NOT NULL,
+    event VARCHAR(255) NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, created_at DESC);
+
+COMMIT;
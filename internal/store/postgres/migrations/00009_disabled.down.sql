@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP INDEX shortener_user_id_idx;
+
+ALTER TABLE shortener DROP COLUMN disabled;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE shortener ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX shortener_user_id_idx ON shortener (user_id);
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE api_keys(
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    prefix VARCHAR(255) NOT NULL,
+    hash VARCHAR(255) NOT NULL UNIQUE,
+    scope VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE users(
+    id VARCHAR(255) PRIMARY KEY,
+    login VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
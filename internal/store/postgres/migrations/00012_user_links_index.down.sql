@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP INDEX shortener_user_clicks_idx;
+DROP INDEX shortener_user_created_at_idx;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+CREATE INDEX shortener_user_created_at_idx ON shortener (user_id, created_at, slug);
+CREATE INDEX shortener_user_clicks_idx ON shortener (user_id, clicks, slug);
+
+COMMIT;
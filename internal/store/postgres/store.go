@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -27,9 +30,6 @@ const CPUMultiplyer = 4
 // ErrDBInsertConflict Обнаружен конфликт в БД, необходимо его обработать.
 var ErrDBInsertConflict = errors.New("conflict insert into table, returned stored value")
 
-// ErrURLDeleted Запрашиваемый URL удален.
-var ErrURLDeleted = errors.New("url is deleted")
-
 // NewPostgresStore Функция получения экземпляра DBStore.
 func NewPostgresStore(ctx context.Context, dsn string) (*DBStore, error) {
 	if err := runMigrations(dsn); err != nil {
@@ -74,8 +74,8 @@ func runMigrations(dsn string) error {
 	return nil
 }
 
-func (db *DBStore) Ping() error {
-	if err := db.conn.Ping(context.Background()); err != nil {
+func (db *DBStore) Ping(ctx context.Context) error {
+	if err := db.conn.Ping(ctx); err != nil {
 		return fmt.Errorf("lost connection to db: %w", err)
 	}
 	return nil
@@ -85,27 +85,49 @@ func (db *DBStore) Close() {
 	db.conn.Close()
 }
 
-func (db *DBStore) Get(id string) (string, error) {
-	row := db.conn.QueryRow(context.Background(), "SELECT original_url, deleted_flag FROM shortener WHERE slug = $1", id)
-	var result string
+func (db *DBStore) Get(id string) (*models.Link, error) {
+	row := db.conn.QueryRow(context.Background(), `
+		SELECT original_url, user_id, deleted_flag, redirect_type, query_mode, utm, rules, variants, sticky,
+			title, interstitial, created_at, disabled
+		FROM shortener
+		WHERE slug = $1
+	`, id)
+	result := models.Link{ShortURL: id}
 	var deleted bool
-	err := row.Scan(&result, &deleted)
+	err := row.Scan(
+		&result.OriginalURL,
+		&result.UserID,
+		&deleted,
+		&result.RedirectType,
+		&result.QueryMode,
+		&result.UTM,
+		&result.Rules,
+		&result.Variants,
+		&result.Sticky,
+		&result.Title,
+		&result.Interstitial,
+		&result.CreatedAt,
+		&result.Disabled,
+	)
 	if err != nil {
-		return "", fmt.Errorf("cant scan result: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cant scan result: %w", err)
 	}
 
 	if deleted {
-		return "", ErrURLDeleted
+		return nil, models.ErrLinkDeleted
 	}
 
-	return result, nil
+	return &result, nil
 }
 
 func (db *DBStore) GetAllByUserID(userID string) ([]models.URLRecord, error) {
 	result := make([]models.URLRecord, 0)
 
 	rows, err := db.conn.Query(context.Background(), `
-		SELECT slug, original_url
+		SELECT slug, original_url, checked_at, health_status, health_latency_ms, health_error, broken
 		FROM shortener
 		WHERE user_id = $1 AND deleted_flag = FALSE
 	`, userID)
@@ -116,9 +138,23 @@ func (db *DBStore) GetAllByUserID(userID string) ([]models.URLRecord, error) {
 
 	for rows.Next() {
 		record := models.URLRecord{}
-		if err := rows.Scan(&record.ShortURL, &record.OriginalURL); err != nil {
+		var checkedAt *time.Time
+		health := models.LinkHealth{}
+		if err := rows.Scan(
+			&record.ShortURL,
+			&record.OriginalURL,
+			&checkedAt,
+			&health.Status,
+			&health.LatencyMS,
+			&health.Error,
+			&health.Broken,
+		); err != nil {
 			return nil, fmt.Errorf("cant scan records: %w", err)
 		}
+		if checkedAt != nil {
+			health.CheckedAt = *checkedAt
+			record.Health = &health
+		}
 
 		result = append(result, record)
 	}
@@ -126,12 +162,181 @@ func (db *DBStore) GetAllByUserID(userID string) ([]models.URLRecord, error) {
 	return result, nil
 }
 
-func (db *DBStore) DeleteMany(ids models.DeleteUserURLsReq, userID string) error {
+// IterateByUserID вызывает fn для ссылок пользователя в порядке возрастания slug, начиная со следующей
+// после after. Строки читаются из курсора по мере обработки, без загрузки всего списка в память.
+func (db *DBStore) IterateByUserID(
+	ctx context.Context,
+	userID string,
+	after string,
+	fn func(models.URLRecord) error,
+) error {
+	rows, err := db.conn.Query(ctx, `
+		SELECT slug, original_url, checked_at, health_status, health_latency_ms, health_error, broken
+		FROM shortener
+		WHERE user_id = $1 AND deleted_flag = FALSE AND slug > $2
+		ORDER BY slug
+	`, userID, after)
+	if err != nil {
+		return fmt.Errorf("failed to query users records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record := models.URLRecord{}
+		var checkedAt *time.Time
+		health := models.LinkHealth{}
+		if err := rows.Scan(
+			&record.ShortURL,
+			&record.OriginalURL,
+			&checkedAt,
+			&health.Status,
+			&health.LatencyMS,
+			&health.Error,
+			&health.Broken,
+		); err != nil {
+			return fmt.Errorf("cant scan records: %w", err)
+		}
+		if checkedAt != nil {
+			health.CheckedAt = *checkedAt
+			record.Health = &health
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading users records: %w", err)
+	}
+
+	return nil
+}
+
+// hostExpr выражение SQL, извлекающее хост из адреса назначения.
+const hostExpr = `lower(substring(original_url from '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^/?#@]*@)?([^/?#:]+)'))`
+
+// likeEscaper экранирует спецсимволы шаблона LIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListByUserID возвращает ссылки пользователя, отобранные и упорядоченные по query.
+// Страница выбирается по ключу (created_at, slug) или (clicks, slug) после позиции курсора,
+// что позволяет использовать индексы по пользователю и ключу сортировки вместо OFFSET.
+func (db *DBStore) ListByUserID(
+	ctx context.Context,
+	userID string,
+	query models.UserLinksQuery,
+) ([]models.UserLink, error) {
+	args := []interface{}{userID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"user_id = $1"}
+	switch query.Deleted {
+	case models.DeletedExclude:
+		conditions = append(conditions, "deleted_flag = FALSE")
+	case models.DeletedOnly:
+		conditions = append(conditions, "deleted_flag = TRUE")
+	}
+	if query.URL != "" {
+		conditions = append(conditions, "original_url ILIKE '%' || "+arg(likeEscaper.Replace(query.URL))+" || '%'")
+	}
+	if query.Domain != "" {
+		conditions = append(conditions, fmt.Sprintf("(%s = %s OR %s LIKE '%%.' || %s)",
+			hostExpr, arg(query.Domain), hostExpr, arg(likeEscaper.Replace(query.Domain))))
+	}
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(query.CreatedFrom))
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(query.CreatedTo))
+	}
+
+	column, order, op := "created_at", "ASC", ">"
+	if query.Sort == models.SortByClicks {
+		column = "clicks"
+	}
+	if query.Desc {
+		order, op = "DESC", "<"
+	}
+	if query.After != nil {
+		var value interface{} = query.After.CreatedAt
+		if query.Sort == models.SortByClicks {
+			value = query.After.Clicks
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, slug) %s (%s, %s)", column, op, arg(value), arg(query.After.Slug)))
+	}
+
+	sql := fmt.Sprintf(`
+		SELECT slug, original_url, created_at, clicks, deleted_flag,
+			checked_at, health_status, health_latency_ms, health_error, broken,
+			redirect_type, query_mode, utm, rules, variants, sticky, title, interstitial
+		FROM shortener
+		WHERE %s
+		ORDER BY %s %s, slug %s
+	`, strings.Join(conditions, " AND "), column, order, order)
+	if query.Limit > 0 {
+		sql += "LIMIT " + arg(query.Limit)
+	}
+
+	rows, err := db.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users records: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.UserLink, 0)
+	for rows.Next() {
+		link := models.UserLink{}
+		var checkedAt *time.Time
+		health := models.LinkHealth{}
+		if err := rows.Scan(
+			&link.ShortURL,
+			&link.OriginalURL,
+			&link.CreatedAt,
+			&link.Clicks,
+			&link.Deleted,
+			&checkedAt,
+			&health.Status,
+			&health.LatencyMS,
+			&health.Error,
+			&health.Broken,
+			&link.RedirectType,
+			&link.QueryMode,
+			&link.UTM,
+			&link.Rules,
+			&link.Variants,
+			&link.Sticky,
+			&link.Title,
+			&link.Interstitial,
+		); err != nil {
+			return nil, fmt.Errorf("cant scan records: %w", err)
+		}
+		if checkedAt != nil {
+			health.CheckedAt = *checkedAt
+			link.Health = &health
+		}
+
+		result = append(result, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading users records: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteMany помечает ссылки пользователя удаленными и возвращает идентификаторы помеченных.
+// Чужие, несуществующие и уже удаленные ссылки пропускаются.
+func (db *DBStore) DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error) {
 	ctx := context.Background()
 
 	query := `
 		UPDATE shortener SET deleted_flag = TRUE
-		WHERE shortener.slug = $1 AND shortener.user_id = $2`
+		WHERE shortener.slug = $1 AND shortener.user_id = $2 AND NOT shortener.deleted_flag`
 	batch := &pgx.Batch{}
 	for _, url := range ids {
 		batch.Queue(query, url, userID)
@@ -143,27 +348,37 @@ func (db *DBStore) DeleteMany(ids models.DeleteUserURLsReq, userID string) error
 		}
 	}()
 
-	for range ids {
-		_, err := batchResults.Exec()
+	deleted := make(models.DeleteUserURLsReq, 0, len(ids))
+	for _, id := range ids {
+		tag, err := batchResults.Exec()
 		if err != nil {
 			log.Printf("error executing: %v", err)
-			return fmt.Errorf("cant exec batch: %w", err)
+			return nil, fmt.Errorf("cant exec batch: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			deleted = append(deleted, id)
 		}
 	}
 
-	return nil
+	return deleted, nil
 }
 
-func (db *DBStore) Put(id string, url string, userID string) (string, error) {
+func (db *DBStore) Put(id string, url string, userID string, opts models.LinkOptions) (string, error) {
 	var err error
 
 	row := db.conn.QueryRow(context.Background(), `
-		INSERT INTO shortener VALUES ($1, $2, $3)
+		INSERT INTO shortener (
+			slug, original_url, user_id, redirect_type, query_mode, utm, rules, variants, sticky, title, interstitial
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		ON CONFLICT (original_url)
 		DO UPDATE SET
 			original_url=EXCLUDED.original_url
 		RETURNING slug
-	`, id, url, userID)
+	`,
+		id, url, userID,
+		opts.RedirectType, opts.QueryMode, opts.UTM, opts.Rules, opts.Variants, opts.Sticky, opts.Title, opts.Interstitial,
+	)
 	var result string
 	if err := row.Scan(&result); err != nil {
 		return "", fmt.Errorf("cant scan put record result: %w", err)
@@ -178,7 +393,12 @@ func (db *DBStore) Put(id string, url string, userID string) (string, error) {
 
 func (db *DBStore) PutBatch(urls []models.URLBatchReq, userID string) ([]models.URLBatchRes, error) {
 	query := `
-		INSERT INTO shortener VALUES (@slug, @originalUrl, @userID)
+		INSERT INTO shortener (
+			slug, original_url, user_id, redirect_type, query_mode, utm, rules, variants, sticky, title, interstitial
+		)
+		VALUES (
+			@slug, @originalUrl, @userID, @redirectType, @queryMode, @utm, @rules, @variants, @sticky, @title, @interstitial
+		)
 		ON CONFLICT (original_url)
 		DO UPDATE SET
 			original_url=EXCLUDED.original_url
@@ -189,9 +409,17 @@ func (db *DBStore) PutBatch(urls []models.URLBatchReq, userID string) ([]models.
 	batch := &pgx.Batch{}
 	for _, url := range urls {
 		args := pgx.NamedArgs{
-			"slug":        url.CorrelationID,
-			"originalUrl": url.OriginalURL,
-			"userID":      userID,
+			"slug":         url.CorrelationID,
+			"originalUrl":  url.OriginalURL,
+			"userID":       userID,
+			"redirectType": url.RedirectType,
+			"queryMode":    url.QueryMode,
+			"utm":          url.UTM,
+			"rules":        url.Rules,
+			"variants":     url.Variants,
+			"sticky":       url.Sticky,
+			"title":        url.Title,
+			"interstitial": url.Interstitial,
 		}
 		batch.Queue(query, args)
 	}
@@ -216,6 +444,463 @@ func (db *DBStore) PutBatch(urls []models.URLBatchReq, userID string) ([]models.
 	return result, nil
 }
 
+func (db *DBStore) SetRules(id string, rules []models.RedirectRule) error {
+	tag, err := db.conn.Exec(context.Background(), `
+		UPDATE shortener SET rules = $2
+		WHERE slug = $1 AND deleted_flag = FALSE
+	`, id, rules)
+	if err != nil {
+		return fmt.Errorf("cant update rules: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("url %s not found", id)
+	}
+
+	return nil
+}
+
+func (db *DBStore) RecordClick(id string, variant string) error {
+	ctx := context.Background()
+
+	batch := &pgx.Batch{}
+	batch.Queue(`UPDATE shortener SET clicks = clicks + 1 WHERE slug = $1`, id)
+	if variant != "" {
+		batch.Queue(`
+			INSERT INTO variant_clicks (slug, variant, clicks) VALUES ($1, $2, 1)
+			ON CONFLICT (slug, variant)
+			DO UPDATE SET clicks = variant_clicks.clicks + 1
+		`, id, variant)
+	}
+
+	if err := db.conn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("cant record click: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) GetClicks(id string) (*models.ClickStats, error) {
+	ctx := context.Background()
+	result := &models.ClickStats{Variants: make(map[string]int64)}
+
+	if err := db.conn.QueryRow(ctx, `SELECT clicks FROM shortener WHERE slug = $1`, id).Scan(&result.Clicks); err != nil {
+		return nil, fmt.Errorf("cant get clicks: %w", err)
+	}
+
+	rows, err := db.conn.Query(ctx, `SELECT variant, clicks FROM variant_clicks WHERE slug = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variant clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variant string
+		var clicks int64
+		if err := rows.Scan(&variant, &clicks); err != nil {
+			return nil, fmt.Errorf("cant scan variant clicks: %w", err)
+		}
+		result.Variants[variant] = clicks
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read variant clicks: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStore) GetLinksToCheck(checkedBefore time.Time, limit int) ([]models.Link, error) {
+	rows, err := db.conn.Query(context.Background(), `
+		SELECT slug, original_url, user_id
+		FROM shortener
+		WHERE deleted_flag = FALSE AND (checked_at IS NULL OR checked_at < $1)
+		ORDER BY checked_at NULLS FIRST
+		LIMIT $2
+	`, checkedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links to check: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.Link, 0)
+	for rows.Next() {
+		link := models.Link{}
+		if err := rows.Scan(&link.ShortURL, &link.OriginalURL, &link.UserID); err != nil {
+			return nil, fmt.Errorf("cant scan link: %w", err)
+		}
+		result = append(result, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read links to check: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStore) SetHealth(id string, health models.LinkHealth) error {
+	_, err := db.conn.Exec(context.Background(), `
+		UPDATE shortener
+		SET checked_at = $2, health_status = $3, health_latency_ms = $4, health_error = $5, broken = $6
+		WHERE slug = $1
+	`, id, health.CheckedAt, health.Status, health.LatencyMS, health.Error, health.Broken)
+	if err != nil {
+		return fmt.Errorf("cant update link health: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) PutWebhook(hook models.Webhook) error {
+	_, err := db.conn.Exec(context.Background(), `
+		INSERT INTO webhooks (id, user_id, url, secret, events, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, hook.ID, hook.UserID, hook.URL, hook.Secret, hook.Events, hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("cant insert webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) GetWebhooks(userID string) ([]models.Webhook, error) {
+	rows, err := db.conn.Query(context.Background(), `
+		SELECT id, user_id, url, secret, events, failures, last_error, disabled, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.Webhook, 0)
+	for rows.Next() {
+		hook := models.Webhook{}
+		if err := rows.Scan(
+			&hook.ID,
+			&hook.UserID,
+			&hook.URL,
+			&hook.Secret,
+			&hook.Events,
+			&hook.Failures,
+			&hook.LastError,
+			&hook.Disabled,
+			&hook.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("cant scan webhook: %w", err)
+		}
+		result = append(result, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStore) UpdateWebhook(hook models.Webhook) error {
+	tag, err := db.conn.Exec(context.Background(), `
+		UPDATE webhooks SET failures = $2, last_error = $3, disabled = $4
+		WHERE id = $1
+	`, hook.ID, hook.Failures, hook.LastError, hook.Disabled)
+	if err != nil {
+		return fmt.Errorf("cant update webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook %s not found", hook.ID)
+	}
+
+	return nil
+}
+
+func (db *DBStore) DeleteWebhook(id string) error {
+	if _, err := db.conn.Exec(context.Background(), `DELETE FROM webhooks WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("cant delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) PutWebhookDelivery(delivery models.WebhookDelivery) error {
+	_, err := db.conn.Exec(context.Background(), `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_id, event, status, attempts, error, success, created_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9
+		WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = $2)
+	`,
+		delivery.ID,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.Event,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.Error,
+		delivery.Success,
+		delivery.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("cant insert webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := db.conn.Query(context.Background(), `
+		SELECT id, webhook_id, event_id, event, status, attempts, error, success, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d := models.WebhookDelivery{}
+		if err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Status, &d.Attempts, &d.Error, &d.Success, &d.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("cant scan webhook delivery: %w", err)
+		}
+		result = append(result, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read webhook deliveries: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStore) SearchLinks(filter models.LinkFilter) ([]models.Link, error) {
+	rows, err := db.conn.Query(context.Background(), `
+		SELECT slug, original_url, user_id, redirect_type, query_mode, utm, rules, variants, sticky,
+			title, interstitial, created_at, disabled
+		FROM shortener
+		WHERE deleted_flag = FALSE
+			AND ($1 = '' OR slug ILIKE '%' || $1 || '%')
+			AND ($2 = '' OR original_url ILIKE '%' || $2 || '%')
+			AND ($3 = '' OR user_id = $3)
+		ORDER BY created_at DESC, slug
+		LIMIT NULLIF($4, 0) OFFSET $5
+	`, filter.Slug, filter.URL, filter.UserID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search links: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.Link, 0)
+	for rows.Next() {
+		link := models.Link{}
+		if err := rows.Scan(
+			&link.ShortURL,
+			&link.OriginalURL,
+			&link.UserID,
+			&link.RedirectType,
+			&link.QueryMode,
+			&link.UTM,
+			&link.Rules,
+			&link.Variants,
+			&link.Sticky,
+			&link.Title,
+			&link.Interstitial,
+			&link.CreatedAt,
+			&link.Disabled,
+		); err != nil {
+			return nil, fmt.Errorf("cant scan link: %w", err)
+		}
+		result = append(result, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read links: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStore) SetDisabled(id string, disabled bool) error {
+	tag, err := db.conn.Exec(context.Background(), `
+		UPDATE shortener SET disabled = $2
+		WHERE slug = $1
+	`, id, disabled)
+	if err != nil {
+		return fmt.Errorf("cant update disabled flag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("url %s not found", id)
+	}
+
+	return nil
+}
+
+func (db *DBStore) TransferLinks(fromUserID string, toUserID string) (int, error) {
+	tag, err := db.conn.Exec(context.Background(), `
+		UPDATE shortener SET user_id = $2
+		WHERE user_id = $1 AND deleted_flag = FALSE
+	`, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("cant transfer links: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (db *DBStore) PurgeUser(userID string) (int, error) {
+	ctx := context.Background()
+
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cant begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Printf("error rolling back: %v", err)
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM variant_clicks
+		WHERE slug IN (SELECT slug FROM shortener WHERE user_id = $1)
+	`, userID); err != nil {
+		return 0, fmt.Errorf("cant delete variant clicks: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM shortener WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("cant delete links: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM webhooks WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("cant delete webhooks: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM api_keys WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("cant delete api keys: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("cant delete user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("cant commit purge: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (db *DBStore) PutAPIKey(key models.APIKey) error {
+	_, err := db.conn.Exec(context.Background(), `
+		INSERT INTO api_keys (id, user_id, name, prefix, hash, scope, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, key.ID, key.UserID, key.Name, key.Prefix, key.Hash, key.Scope, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("cant insert api key: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) GetAPIKeys(userID string) ([]models.APIKey, error) {
+	rows, err := db.conn.Query(context.Background(), `
+		SELECT id, user_id, name, prefix, hash, scope, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]models.APIKey, 0)
+	for rows.Next() {
+		key := models.APIKey{}
+		if err := rows.Scan(
+			&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &key.Scope, &key.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("cant scan api key: %w", err)
+		}
+		result = append(result, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStore) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	key := models.APIKey{}
+	err := db.conn.QueryRow(context.Background(), `
+		SELECT id, user_id, name, prefix, hash, scope, created_at
+		FROM api_keys
+		WHERE hash = $1
+	`, hash).Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &key.Scope, &key.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cant scan api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (db *DBStore) DeleteAPIKey(id string) error {
+	if _, err := db.conn.Exec(context.Background(), `DELETE FROM api_keys WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("cant delete api key: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStore) CreateUser(user models.User) error {
+	tag, err := db.conn.Exec(context.Background(), `
+		INSERT INTO users (id, login, password_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, user.ID, user.Login, user.PasswordHash, user.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("cant insert user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserConflict
+	}
+
+	return nil
+}
+
+func (db *DBStore) GetUserByLogin(login string) (*models.User, error) {
+	return db.getUser(`SELECT id, login, password_hash, created_at FROM users WHERE login = $1`, login)
+}
+
+func (db *DBStore) GetUserByID(id string) (*models.User, error) {
+	return db.getUser(`SELECT id, login, password_hash, created_at FROM users WHERE id = $1`, id)
+}
+
+func (db *DBStore) getUser(query string, arg string) (*models.User, error) {
+	user := models.User{}
+	err := db.conn.QueryRow(context.Background(), query, arg).
+		Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cant scan user: %w", err)
+	}
+
+	return &user, nil
+}
+
 func (db *DBStore) GetStats() (*models.Stats, error) {
 	row := db.conn.QueryRow(context.Background(), "SELECT COUNT(*), COUNT(DISTINCT user_id) FROM shortener")
 	var result models.Stats
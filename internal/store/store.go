@@ -5,6 +5,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"time"
 
 	_ "github.com/golang/mock/mockgen/model"
 	"github.com/rawen554/shortener/internal/config"
@@ -16,13 +17,37 @@ import (
 
 // Store Интерфейс содержит все необходимые методы для работы сервиса.
 type Store interface {
-	Get(id string) (string, error)
+	Get(id string) (*models.Link, error)
 	GetStats() (*models.Stats, error)
 	GetAllByUserID(userID string) ([]models.URLRecord, error)
-	DeleteMany(ids models.DeleteUserURLsReq, userID string) error
-	Put(id string, shortURL string, userID string) (string, error)
+	IterateByUserID(ctx context.Context, userID string, after string, fn func(models.URLRecord) error) error
+	ListByUserID(ctx context.Context, userID string, query models.UserLinksQuery) ([]models.UserLink, error)
+	DeleteMany(ids models.DeleteUserURLsReq, userID string) (models.DeleteUserURLsReq, error)
+	Put(id string, shortURL string, userID string, opts models.LinkOptions) (string, error)
 	PutBatch(data []models.URLBatchReq, userID string) ([]models.URLBatchRes, error)
-	Ping() error
+	SetRules(id string, rules []models.RedirectRule) error
+	RecordClick(id string, variant string) error
+	GetClicks(id string) (*models.ClickStats, error)
+	GetLinksToCheck(checkedBefore time.Time, limit int) ([]models.Link, error)
+	SetHealth(id string, health models.LinkHealth) error
+	PutWebhook(hook models.Webhook) error
+	GetWebhooks(userID string) ([]models.Webhook, error)
+	UpdateWebhook(hook models.Webhook) error
+	DeleteWebhook(id string) error
+	PutWebhookDelivery(delivery models.WebhookDelivery) error
+	GetWebhookDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error)
+	SearchLinks(filter models.LinkFilter) ([]models.Link, error)
+	SetDisabled(id string, disabled bool) error
+	TransferLinks(fromUserID string, toUserID string) (int, error)
+	PurgeUser(userID string) (int, error)
+	PutAPIKey(key models.APIKey) error
+	GetAPIKeys(userID string) ([]models.APIKey, error)
+	GetAPIKeyByHash(hash string) (*models.APIKey, error)
+	DeleteAPIKey(id string) error
+	CreateUser(user models.User) error
+	GetUserByLogin(login string) (*models.User, error)
+	GetUserByID(id string) (*models.User, error)
+	Ping(ctx context.Context) error
 	Close()
 }
 
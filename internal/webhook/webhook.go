@@ -0,0 +1,499 @@
+// Модуль доставки событий ссылок на адреса подписчиков.
+//
+// Каждый запрос подписывается HMAC-SHA256 от строки "<timestamp>.<body>" с ключом подписки.
+// Подпись передается в заголовке X-Shortener-Signature в виде "sha256=<hex>",
+// время формирования подписи в секундах Unix — в заголовке X-Shortener-Timestamp.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/netguard"
+	"go.uber.org/zap"
+)
+
+// Заголовки запроса к подписчику.
+const (
+	SignatureHeader = "X-Shortener-Signature"
+	TimestampHeader = "X-Shortener-Timestamp"
+	EventHeader     = "X-Shortener-Event"
+	DeliveryHeader  = "X-Shortener-Delivery"
+
+	signaturePrefix = "sha256="
+	userAgent       = "shortener-webhook/1.0"
+	maxBodyRead     = 4 << 10
+	idLength        = 8
+)
+
+// errStopped доставка прервана остановкой сервиса.
+var errStopped = errors.New("dispatcher stopped")
+
+// Store хранилище подписок и журнала доставок.
+type Store interface {
+	GetWebhooks(userID string) ([]models.Webhook, error)
+	UpdateWebhook(hook models.Webhook) error
+	PutWebhookDelivery(delivery models.WebhookDelivery) error
+}
+
+// Config параметры доставки.
+type Config struct {
+	// BaseDelay пауза перед первым повтором, каждая следующая вдвое больше.
+	BaseDelay time.Duration
+	// MaxDelay ограничение на паузу между повторами.
+	MaxDelay time.Duration
+	// Timeout ограничение на время одного запроса.
+	Timeout time.Duration
+	// MaxAttempts количество попыток доставки одного события.
+	MaxAttempts int
+	// DisableAfter после скольких подряд неудачных доставок подписка отключается.
+	DisableAfter int
+	// Workers количество одновременных доставок.
+	Workers int
+	// QueueSize сколько событий ждут доставки, события сверх очереди отбрасываются.
+	QueueSize int
+	// CacheTTL сколько подписки пользователя хранятся в памяти, 0 отключает кэш.
+	CacheTTL time.Duration
+	// CacheSize наибольшее число пользователей в кэше подписок.
+	CacheSize int
+	// CloseTimeout сколько Close ждет начатых доставок, после чего прерывает их; 0 — без ограничения.
+	CloseTimeout time.Duration
+	// AllowLoopback разрешает доставку на адреса loopback.
+	AllowLoopback bool
+}
+
+// DefaultConfig параметры доставки по умолчанию: 5 попыток с паузами от 1 секунды до 1 минуты,
+// отключение подписки после 10 неудачных доставок подряд, очередь на 1000 событий.
+func DefaultConfig() Config {
+	return Config{
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Timeout:      time.Second * 10,
+		MaxAttempts:  5,
+		DisableAfter: 10,
+		Workers:      8,
+		QueueSize:    1000,
+		CacheTTL:     time.Second * 30,
+		CacheSize:    10000,
+		CloseTimeout: time.Second * 3,
+	}
+}
+
+// job событие, ожидающее доставки одной подписке.
+type job struct {
+	event models.WebhookEvent
+	hook  models.Webhook
+}
+
+// cachedHooks подписки пользователя, прочитанные из хранилища.
+type cachedHooks struct {
+	expires time.Time
+	hooks   []models.Webhook
+}
+
+// Dispatcher отправляет события подписчикам в фоне: события попадают в очередь,
+// которую разбирает постоянный набор обработчиков.
+type Dispatcher struct {
+	ctx      context.Context
+	store    Store
+	client   *http.Client
+	logger   *zap.SugaredLogger
+	queue    chan job
+	done     chan struct{}
+	cancel   context.CancelFunc
+	cache    map[string]cachedHooks
+	wg       *sync.WaitGroup
+	mux      *sync.Mutex
+	cacheMux *sync.Mutex
+	queueMux *sync.RWMutex
+	once     *sync.Once
+	config   Config
+	closed   bool
+}
+
+// NewDispatcher создает диспетчер. Если client не задан, используется клиент с таймаутом из конфига,
+// который не обращается к адресам внутренних сетей и не выполняет перенаправления.
+func NewDispatcher(config Config, store Store, logger *zap.SugaredLogger, client *http.Client) *Dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1
+	}
+	if client == nil {
+		client = netguard.NewClient(config.Timeout, config.AllowLoopback, false)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		ctx:      ctx,
+		store:    store,
+		client:   client,
+		logger:   logger,
+		queue:    make(chan job, config.QueueSize),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+		cache:    make(map[string]cachedHooks),
+		wg:       &sync.WaitGroup{},
+		mux:      &sync.Mutex{},
+		cacheMux: &sync.Mutex{},
+		queueMux: &sync.RWMutex{},
+		once:     &sync.Once{},
+		config:   config,
+	}
+
+	d.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go d.work()
+	}
+
+	return d
+}
+
+// Publish отправляет событие всем активным подпискам пользователя, подписанным на его тип.
+// Доставка выполняется асинхронно; если очередь заполнена, событие отбрасывается.
+func (d *Dispatcher) Publish(userID string, eventType string, data models.LinkEvent) {
+	hooks, err := d.getWebhooks(userID)
+	if err != nil {
+		d.logger.Errorf("error getting webhooks: %v", err)
+		return
+	}
+
+	var event *models.WebhookEvent
+	for _, hook := range hooks {
+		if hook.Disabled || !hook.Subscribed(eventType) {
+			continue
+		}
+
+		if event == nil {
+			id, err := NewID()
+			if err != nil {
+				d.logger.Error(err)
+				return
+			}
+			event = &models.WebhookEvent{
+				CreatedAt: time.Now().UTC(),
+				ID:        id,
+				Type:      eventType,
+				Data:      data,
+			}
+		}
+
+		d.enqueue(job{hook: hook, event: *event})
+	}
+}
+
+// Invalidate сбрасывает подписки пользователя в кэше, их нужно вызывать после изменения подписок.
+func (d *Dispatcher) Invalidate(userID string) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
+
+	delete(d.cache, userID)
+}
+
+// Close перестает принимать события, отменяет оставшиеся повторные попытки и дожидается
+// первой попытки доставки событий из очереди. Доставки, не завершившиеся за CloseTimeout, прерываются.
+func (d *Dispatcher) Close() {
+	d.once.Do(func() {
+		d.queueMux.Lock()
+		d.closed = true
+		close(d.queue)
+		d.queueMux.Unlock()
+		close(d.done)
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(stopped)
+	}()
+
+	var timeout <-chan time.Time
+	if d.config.CloseTimeout > 0 {
+		timer := time.NewTimer(d.config.CloseTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-stopped:
+	case <-timeout:
+		d.logger.Warnf("webhook deliveries have not finished in %s, aborting", d.config.CloseTimeout)
+		d.cancel()
+		<-stopped
+	}
+	d.cancel()
+}
+
+func (d *Dispatcher) enqueue(j job) {
+	d.queueMux.RLock()
+	defer d.queueMux.RUnlock()
+
+	if d.closed {
+		return
+	}
+	select {
+	case d.queue <- j:
+	default:
+		d.logger.Warnf("webhook queue is full, event %s for webhook %s dropped", j.event.ID, j.hook.ID)
+	}
+}
+
+// work доставляет события из очереди, пока очередь не закрыта.
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for j := range d.queue {
+		// после прерывания остановкой оставшиеся события не отправляются
+		if d.ctx.Err() != nil {
+			continue
+		}
+		d.deliver(j.hook, j.event)
+	}
+}
+
+// getWebhooks возвращает подписки пользователя из кэша или из хранилища.
+func (d *Dispatcher) getWebhooks(userID string) ([]models.Webhook, error) {
+	now := time.Now()
+	d.cacheMux.Lock()
+	cached, ok := d.cache[userID]
+	d.cacheMux.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.hooks, nil
+	}
+
+	hooks, err := d.store.GetWebhooks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading webhooks: %w", err)
+	}
+	if d.config.CacheTTL <= 0 {
+		return hooks, nil
+	}
+
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
+	if d.config.CacheSize > 0 && len(d.cache) >= d.config.CacheSize {
+		for id, entry := range d.cache {
+			if !now.Before(entry.expires) {
+				delete(d.cache, id)
+			}
+		}
+		if len(d.cache) >= d.config.CacheSize {
+			d.cache = make(map[string]cachedHooks)
+		}
+	}
+	d.cache[userID] = cachedHooks{hooks: hooks, expires: now.Add(d.config.CacheTTL)}
+
+	return hooks, nil
+}
+
+func (d *Dispatcher) deliver(hook models.Webhook, event models.WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Errorf("error encoding webhook event: %v", err)
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		CreatedAt: event.CreatedAt,
+		ID:        event.ID + "-" + hook.ID,
+		WebhookID: hook.ID,
+		EventID:   event.ID,
+		Event:     event.Type,
+	}
+
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		if attempt > 1 && !d.wait(d.backoff(attempt-1)) {
+			err = errStopped
+			break
+		}
+
+		delivery.Attempts = attempt
+		delivery.Status, err = d.send(hook, event, body)
+		if err != nil && d.ctx.Err() != nil {
+			err = errStopped
+			break
+		}
+		if err == nil || !retryable(delivery.Status) {
+			break
+		}
+	}
+
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	if err := d.store.PutWebhookDelivery(delivery); err != nil {
+		d.logger.Errorf("error saving webhook delivery: %v", err)
+	}
+	// прерванная остановкой сервиса доставка не считается неудачной
+	if !errors.Is(err, errStopped) {
+		d.updateState(hook, delivery)
+	}
+}
+
+// updateState обновляет счетчик неудачных доставок и отключает подписку при превышении порога.
+func (d *Dispatcher) updateState(hook models.Webhook, delivery models.WebhookDelivery) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	// состояние подписки могло измениться, пока шла доставка
+	hooks, err := d.store.GetWebhooks(hook.UserID)
+	if err != nil {
+		d.logger.Errorf("error getting webhooks: %v", err)
+		return
+	}
+	var current *models.Webhook
+	for i := range hooks {
+		if hooks[i].ID == hook.ID {
+			current = &hooks[i]
+			break
+		}
+	}
+	if current == nil {
+		return
+	}
+
+	if delivery.Success {
+		if current.Failures == 0 && current.LastError == "" {
+			return
+		}
+		current.Failures = 0
+		current.LastError = ""
+	} else {
+		current.Failures++
+		current.LastError = delivery.Error
+		if d.config.DisableAfter > 0 && current.Failures >= d.config.DisableAfter {
+			current.Disabled = true
+			d.logger.Infof("webhook %s disabled after %d failed deliveries", current.ID, current.Failures)
+		}
+	}
+
+	if err := d.store.UpdateWebhook(*current); err != nil {
+		d.logger.Errorf("error updating webhook: %v", err)
+	}
+	d.Invalidate(hook.UserID)
+}
+
+func (d *Dispatcher) send(hook models.Webhook, event models.WebhookEvent, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("error building request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			d.logger.Errorf("error closing response body: %v", err)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff пауза перед повтором номер retry.
+func (d *Dispatcher) backoff(retry int) time.Duration {
+	delay := d.config.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if d.config.MaxDelay > 0 && delay >= d.config.MaxDelay {
+			return d.config.MaxDelay
+		}
+	}
+	return delay
+}
+
+// wait ждет delay, возвращает false при остановке диспетчера.
+func (d *Dispatcher) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-d.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// retryable ответы, после которых имеет смысл повторить доставку:
+// сетевая ошибка, 408, 429 и 5xx.
+func retryable(status int) bool {
+	return status == 0 ||
+		status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status >= http.StatusInternalServerError
+}
+
+// Sign вычисляет значение заголовка подписи для тела запроса.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса на стороне подписчика.
+func Verify(secret string, signature string, timestamp int64, body []byte) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// NewID генерирует случайный идентификатор подписки или события.
+func NewID() (string, error) {
+	b := make([]byte, idLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random generator error: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// NewSecret генерирует ключ подписи.
+func NewSecret() (string, error) {
+	b := make([]byte, sha256.Size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random generator error: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rawen554/shortener/internal/models"
+	"github.com/rawen554/shortener/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	userID = "user"
+	secret = "whsec_test"
+)
+
+func testConfig() Config {
+	return Config{
+		BaseDelay:    time.Millisecond,
+		MaxDelay:     time.Millisecond * 4,
+		MaxAttempts:  3,
+		DisableAfter: 2,
+		Workers:      2,
+		QueueSize:    10,
+		// тестовые серверы слушают loopback
+		AllowLoopback: true,
+	}
+}
+
+func newStorage(t *testing.T, hookURL string) *memory.MemoryStorage {
+	t.Helper()
+
+	storage, err := memory.NewMemoryStorage(make(map[string]models.URLRecordMemory))
+	require.NoError(t, err)
+	require.NoError(t, storage.PutWebhook(models.Webhook{
+		ID:     "hook",
+		UserID: userID,
+		URL:    hookURL,
+		Secret: secret,
+		Events: []string{models.EventLinkCreated},
+	}))
+
+	return storage
+}
+
+// waitDeliveries ждет, пока в журнале появится count доставок.
+func waitDeliveries(t *testing.T, storage *memory.MemoryStorage, count int) []models.WebhookDelivery {
+	t.Helper()
+
+	var deliveries []models.WebhookDelivery
+	require.Eventually(t, func() bool {
+		var err error
+		deliveries, err = storage.GetWebhookDeliveries("hook", 10)
+		require.NoError(t, err)
+		return len(deliveries) == count
+	}, time.Second*5, time.Millisecond*10)
+
+	return deliveries
+}
+
+func TestDispatcher_Signature(t *testing.T) {
+	received := make(chan models.WebhookEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.True(t, Verify(secret, r.Header.Get(SignatureHeader), timestamp, body))
+		assert.False(t, Verify("other", r.Header.Get(SignatureHeader), timestamp, body))
+		assert.Equal(t, models.EventLinkCreated, r.Header.Get(EventHeader))
+
+		var event models.WebhookEvent
+		require.NoError(t, json.Unmarshal(body, &event))
+		received <- event
+	}))
+	defer srv.Close()
+
+	storage := newStorage(t, srv.URL)
+	d := NewDispatcher(testConfig(), storage, zap.NewNop().Sugar(), nil)
+
+	// на клики подписки нет, событие не отправляется
+	d.Publish(userID, models.EventLinkClicked, models.LinkEvent{ShortURL: "http://localhost/abcd"})
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{ShortURL: "http://localhost/abcd"})
+	d.Close()
+
+	require.Len(t, received, 1)
+	event := <-received
+	assert.Equal(t, models.EventLinkCreated, event.Type)
+	assert.Equal(t, "http://localhost/abcd", event.Data.ShortURL)
+
+	deliveries := waitDeliveries(t, storage, 1)
+	assert.True(t, deliveries[0].Success)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].Status)
+}
+
+func TestDispatcher_RetryAndDisable(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	storage := newStorage(t, srv.URL)
+	config := testConfig()
+	config.Workers = 1
+	d := NewDispatcher(config, storage, zap.NewNop().Sugar(), nil)
+
+	for i := 0; i < 2; i++ {
+		d.Publish(userID, models.EventLinkCreated, models.LinkEvent{ShortURL: "http://localhost/abcd"})
+	}
+	deliveries := waitDeliveries(t, storage, 2)
+	d.Close()
+
+	// каждое событие доставлялось MaxAttempts раз
+	assert.Equal(t, int32(6), atomic.LoadInt32(&calls))
+	for _, delivery := range deliveries {
+		assert.False(t, delivery.Success)
+		assert.Equal(t, 3, delivery.Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, delivery.Status)
+		assert.NotEmpty(t, delivery.Error)
+	}
+
+	hooks, err := storage.GetWebhooks(userID)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.True(t, hooks[0].Disabled)
+	assert.Equal(t, 2, hooks[0].Failures)
+	assert.Equal(t, "unexpected status 503", hooks[0].LastError)
+
+	// отключенная подписка больше не получает события
+	d = NewDispatcher(config, storage, zap.NewNop().Sugar(), nil)
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{ShortURL: "http://localhost/abcd"})
+	d.Close()
+	assert.Equal(t, int32(6), atomic.LoadInt32(&calls))
+}
+
+func TestDispatcher_NoRetryOnClientError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	storage := newStorage(t, srv.URL)
+	d := NewDispatcher(testConfig(), storage, zap.NewNop().Sugar(), nil)
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{ShortURL: "http://localhost/abcd"})
+	d.Close()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	storage := newStorage(t, srv.URL)
+	config := testConfig()
+	config.Workers = 1
+	config.QueueSize = 1
+	d := NewDispatcher(config, storage, zap.NewNop().Sugar(), nil)
+
+	// первое событие занимает обработчик, второе ждет в очереди, третье отбрасывается
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{})
+	<-started
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{})
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{})
+	close(release)
+	d.Close()
+
+	assert.Len(t, started, 1)
+	waitDeliveries(t, storage, 2)
+}
+
+func TestDispatcher_CloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	storage := newStorage(t, srv.URL)
+	config := testConfig()
+	config.CloseTimeout = time.Millisecond * 50
+	d := NewDispatcher(config, storage, zap.NewNop().Sugar(), nil)
+	d.Publish(userID, models.EventLinkCreated, models.LinkEvent{})
+
+	start := time.Now()
+	d.Close()
+	assert.Less(t, time.Since(start), time.Second)
+
+	// прерванная доставка не считается неудачной
+	hooks, err := storage.GetWebhooks(userID)
+	require.NoError(t, err)
+	assert.Zero(t, hooks[0].Failures)
+}
+
+// countingStore считает чтения подписок.
+type countingStore struct {
+	*memory.MemoryStorage
+	mux   sync.Mutex
+	reads int
+}
+
+func (s *countingStore) GetWebhooks(userID string) ([]models.Webhook, error) {
+	s.mux.Lock()
+	s.reads++
+	s.mux.Unlock()
+	//nolint: wrapcheck // обертка для теста
+	return s.MemoryStorage.GetWebhooks(userID)
+}
+
+func TestDispatcher_Cache(t *testing.T) {
+	storage := &countingStore{MemoryStorage: newStorage(t, "http://localhost")}
+	config := testConfig()
+	config.CacheTTL = time.Minute
+	d := NewDispatcher(config, storage, zap.NewNop().Sugar(), nil)
+	defer d.Close()
+
+	// на клики подписки нет: хранилище не читается на каждом переходе
+	for i := 0; i < 3; i++ {
+		d.Publish(userID, models.EventLinkClicked, models.LinkEvent{})
+		d.Publish("other", models.EventLinkClicked, models.LinkEvent{})
+	}
+	assert.Equal(t, 2, storage.reads)
+
+	d.Invalidate(userID)
+	d.Publish(userID, models.EventLinkClicked, models.LinkEvent{})
+	assert.Equal(t, 3, storage.reads)
+}
+
+func TestDispatcher_ForbiddenTargets(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		allowLoopback bool
+	}{
+		{name: "loopback is forbidden by default", url: srv.URL},
+		{name: "redirects are not followed", url: srv.URL + "/redirect", allowLoopback: true, wantStatus: http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newStorage(t, tt.url)
+			config := testConfig()
+			config.MaxAttempts = 1
+			config.AllowLoopback = tt.allowLoopback
+			d := NewDispatcher(config, storage, zap.NewNop().Sugar(), nil)
+			d.Publish(userID, models.EventLinkCreated, models.LinkEvent{})
+			d.Close()
+
+			deliveries := waitDeliveries(t, storage, 1)
+			assert.False(t, deliveries[0].Success)
+			assert.Equal(t, tt.wantStatus, deliveries[0].Status)
+		})
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := NewDispatcher(Config{BaseDelay: time.Second, MaxDelay: time.Second * 5}, nil, zap.NewNop().Sugar(), nil)
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, time.Second*2, d.backoff(2))
+	assert.Equal(t, time.Second*4, d.backoff(3))
+	assert.Equal(t, time.Second*5, d.backoff(4))
+}